@@ -0,0 +1,51 @@
+package servicediscovery
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+const (
+	// apiKeyHeader carries the API key set with WithAPIKey.
+	apiKeyHeader = "X-API-Key"
+	// ownerTokenHeader carries the token proving this client registered an
+	// instance, required to change it.
+	ownerTokenHeader = "X-Owner-Token"
+)
+
+// TokenSource supplies bearer tokens for requests to the registry. Token is
+// called for every request, so implementations can cache and refresh
+// short-lived tokens.
+type TokenSource interface {
+	Token(ctx context.Context) (string, error)
+}
+
+// TokenSourceFunc adapts a function to TokenSource.
+type TokenSourceFunc func(ctx context.Context) (string, error)
+
+func (f TokenSourceFunc) Token(ctx context.Context) (string, error) {
+	return f(ctx)
+}
+
+// StaticToken returns a TokenSource that always yields token.
+func StaticToken(token string) TokenSource {
+	return TokenSourceFunc(func(context.Context) (string, error) {
+		return token, nil
+	})
+}
+
+// authorize attaches the configured credentials to req.
+func (c *Client) authorize(req *http.Request) error {
+	if c.options.apiKey != "" {
+		req.Header.Set(apiKeyHeader, c.options.apiKey)
+	}
+	if c.options.tokenSource != nil {
+		token, err := c.options.tokenSource.Token(req.Context())
+		if err != nil {
+			return fmt.Errorf("obtain token: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return nil
+}
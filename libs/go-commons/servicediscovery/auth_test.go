@@ -0,0 +1,97 @@
+package servicediscovery
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithAPIKey(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "secret-key", r.Header.Get("X-API-Key"))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, WithAPIKey("secret-key"))
+	require.NoError(t, client.Heartbeat(context.Background(), "test-id"))
+}
+
+func TestWithTokenSource(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer token-1", r.Header.Get("Authorization"))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, WithTokenSource(StaticToken("token-1")))
+	require.NoError(t, client.Heartbeat(context.Background(), "test-id"))
+}
+
+func TestTokenSourceError(t *testing.T) {
+	failing := TokenSourceFunc(func(context.Context) (string, error) {
+		return "", errors.New("token endpoint down")
+	})
+
+	client := NewClient("http://127.0.0.1:1", WithTokenSource(failing))
+	err := client.Heartbeat(context.Background(), "test-id")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "token endpoint down")
+}
+
+func TestAuthErrors(t *testing.T) {
+	status := http.StatusUnauthorized
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(`{"error":"invalid credentials"}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	_, err := client.List(context.Background())
+	assert.ErrorIs(t, err, ErrUnauthorized)
+
+	status = http.StatusForbidden
+	err = client.Unregister(context.Background(), "test-id")
+	assert.ErrorIs(t, err, ErrForbidden)
+}
+
+func TestOwnerTokenAttachedToOwnInstance(t *testing.T) {
+	var got []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, r.URL.Path+" "+r.Header.Get("X-Owner-Token"))
+		if r.URL.Path == "/api/v1/services/register" {
+			w.Header().Set("X-Owner-Token", "owner-secret")
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"id":"mine","name":"uploader"}`))
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	ctx := context.Background()
+	_, err := client.Register(ctx, &RegisterRequest{Name: "uploader", Host: "localhost", Port: 3000})
+	require.NoError(t, err)
+	assert.Equal(t, "owner-secret", client.GetOwnerToken())
+
+	require.NoError(t, client.Heartbeat(ctx, "mine"))
+	require.NoError(t, client.Heartbeat(ctx, "someone-else"))
+	_, err = client.Register(ctx, &RegisterRequest{Name: "uploader", Host: "localhost", Port: 3000})
+	require.NoError(t, err)
+	require.NoError(t, client.Unregister(ctx, "mine"))
+
+	assert.Equal(t, []string{
+		"/api/v1/services/register ",
+		"/api/v1/services/mine/heartbeat owner-secret",
+		"/api/v1/services/someone-else/heartbeat ",
+		"/api/v1/services/register owner-secret",
+		"/api/v1/services/mine/unregister owner-secret",
+	}, got)
+}
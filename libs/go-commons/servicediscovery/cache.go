@@ -0,0 +1,193 @@
+package servicediscovery
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// staleCache keeps the last successful body of each GET request so it can be
+// served while the registry is unreachable. Entries older than maxStaleness
+// are never served. With a directory set, entries are also written to disk
+// and survive a restart of the process.
+type staleCache struct {
+	maxStaleness time.Duration
+	dir          string
+	now          func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+}
+
+type cacheEntry struct {
+	StoredAt time.Time       `json:"stored_at"`
+	Body     json.RawMessage `json:"body"`
+}
+
+func newStaleCache(maxStaleness time.Duration, dir string) *staleCache {
+	return &staleCache{
+		maxStaleness: maxStaleness,
+		dir:          dir,
+		now:          time.Now,
+		entries:      make(map[string]cacheEntry),
+	}
+}
+
+func (c *staleCache) store(key string, body []byte) {
+	entry := cacheEntry{StoredAt: c.now(), Body: append(json.RawMessage(nil), body...)}
+
+	c.mu.Lock()
+	c.entries[key] = entry
+	c.mu.Unlock()
+
+	if c.dir != "" {
+		// The disk copy is best effort; the in-memory entry is already stored.
+		_ = c.write(key, entry)
+	}
+}
+
+// load returns the cached body for key and its age, if one exists that is
+// not older than maxStaleness.
+func (c *staleCache) load(key string) ([]byte, time.Duration, bool) {
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	c.mu.Unlock()
+
+	if !ok && c.dir != "" {
+		entry, ok = c.read(key)
+	}
+	if !ok {
+		return nil, 0, false
+	}
+
+	age := c.now().Sub(entry.StoredAt)
+	if age > c.maxStaleness {
+		return nil, 0, false
+	}
+	return entry.Body, age, true
+}
+
+func (c *staleCache) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+func (c *staleCache) write(key string, entry cacheEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(c.dir, 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(c.dir, ".cache-*")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), c.path(key))
+}
+
+func (c *staleCache) read(key string) (cacheEntry, bool) {
+	data, err := os.ReadFile(c.path(key))
+	if err != nil {
+		return cacheEntry{}, false
+	}
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return cacheEntry{}, false
+	}
+	return entry, true
+}
+
+// response is the outcome of a GET request, possibly served from the cache.
+type response struct {
+	status int
+	body   []byte
+	stale  bool
+	age    time.Duration
+}
+
+// mark flags services decoded from a cached response as stale.
+func (r *response) mark(services ...*Service) {
+	if !r.stale {
+		return
+	}
+	for _, svc := range services {
+		if svc != nil {
+			svc.Stale = true
+			svc.Age = r.age
+		}
+	}
+}
+
+// get performs a GET request through the stale cache, when enabled: 200
+// bodies are cached, and a cached body is returned instead when the registry
+// cannot be reached or answers 502, 503 or 504.
+func (c *Client) get(ctx context.Context, path string) (*response, error) {
+	res, err := c.fetch(ctx, path)
+	if c.cache == nil {
+		return res, err
+	}
+
+	if err == nil && !unavailable(res.status) {
+		if res.status == http.StatusOK {
+			c.cache.store(c.cacheKey(path), res.body)
+		}
+		return res, nil
+	}
+	if err != nil && (!errors.Is(err, ErrConnectionFailed) || ctx.Err() != nil) {
+		return nil, err
+	}
+
+	if body, age, ok := c.cache.load(c.cacheKey(path)); ok {
+		return &response{status: http.StatusOK, body: body, stale: true, age: age}, nil
+	}
+	return res, err
+}
+
+// cacheKey keeps the cached results of clients in different namespaces
+// apart, as they may share a cache directory.
+func (c *Client) cacheKey(path string) string {
+	if c.options.namespace == "" {
+		return path
+	}
+	return c.options.namespace + " " + path
+}
+
+func (c *Client) fetch(ctx context.Context, path string) (*response, error) {
+	resp, err := c.doRequest(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return &response{status: resp.StatusCode, body: body}, nil
+}
+
+func unavailable(status int) bool {
+	return status == http.StatusBadGateway ||
+		status == http.StatusServiceUnavailable ||
+		status == http.StatusGatewayTimeout
+}
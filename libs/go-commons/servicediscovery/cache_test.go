@@ -0,0 +1,131 @@
+package servicediscovery
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// flakyRegistry serves a fixed catalog until it is taken down, after which
+// it either closes connections or answers 503.
+type flakyRegistry struct {
+	down    atomic.Bool
+	refuse  bool
+	catalog []*Service
+}
+
+func (f *flakyRegistry) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if f.down.Load() {
+		if f.refuse {
+			hj, _ := w.(http.Hijacker)
+			conn, _, _ := hj.Hijack()
+			_ = conn.Close()
+			return
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = w.Write([]byte(`{"error": "no leader"}`))
+		return
+	}
+
+	if r.URL.Path == "/api/v1/services/1" {
+		_ = json.NewEncoder(w).Encode(f.catalog[0])
+		return
+	}
+	_ = json.NewEncoder(w).Encode(&ListResponse{Services: f.catalog, Count: len(f.catalog)})
+}
+
+func newFlakyRegistry(t *testing.T, refuse bool) (*flakyRegistry, string) {
+	registry := &flakyRegistry{
+		refuse:  refuse,
+		catalog: []*Service{{ID: "1", Name: "transcoder", Status: StatusHealthy}},
+	}
+	server := httptest.NewServer(registry)
+	t.Cleanup(server.Close)
+	return registry, server.URL
+}
+
+func TestStaleCacheServesWhenUnreachable(t *testing.T) {
+	registry, url := newFlakyRegistry(t, true)
+
+	client := NewClient(url, WithRetries(0), WithStaleCache(time.Minute))
+	now := time.Now()
+	client.cache.now = func() time.Time { return now }
+
+	services, err := client.Search(context.Background(), SearchQuery{Name: "transcoder"})
+	require.NoError(t, err)
+	assert.False(t, services[0].Stale)
+	_, err = client.Get(context.Background(), "1")
+	require.NoError(t, err)
+
+	registry.down.Store(true)
+	now = now.Add(20 * time.Second)
+
+	services, err = client.Search(context.Background(), SearchQuery{Name: "transcoder"})
+	require.NoError(t, err)
+	require.Len(t, services, 1)
+	assert.True(t, services[0].Stale)
+	assert.Equal(t, 20*time.Second, services[0].Age)
+
+	service, err := client.Get(context.Background(), "1")
+	require.NoError(t, err)
+	assert.True(t, service.Stale)
+
+	_, err = client.Search(context.Background(), SearchQuery{Name: "uploader"})
+	assert.ErrorIs(t, err, ErrConnectionFailed, "queries never answered are not cached")
+
+	now = now.Add(time.Minute)
+	_, err = client.Search(context.Background(), SearchQuery{Name: "transcoder"})
+	assert.ErrorIs(t, err, ErrConnectionFailed, "entries older than the maximum staleness are dropped")
+}
+
+func TestStaleCacheServesOnServiceUnavailable(t *testing.T) {
+	registry, url := newFlakyRegistry(t, false)
+
+	client := NewClient(url, WithRetries(0), WithStaleCache(time.Minute))
+	_, err := client.List(context.Background())
+	require.NoError(t, err)
+
+	registry.down.Store(true)
+
+	services, err := client.List(context.Background())
+	require.NoError(t, err)
+	assert.True(t, services[0].Stale)
+}
+
+func TestStaleCacheDisabledByDefault(t *testing.T) {
+	registry, url := newFlakyRegistry(t, false)
+
+	client := NewClient(url, WithRetries(0))
+	_, err := client.List(context.Background())
+	require.NoError(t, err)
+
+	registry.down.Store(true)
+
+	_, err = client.List(context.Background())
+	assert.EqualError(t, err, "no leader")
+}
+
+func TestStaleCachePersistsToDisk(t *testing.T) {
+	registry, url := newFlakyRegistry(t, true)
+	dir := t.TempDir()
+
+	first := NewClient(url, WithRetries(0), WithStaleCache(time.Minute), WithCacheDir(dir))
+	_, err := first.List(context.Background())
+	require.NoError(t, err)
+
+	registry.down.Store(true)
+
+	restarted := NewClient(url, WithRetries(0), WithStaleCache(time.Minute), WithCacheDir(dir))
+	services, err := restarted.List(context.Background())
+	require.NoError(t, err)
+	require.Len(t, services, 1)
+	assert.Equal(t, "transcoder", services[0].Name)
+	assert.True(t, services[0].Stale)
+}
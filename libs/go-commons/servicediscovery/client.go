@@ -16,14 +16,19 @@ import (
 	"time"
 )
 
+// namespaceHeader carries the namespace set with WithNamespace.
+const namespaceHeader = "X-Namespace"
+
 type Client struct {
 	baseURL    string
 	httpClient *http.Client
 	options    *clientOptions
+	cache      *staleCache
 
-	serviceID string
-	stopCh    chan struct{}
-	mu        sync.Mutex
+	serviceID  string
+	ownerToken string
+	stopCh     chan struct{}
+	mu         sync.Mutex
 }
 
 func NewClient(baseURL string, opts ...ClientOption) *Client {
@@ -31,14 +36,41 @@ func NewClient(baseURL string, opts ...ClientOption) *Client {
 	for _, opt := range opts {
 		opt(options)
 	}
+	if options.namespace == "" {
+		options.namespace = os.Getenv("SERVICE_NAMESPACE")
+	}
 
-	return &Client{
-		baseURL: strings.TrimSuffix(baseURL, "/"),
-		httpClient: &http.Client{
-			Timeout: options.timeout,
-		},
-		options: options,
+	client := &Client{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		httpClient: newHTTPClient(options),
+		options:    options,
+	}
+	if options.maxStaleness > 0 {
+		client.cache = newStaleCache(options.maxStaleness, options.cacheDir)
 	}
+	return client
+}
+
+func newHTTPClient(options *clientOptions) *http.Client {
+	httpClient := &http.Client{Timeout: options.timeout}
+	if options.httpClient != nil {
+		// Copied so that the TLS settings below don't leak into the caller's client.
+		copied := *options.httpClient
+		httpClient = &copied
+	}
+
+	if options.tlsConfig != nil {
+		base, ok := httpClient.Transport.(*http.Transport)
+		if httpClient.Transport == nil {
+			base, ok = http.DefaultTransport.(*http.Transport)
+		}
+		if ok {
+			transport := base.Clone()
+			transport.TLSClientConfig = options.tlsConfig.Clone()
+			httpClient.Transport = transport
+		}
+	}
+	return httpClient
 }
 
 func (c *Client) Register(ctx context.Context, req *RegisterRequest) (*Service, error) {
@@ -47,13 +79,15 @@ func (c *Client) Register(ctx context.Context, req *RegisterRequest) (*Service,
 		return nil, err
 	}
 
-	resp, err := c.doRequest(ctx, http.MethodPost, "/api/v1/services/register", body)
+	// Refreshing an instance registered earlier requires its owner token.
+	resp, err := c.doRequestWithHeader(ctx, http.MethodPost, "/api/v1/services/register", body, c.ownerHeader(""))
 	if err != nil {
 		return nil, err
 	}
 	defer func() { _ = resp.Body.Close() }()
 
-	if resp.StatusCode != http.StatusCreated {
+	// 200 means an existing registration of the same instance was refreshed.
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
 		return nil, c.parseError(resp)
 	}
 
@@ -64,6 +98,9 @@ func (c *Client) Register(ctx context.Context, req *RegisterRequest) (*Service,
 
 	c.mu.Lock()
 	c.serviceID = service.ID
+	if token := resp.Header.Get(ownerTokenHeader); token != "" {
+		c.ownerToken = token
+	}
 	c.mu.Unlock()
 
 	return &service, nil
@@ -76,6 +113,7 @@ func (c *Client) AutoRegister(ctx context.Context, opts ...RegisterOption) (*Ser
 	}
 
 	req := &RegisterRequest{
+		ID:          regOpts.id,
 		Name:        c.getServiceName(regOpts.name),
 		Host:        c.getHost(regOpts.host),
 		Port:        c.getPort(regOpts.port),
@@ -85,85 +123,90 @@ func (c *Client) AutoRegister(ctx context.Context, opts ...RegisterOption) (*Ser
 		HealthCheck: c.getHealthCheck(regOpts.healthCheck),
 		Tags:        c.getTags(regOpts.tags),
 		Metadata:    c.getMetadata(regOpts.metadata),
+		TTL:         Duration(c.getTTL(regOpts.ttl)),
 	}
 
 	return c.Register(ctx, req)
 }
 
 func (c *Client) Get(ctx context.Context, id string) (*Service, error) {
-	resp, err := c.doRequest(ctx, http.MethodGet, "/api/v1/services/"+id, nil)
+	res, err := c.get(ctx, "/api/v1/services/"+id)
 	if err != nil {
 		return nil, err
 	}
-	defer func() { _ = resp.Body.Close() }()
 
-	if resp.StatusCode == http.StatusNotFound {
+	if res.status == http.StatusNotFound {
 		return nil, ErrServiceNotFound
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, c.parseError(resp)
+	if res.status != http.StatusOK {
+		return nil, errorFromBody(res.status, res.body)
 	}
 
 	var service Service
-	if err := json.NewDecoder(resp.Body).Decode(&service); err != nil {
+	if err := json.Unmarshal(res.body, &service); err != nil {
 		return nil, err
 	}
 
+	res.mark(&service)
 	return &service, nil
 }
 
 func (c *Client) List(ctx context.Context) ([]*Service, error) {
-	resp, err := c.doRequest(ctx, http.MethodGet, "/api/v1/services/list", nil)
-	if err != nil {
-		return nil, err
-	}
-	defer func() { _ = resp.Body.Close() }()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, c.parseError(resp)
-	}
+	return c.list(ctx, "/api/v1/services/list")
+}
 
-	var listResp ListResponse
-	if err := json.NewDecoder(resp.Body).Decode(&listResp); err != nil {
-		return nil, err
+func (c *Client) Search(ctx context.Context, q SearchQuery) ([]*Service, error) {
+	path := "/api/v1/services/search"
+	if params := q.values(); len(params) > 0 {
+		path += "?" + params.Encode()
 	}
 
-	return listResp.Services, nil
+	return c.list(ctx, path)
 }
 
-func (c *Client) Search(ctx context.Context, route, name, tag string) ([]*Service, error) {
+func (q SearchQuery) values() url.Values {
 	params := url.Values{}
-	if route != "" {
-		params.Set("route", route)
+	if q.Route != "" {
+		params.Set("route", q.Route)
 	}
-	if name != "" {
-		params.Set("name", name)
+	if q.Name != "" {
+		params.Set("name", q.Name)
 	}
-	if tag != "" {
-		params.Set("tag", tag)
+	if q.Protocol != "" {
+		params.Set("protocol", q.Protocol)
 	}
-
-	path := "/api/v1/services/search"
-	if len(params) > 0 {
-		path += "?" + params.Encode()
+	if q.Status != "" {
+		params.Set("status", string(q.Status))
+	}
+	if len(q.Tags) > 0 {
+		params.Set("tags", strings.Join(q.Tags, ","))
+		if q.AnyTag {
+			params.Set("tag_mode", "any")
+		}
 	}
+	if q.Selector != "" {
+		params.Set("selector", q.Selector)
+	}
+	return params
+}
 
-	resp, err := c.doRequest(ctx, http.MethodGet, path, nil)
+func (c *Client) list(ctx context.Context, path string) ([]*Service, error) {
+	res, err := c.get(ctx, path)
 	if err != nil {
 		return nil, err
 	}
-	defer func() { _ = resp.Body.Close() }()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, c.parseError(resp)
+	if res.status != http.StatusOK {
+		return nil, errorFromBody(res.status, res.body)
 	}
 
 	var listResp ListResponse
-	if err := json.NewDecoder(resp.Body).Decode(&listResp); err != nil {
+	if err := json.Unmarshal(res.body, &listResp); err != nil {
 		return nil, err
 	}
 
+	res.mark(listResp.Services...)
 	return listResp.Services, nil
 }
 
@@ -173,7 +216,7 @@ func (c *Client) Update(ctx context.Context, id string, req *UpdateRequest) (*Se
 		return nil, err
 	}
 
-	resp, err := c.doRequest(ctx, http.MethodPut, "/api/v1/services/"+id+"/update", body)
+	resp, err := c.doRequestWithHeader(ctx, http.MethodPut, "/api/v1/services/"+id+"/update", body, c.ownerHeader(id))
 	if err != nil {
 		return nil, err
 	}
@@ -196,7 +239,7 @@ func (c *Client) Update(ctx context.Context, id string, req *UpdateRequest) (*Se
 }
 
 func (c *Client) Unregister(ctx context.Context, id string) error {
-	resp, err := c.doRequest(ctx, http.MethodDelete, "/api/v1/services/"+id+"/unregister", nil)
+	resp, err := c.doRequestWithHeader(ctx, http.MethodDelete, "/api/v1/services/"+id+"/unregister", nil, c.ownerHeader(id))
 	if err != nil {
 		return err
 	}
@@ -214,7 +257,7 @@ func (c *Client) Unregister(ctx context.Context, id string) error {
 }
 
 func (c *Client) Heartbeat(ctx context.Context, id string) error {
-	resp, err := c.doRequest(ctx, http.MethodPut, "/api/v1/services/"+id+"/heartbeat", nil)
+	resp, err := c.doRequestWithHeader(ctx, http.MethodPut, "/api/v1/services/"+id+"/heartbeat", nil, c.ownerHeader(id))
 	if err != nil {
 		return err
 	}
@@ -231,6 +274,8 @@ func (c *Client) Heartbeat(ctx context.Context, id string) error {
 	return nil
 }
 
+// StartHeartbeat heartbeats id in the background and ignores failures; Run
+// also registers again when the registry forgets the instance.
 func (c *Client) StartHeartbeat(ctx context.Context, id string, interval time.Duration) {
 	c.mu.Lock()
 	if c.stopCh != nil {
@@ -273,10 +318,52 @@ func (c *Client) GetServiceID() string {
 	return c.serviceID
 }
 
+// GetNamespace returns the namespace the client's requests are scoped to,
+// or "" for the registry's default namespace.
+func (c *Client) GetNamespace() string {
+	return c.options.namespace
+}
+
+// scope sends req to the client's namespace.
+func (c *Client) scope(req *http.Request) {
+	if c.options.namespace != "" {
+		req.Header.Set(namespaceHeader, c.options.namespace)
+	}
+}
+
+// GetOwnerToken returns the owner token the registry issued for the
+// instance registered by this client. It is sent automatically with
+// changes to that instance.
+func (c *Client) GetOwnerToken() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ownerToken
+}
+
+// ownerHeader returns the owner token header for requests about id, or about
+// the registered instance when id is empty.
+func (c *Client) ownerHeader(id string) http.Header {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.ownerToken == "" || (id != "" && id != c.serviceID) {
+		return nil
+	}
+	return http.Header{ownerTokenHeader: []string{c.ownerToken}}
+}
+
+// doRequest sends a request, retrying according to the client's retry
+// policy. When retries are exhausted on a retryable status, that response is
+// returned for the caller to handle.
 func (c *Client) doRequest(ctx context.Context, method, path string, body []byte) (*http.Response, error) {
-	var lastErr error
+	return c.doRequestWithHeader(ctx, method, path, body, nil)
+}
+
+// doRequestWithHeader is doRequest with extra request headers.
+func (c *Client) doRequestWithHeader(ctx context.Context, method, path string, body []byte, header http.Header) (*http.Response, error) {
+	start := time.Now()
 
-	for i := 0; i <= c.options.retries; i++ {
+	for attempt := 0; ; attempt++ {
 		var reqBody io.Reader
 		if body != nil {
 			reqBody = bytes.NewReader(body)
@@ -290,32 +377,81 @@ func (c *Client) doRequest(ctx context.Context, method, path string, body []byte
 		if body != nil {
 			req.Header.Set("Content-Type", "application/json")
 		}
+		for name, values := range header {
+			req.Header[name] = values
+		}
+		c.scope(req)
+		if err := c.authorize(req); err != nil {
+			return nil, err
+		}
 
+		req, span := c.startSpan(req, attempt)
 		resp, err := c.httpClient.Do(req)
-		if err != nil {
-			lastErr = err
-			if i < c.options.retries {
-				time.Sleep(c.options.retryDelay)
-				continue
+		endSpan(span, resp, err)
+		if err == nil && !c.options.shouldRetryStatus(method, resp.StatusCode) {
+			return resp, nil
+		}
+		if ctx.Err() != nil {
+			if resp != nil {
+				_ = resp.Body.Close()
 			}
-			return nil, fmt.Errorf("%w: %v", ErrConnectionFailed, err)
+			return nil, fmt.Errorf("%w: %w", ErrConnectionFailed, ctx.Err())
 		}
 
-		return resp, nil
-	}
+		// A Retry-After longer than the remaining MaxElapsedTime ends the
+		// call even though the actual wait is capped at maxRetryDelay.
+		delay := c.options.backoff(attempt)
+		requested := delay
+		if resp != nil {
+			if d, ok := retryAfter(resp, time.Now()); ok {
+				requested = d
+				delay = c.options.capDelay(d)
+			}
+		}
+
+		elapsed := time.Since(start) + requested
+		if attempt >= c.options.retries || (c.options.maxRetryElapsed > 0 && elapsed > c.options.maxRetryElapsed) {
+			if err != nil {
+				return nil, fmt.Errorf("%w: %v", ErrConnectionFailed, err)
+			}
+			return resp, nil
+		}
+		if resp != nil {
+			_, _ = io.Copy(io.Discard, resp.Body)
+			_ = resp.Body.Close()
+		}
 
-	return nil, lastErr
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, fmt.Errorf("%w: %w", ErrConnectionFailed, ctx.Err())
+		case <-timer.C:
+		}
+	}
 }
 
 func (c *Client) parseError(resp *http.Response) error {
 	body, _ := io.ReadAll(resp.Body)
+	return errorFromBody(resp.StatusCode, body)
+}
+
+func errorFromBody(status int, body []byte) error {
 	var errResp struct {
 		Error string `json:"error"`
 	}
+	message := fmt.Sprintf("unexpected status code: %d", status)
 	if err := json.Unmarshal(body, &errResp); err == nil && errResp.Error != "" {
-		return fmt.Errorf("%s", errResp.Error)
+		message = errResp.Error
+	}
+
+	switch status {
+	case http.StatusUnauthorized:
+		return fmt.Errorf("%w: %s", ErrUnauthorized, message)
+	case http.StatusForbidden:
+		return fmt.Errorf("%w: %s", ErrForbidden, message)
 	}
-	return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	return fmt.Errorf("%s", message)
 }
 
 func (c *Client) getServiceName(override string) string {
@@ -396,6 +532,18 @@ func (c *Client) getTags(override []string) []string {
 	return nil
 }
 
+func (c *Client) getTTL(override time.Duration) time.Duration {
+	if override != 0 {
+		return override
+	}
+	if ttl := os.Getenv("SERVICE_TTL"); ttl != "" {
+		if d, err := time.ParseDuration(ttl); err == nil {
+			return d
+		}
+	}
+	return 0
+}
+
 func (c *Client) getMetadata(override map[string]string) map[string]string {
 	if len(override) > 0 {
 		return override
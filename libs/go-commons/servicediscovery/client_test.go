@@ -137,6 +137,56 @@ func TestAutoRegisterWithOverrides(t *testing.T) {
 	assert.Equal(t, "override-service", service.Name)
 }
 
+func TestAutoRegisterRefreshesWithInstanceID(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req RegisterRequest
+		err := json.NewDecoder(r.Body).Decode(&req)
+		require.NoError(t, err)
+
+		assert.Equal(t, "worker-0", req.ID)
+
+		w.WriteHeader(http.StatusOK)
+		err = json.NewEncoder(w).Encode(&Service{
+			ID:   req.ID,
+			Name: req.Name,
+		})
+		require.NoError(t, err)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	service, err := client.AutoRegister(context.Background(),
+		WithInstanceID("worker-0"),
+		WithName("worker"),
+	)
+
+	require.NoError(t, err)
+	assert.Equal(t, "worker-0", service.ID)
+	assert.Equal(t, "worker-0", client.GetServiceID())
+}
+
+func TestAutoRegisterWithTTL(t *testing.T) {
+	_ = os.Setenv("SERVICE_TTL", "45s")
+	defer func() { _ = os.Unsetenv("SERVICE_TTL") }()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var raw map[string]interface{}
+		err := json.NewDecoder(r.Body).Decode(&raw)
+		require.NoError(t, err)
+		assert.Equal(t, "15s", raw["ttl"])
+
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"id": "ttl-id", "ttl": "15s"}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	service, err := client.AutoRegister(context.Background(), WithTTL(15*time.Second))
+
+	require.NoError(t, err)
+	assert.Equal(t, Duration(15*time.Second), service.TTL)
+}
+
 func TestList(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		assert.Equal(t, "/api/v1/services/list", r.URL.Path)
@@ -248,8 +298,37 @@ func TestSearch(t *testing.T) {
 	defer server.Close()
 
 	client := NewClient(server.URL)
-	services, err := client.Search(context.Background(), "/users", "", "")
+	services, err := client.Search(context.Background(), SearchQuery{Route: "/users"})
 
 	require.NoError(t, err)
 	assert.Len(t, services, 1)
 }
+
+func TestSearchQuery(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		assert.Equal(t, "encoder", query.Get("name"))
+		assert.Equal(t, "grpc", query.Get("protocol"))
+		assert.Equal(t, "healthy", query.Get("status"))
+		assert.Equal(t, "gpu,h264", query.Get("tags"))
+		assert.Equal(t, "any", query.Get("tag_mode"))
+		assert.Equal(t, "env=prod,region in (us,eu),!deprecated", query.Get("selector"))
+		assert.False(t, query.Has("route"))
+
+		w.WriteHeader(http.StatusOK)
+		require.NoError(t, json.NewEncoder(w).Encode(&ListResponse{}))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	_, err := client.Search(context.Background(), SearchQuery{
+		Name:     "encoder",
+		Protocol: "grpc",
+		Status:   StatusHealthy,
+		Tags:     []string{"gpu", "h264"},
+		AnyTag:   true,
+		Selector: "env=prod,region in (us,eu),!deprecated",
+	})
+
+	require.NoError(t, err)
+}
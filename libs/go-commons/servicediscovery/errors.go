@@ -7,4 +7,9 @@ var (
 	ErrInvalidRequest  = errors.New("invalid request")
 	ErrConnectionFailed = errors.New("connection to service discovery failed")
 	ErrTimeout         = errors.New("request timeout")
+
+	ErrNoInstanceAvailable = errors.New("no healthy instance available")
+
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrForbidden    = errors.New("forbidden")
 )
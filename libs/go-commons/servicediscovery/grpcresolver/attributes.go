@@ -0,0 +1,51 @@
+package grpcresolver
+
+import (
+	"maps"
+	"slices"
+
+	"google.golang.org/grpc/attributes"
+)
+
+type (
+	instanceIDKey struct{}
+	tagsKey       struct{}
+	metadataKey   struct{}
+)
+
+// tags and metadata implement Equal, which gRPC needs to compare attribute
+// values that are not comparable with ==.
+type tags []string
+
+func (t tags) Equal(o any) bool {
+	other, ok := o.(tags)
+	return ok && slices.Equal(t, other)
+}
+
+type metadata map[string]string
+
+func (m metadata) Equal(o any) bool {
+	other, ok := o.(metadata)
+	return ok && maps.Equal(m, other)
+}
+
+// InstanceID returns the registry ID of the instance behind an endpoint or
+// address, given its Attributes or BalancerAttributes.
+func InstanceID(attrs *attributes.Attributes) string {
+	id, _ := attrs.Value(instanceIDKey{}).(string)
+	return id
+}
+
+// Tags returns the registered tags of the instance behind an endpoint or
+// address, given its Attributes or BalancerAttributes.
+func Tags(attrs *attributes.Attributes) []string {
+	t, _ := attrs.Value(tagsKey{}).(tags)
+	return t
+}
+
+// Metadata returns the registered metadata of the instance behind an
+// endpoint or address, given its Attributes or BalancerAttributes.
+func Metadata(attrs *attributes.Attributes) map[string]string {
+	m, _ := attrs.Value(metadataKey{}).(metadata)
+	return m
+}
@@ -0,0 +1,37 @@
+package grpcresolver
+
+import "time"
+
+type Option func(*options)
+
+type options struct {
+	waitTime   time.Duration
+	minBackoff time.Duration
+	maxBackoff time.Duration
+}
+
+func defaultOptions() *options {
+	return &options{
+		waitTime:   time.Minute,
+		minBackoff: time.Second,
+		maxBackoff: 30 * time.Second,
+	}
+}
+
+// WithWaitTime sets how long each blocking query may be held by the
+// registry before it answers without a change. The registry caps it at 5m.
+func WithWaitTime(d time.Duration) Option {
+	return func(o *options) {
+		o.waitTime = d
+	}
+}
+
+// WithRetryBackoff bounds the delay before querying the registry again
+// after a failure. The delay grows from min to max with each consecutive
+// failure.
+func WithRetryBackoff(minDelay, maxDelay time.Duration) Option {
+	return func(o *options) {
+		o.minBackoff = minDelay
+		o.maxBackoff = maxDelay
+	}
+}
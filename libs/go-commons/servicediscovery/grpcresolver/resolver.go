@@ -0,0 +1,237 @@
+// Package grpcresolver lets gRPC clients dial services registered in
+// service-discover. Targets have the form sd:///<service-name>, optionally
+// with ?tag=<tag> to keep only instances carrying that tag and
+// ?selector=<selector> to keep those whose tags and metadata match a label
+// selector such as env=prod,region in (us,eu):
+//
+//	grpcresolver.Register(client)
+//	conn, err := grpc.NewClient("sd:///video-processor",
+//		grpc.WithTransportCredentials(insecure.NewCredentials()))
+//
+// The resolver follows the registry with blocking queries and hands the
+// addresses of healthy instances to gRPC's balancer as soon as they change.
+// Each endpoint carries the instance's ID, tags and metadata as attributes;
+// read them with InstanceID, Tags and Metadata.
+package grpcresolver
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net"
+	"sort"
+	"strconv"
+	"time"
+
+	"google.golang.org/grpc/attributes"
+	"google.golang.org/grpc/resolver"
+
+	"github.com/carlosealves2/video-ia/go-commons/servicediscovery"
+)
+
+// Scheme is the gRPC target scheme handled by the Builder.
+const Scheme = servicediscovery.Scheme
+
+// Builder builds resolvers for sd:/// targets.
+type Builder struct {
+	client  *servicediscovery.Client
+	options *options
+}
+
+func NewBuilder(client *servicediscovery.Client, opts ...Option) *Builder {
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(o)
+	}
+	return &Builder{client: client, options: o}
+}
+
+// Register registers a Builder for client in gRPC's global resolver
+// registry. It must be called during initialization, before any connection
+// is created; use grpc.WithResolvers with NewBuilder to scope the resolver
+// to a single connection instead.
+func Register(client *servicediscovery.Client, opts ...Option) {
+	resolver.Register(NewBuilder(client, opts...))
+}
+
+func (b *Builder) Scheme() string {
+	return Scheme
+}
+
+func (b *Builder) Build(target resolver.Target, cc resolver.ClientConn, _ resolver.BuildOptions) (resolver.Resolver, error) {
+	name := target.Endpoint()
+	if name == "" {
+		return nil, errors.New("grpcresolver: target has no service name, expected sd:///<service-name>")
+	}
+
+	query := servicediscovery.SearchQuery{
+		Name:     name,
+		Selector: target.URL.Query().Get("selector"),
+	}
+	if tag := target.URL.Query().Get("tag"); tag != "" {
+		query.Tags = []string{tag}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &sdResolver{
+		client:     b.client,
+		cc:         cc,
+		name:       name,
+		query:      query,
+		options:    b.options,
+		ctx:        ctx,
+		cancel:     cancel,
+		resolveNow: make(chan struct{}, 1),
+		done:       make(chan struct{}),
+	}
+	go r.watch()
+	return r, nil
+}
+
+type sdResolver struct {
+	client  *servicediscovery.Client
+	cc      resolver.ClientConn
+	name    string
+	query   servicediscovery.SearchQuery
+	options *options
+
+	ctx        context.Context
+	cancel     context.CancelFunc
+	resolveNow chan struct{}
+	done       chan struct{}
+
+	last []resolver.Endpoint
+}
+
+// ResolveNow cuts short the wait before retrying a failed query. While the
+// registry is reachable the resolver already receives every change, so
+// there is nothing more to do.
+func (r *sdResolver) ResolveNow(resolver.ResolveNowOptions) {
+	select {
+	case r.resolveNow <- struct{}{}:
+	default:
+	}
+}
+
+func (r *sdResolver) Close() {
+	r.cancel()
+	<-r.done
+}
+
+func (r *sdResolver) watch() {
+	defer close(r.done)
+
+	var (
+		index    uint64
+		failures int
+	)
+	for {
+		services, next, err := r.client.Watch(r.ctx, r.query, index, r.options.waitTime)
+		if r.ctx.Err() != nil {
+			return
+		}
+
+		if err != nil {
+			r.cc.ReportError(err)
+			r.last = nil
+
+			timer := time.NewTimer(r.options.backoff(failures))
+			failures++
+			select {
+			case <-r.ctx.Done():
+				timer.Stop()
+				return
+			case <-r.resolveNow:
+				timer.Stop()
+			case <-timer.C:
+			}
+			continue
+		}
+
+		failures = 0
+		// An index of zero would make the next query return immediately.
+		index = max(next, 1)
+		r.update(services)
+	}
+}
+
+// update pushes the healthy instances to gRPC when they differ from the last
+// state pushed. The registry wakes blocking queries on any catalog change,
+// including those to other services, and each wait that elapses returns the
+// unchanged list, so many rounds change nothing.
+func (r *sdResolver) update(services []*servicediscovery.Service) {
+	endpoints := r.endpoints(services)
+	if len(endpoints) == 0 {
+		r.cc.ReportError(servicediscovery.ErrNoInstanceAvailable)
+		r.last = nil
+		return
+	}
+	if equalEndpoints(endpoints, r.last) {
+		return
+	}
+
+	addresses := make([]resolver.Address, 0, len(endpoints))
+	for _, ep := range endpoints {
+		addresses = append(addresses, ep.Addresses...)
+	}
+
+	if err := r.cc.UpdateState(resolver.State{Endpoints: endpoints, Addresses: addresses}); err != nil {
+		// Push the state again on the next round.
+		r.last = nil
+		return
+	}
+	r.last = endpoints
+}
+
+func (r *sdResolver) endpoints(services []*servicediscovery.Service) []resolver.Endpoint {
+	var instances []*servicediscovery.Service
+	for _, svc := range services {
+		// Search matches names by substring; keep only this service.
+		if svc.Name == r.name && svc.Status == servicediscovery.StatusHealthy {
+			instances = append(instances, svc)
+		}
+	}
+	sort.Slice(instances, func(i, j int) bool { return instances[i].ID < instances[j].ID })
+
+	endpoints := make([]resolver.Endpoint, 0, len(instances))
+	for _, svc := range instances {
+		attrs := attributes.New(instanceIDKey{}, svc.ID).
+			WithValue(tagsKey{}, tags(svc.Tags)).
+			WithValue(metadataKey{}, metadata(svc.Metadata))
+
+		endpoints = append(endpoints, resolver.Endpoint{
+			Addresses: []resolver.Address{{
+				Addr:               net.JoinHostPort(svc.Host, strconv.Itoa(svc.Port)),
+				BalancerAttributes: attrs,
+			}},
+			Attributes: attrs,
+		})
+	}
+	return endpoints
+}
+
+func equalEndpoints(a, b []resolver.Endpoint) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Addresses[0].Addr != b[i].Addresses[0].Addr || !a[i].Attributes.Equal(b[i].Attributes) {
+			return false
+		}
+	}
+	return true
+}
+
+// backoff returns the full-jitter delay before retrying after failures
+// consecutive failed queries.
+func (o *options) backoff(failures int) time.Duration {
+	ceiling := o.minBackoff
+	for i := 0; i < failures && ceiling < o.maxBackoff; i++ {
+		ceiling *= 2
+	}
+	ceiling = min(ceiling, o.maxBackoff)
+	if ceiling <= 0 {
+		return 0
+	}
+	return rand.N(ceiling)
+}
@@ -0,0 +1,242 @@
+package grpcresolver
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/resolver"
+
+	"github.com/carlosealves2/video-ia/go-commons/servicediscovery"
+)
+
+// fakeRegistry answers blocking searches like service-discover does.
+type fakeRegistry struct {
+	mu       sync.Mutex
+	index    uint64
+	services []*servicediscovery.Service
+	changed  chan struct{}
+	down     bool
+}
+
+func newFakeRegistry(services ...*servicediscovery.Service) *fakeRegistry {
+	return &fakeRegistry{index: 1, services: services, changed: make(chan struct{})}
+}
+
+func (f *fakeRegistry) set(down bool, services ...*servicediscovery.Service) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.down = down
+	f.services = services
+	f.index++
+	close(f.changed)
+	f.changed = make(chan struct{})
+}
+
+func (f *fakeRegistry) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	index, _ := strconv.ParseUint(r.URL.Query().Get("index"), 10, 64)
+
+	f.mu.Lock()
+	if index > 0 && f.index <= index {
+		changed := f.changed
+		f.mu.Unlock()
+		select {
+		case <-changed:
+		case <-time.After(time.Second):
+		case <-r.Context().Done():
+			return
+		}
+		f.mu.Lock()
+	}
+	down, current := f.down, f.index
+	var matched []*servicediscovery.Service
+	for _, svc := range f.services {
+		if tag := r.URL.Query().Get("tags"); tag == "" || contains(svc.Tags, tag) {
+			matched = append(matched, svc)
+		}
+	}
+	f.mu.Unlock()
+
+	if down {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	w.Header().Set("X-Registry-Index", strconv.FormatUint(current, 10))
+	_ = json.NewEncoder(w).Encode(servicediscovery.ListResponse{Services: matched, Count: len(matched)})
+}
+
+func contains(values []string, v string) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
+
+type fakeClientConn struct {
+	resolver.ClientConn
+
+	states chan resolver.State
+	errs   chan error
+}
+
+func newFakeClientConn() *fakeClientConn {
+	return &fakeClientConn{states: make(chan resolver.State, 10), errs: make(chan error, 10)}
+}
+
+func (f *fakeClientConn) UpdateState(s resolver.State) error {
+	f.states <- s
+	return nil
+}
+
+func (f *fakeClientConn) ReportError(err error) {
+	select {
+	case f.errs <- err:
+	default:
+	}
+}
+
+func (f *fakeClientConn) nextState(t *testing.T) resolver.State {
+	t.Helper()
+	select {
+	case s := <-f.states:
+		return s
+	case <-time.After(3 * time.Second):
+		t.Fatal("no state update")
+		return resolver.State{}
+	}
+}
+
+func (f *fakeClientConn) nextError(t *testing.T) error {
+	t.Helper()
+	select {
+	case err := <-f.errs:
+		return err
+	case <-time.After(3 * time.Second):
+		t.Fatal("no error reported")
+		return nil
+	}
+}
+
+func instance(id, name, host string, status servicediscovery.ServiceStatus, tags ...string) *servicediscovery.Service {
+	return &servicediscovery.Service{
+		ID:       id,
+		Name:     name,
+		Host:     host,
+		Port:     9000,
+		Tags:     tags,
+		Metadata: map[string]string{"zone": "a"},
+		Status:   status,
+	}
+}
+
+func build(t *testing.T, registry *fakeRegistry, target string) *fakeClientConn {
+	t.Helper()
+	server := httptest.NewServer(registry)
+	t.Cleanup(server.Close)
+
+	u, err := url.Parse(target)
+	require.NoError(t, err)
+
+	cc := newFakeClientConn()
+	builder := NewBuilder(servicediscovery.NewClient(server.URL),
+		WithWaitTime(time.Second),
+		WithRetryBackoff(10*time.Millisecond, 50*time.Millisecond),
+	)
+	r, err := builder.Build(resolver.Target{URL: *u}, cc, resolver.BuildOptions{})
+	require.NoError(t, err)
+	t.Cleanup(r.Close)
+	return cc
+}
+
+func addrs(s resolver.State) []string {
+	var out []string
+	for _, ep := range s.Endpoints {
+		out = append(out, ep.Addresses[0].Addr)
+	}
+	return out
+}
+
+func TestResolverPushesHealthyInstances(t *testing.T) {
+	registry := newFakeRegistry(
+		instance("b", "encoder", "10.0.0.2", servicediscovery.StatusHealthy, "gpu"),
+		instance("a", "encoder", "10.0.0.1", servicediscovery.StatusHealthy),
+		instance("c", "encoder", "10.0.0.3", servicediscovery.StatusUnhealthy),
+		instance("d", "encoder-admin", "10.0.0.4", servicediscovery.StatusHealthy),
+	)
+	cc := build(t, registry, "sd:///encoder")
+
+	state := cc.nextState(t)
+	assert.Equal(t, []string{"10.0.0.1:9000", "10.0.0.2:9000"}, addrs(state))
+	assert.Len(t, state.Addresses, 2)
+
+	attrs := state.Endpoints[1].Attributes
+	assert.Equal(t, "b", InstanceID(attrs))
+	assert.Equal(t, []string{"gpu"}, Tags(attrs))
+	assert.Equal(t, map[string]string{"zone": "a"}, Metadata(attrs))
+	assert.Equal(t, "b", InstanceID(state.Addresses[1].BalancerAttributes))
+}
+
+func TestResolverFollowsRegistryChanges(t *testing.T) {
+	registry := newFakeRegistry(instance("a", "encoder", "10.0.0.1", servicediscovery.StatusHealthy))
+	cc := build(t, registry, "sd:///encoder")
+	assert.Equal(t, []string{"10.0.0.1:9000"}, addrs(cc.nextState(t)))
+
+	registry.set(false,
+		instance("a", "encoder", "10.0.0.1", servicediscovery.StatusUnhealthy),
+		instance("b", "encoder", "10.0.0.2", servicediscovery.StatusHealthy),
+	)
+	assert.Equal(t, []string{"10.0.0.2:9000"}, addrs(cc.nextState(t)))
+}
+
+func TestResolverSkipsUnchangedState(t *testing.T) {
+	a := instance("a", "encoder", "10.0.0.1", servicediscovery.StatusHealthy)
+	registry := newFakeRegistry(a)
+	cc := build(t, registry, "sd:///encoder")
+	cc.nextState(t)
+
+	// Another service changing wakes the query without changing this one.
+	registry.set(false, a, instance("x", "other", "10.0.0.9", servicediscovery.StatusHealthy))
+	registry.set(false, a, instance("b", "encoder", "10.0.0.2", servicediscovery.StatusHealthy))
+
+	assert.Equal(t, []string{"10.0.0.1:9000", "10.0.0.2:9000"}, addrs(cc.nextState(t)))
+}
+
+func TestResolverFiltersByTag(t *testing.T) {
+	registry := newFakeRegistry(
+		instance("a", "encoder", "10.0.0.1", servicediscovery.StatusHealthy),
+		instance("b", "encoder", "10.0.0.2", servicediscovery.StatusHealthy, "gpu"),
+	)
+	cc := build(t, registry, "sd:///encoder?tag=gpu")
+	assert.Equal(t, []string{"10.0.0.2:9000"}, addrs(cc.nextState(t)))
+}
+
+func TestResolverReportsNoInstance(t *testing.T) {
+	registry := newFakeRegistry(instance("a", "encoder", "10.0.0.1", servicediscovery.StatusUnhealthy))
+	cc := build(t, registry, "sd:///encoder")
+	assert.ErrorIs(t, cc.nextError(t), servicediscovery.ErrNoInstanceAvailable)
+}
+
+func TestResolverRecoversAfterRegistryFailure(t *testing.T) {
+	registry := newFakeRegistry()
+	registry.set(true)
+	cc := build(t, registry, "sd:///encoder")
+	require.Error(t, cc.nextError(t))
+
+	registry.set(false, instance("a", "encoder", "10.0.0.1", servicediscovery.StatusHealthy))
+	assert.Equal(t, []string{"10.0.0.1:9000"}, addrs(cc.nextState(t)))
+}
+
+func TestBuildRequiresServiceName(t *testing.T) {
+	_, err := NewBuilder(servicediscovery.NewClient("http://localhost")).
+		Build(resolver.Target{URL: url.URL{Scheme: Scheme}}, newFakeClientConn(), resolver.BuildOptions{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,122 @@
+package servicediscovery
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+const defaultHeartbeatInterval = 10 * time.Second
+
+type RegistrationState string
+
+const (
+	// StateRegistered means the instance is registered and its heartbeats
+	// are being accepted.
+	StateRegistered RegistrationState = "registered"
+	// StateDisconnected means registering or heartbeating is failing, e.g.
+	// because the registry is unreachable. Run keeps retrying.
+	StateDisconnected RegistrationState = "disconnected"
+	// StateExpired means the registry no longer knows the instance, e.g.
+	// after a restart or TTL expiry. Run registers it again right away.
+	StateExpired RegistrationState = "expired"
+	// StateDeregistered means the instance was removed on shutdown.
+	StateDeregistered RegistrationState = "deregistered"
+)
+
+// RegistrationEvent reports a change of RegistrationState. Service is the
+// current registration, if any; Err is the failure behind the change.
+type RegistrationEvent struct {
+	State   RegistrationState
+	Service *Service
+	Err     error
+}
+
+// Run manages the instance's registration until ctx is cancelled: it
+// registers with AutoRegister, heartbeats on an interval, registers again
+// when the registry has forgotten the instance, and deregisters on the way
+// out. Registration failures are retried on the heartbeat interval. Run
+// blocks and returns the error of the final deregistration, if any.
+func (c *Client) Run(ctx context.Context, opts ...RegisterOption) error {
+	regOpts := &registerOptions{}
+	for _, opt := range opts {
+		opt(regOpts)
+	}
+
+	interval := regOpts.heartbeatInterval
+	if interval <= 0 {
+		interval = defaultHeartbeatInterval
+		// Three beats per TTL leave room for one or two to be lost.
+		if ttl := c.getTTL(regOpts.ttl); ttl > 0 {
+			interval = ttl / 3
+		}
+	}
+
+	var (
+		service *Service
+		state   RegistrationState
+	)
+	notify := func(next RegistrationState, err error) {
+		if next == state {
+			return
+		}
+		state = next
+		if regOpts.onStateChange != nil {
+			regOpts.onStateChange(RegistrationEvent{State: next, Service: service, Err: err})
+		}
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if service == nil {
+			registered, err := c.AutoRegister(ctx, opts...)
+			switch {
+			case err == nil:
+				service = registered
+				notify(StateRegistered, nil)
+			case ctx.Err() == nil:
+				notify(StateDisconnected, err)
+			}
+		} else {
+			err := c.Heartbeat(ctx, service.ID)
+			switch {
+			case err == nil:
+				notify(StateRegistered, nil)
+			case errors.Is(err, ErrServiceNotFound):
+				notify(StateExpired, err)
+				service = nil
+				continue
+			case ctx.Err() == nil:
+				notify(StateDisconnected, err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			if service == nil {
+				return nil
+			}
+			if err := c.deregister(ctx, service.ID); err != nil {
+				return err
+			}
+			notify(StateDeregistered, nil)
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// deregister unregisters id once ctx is already done, giving the request
+// its own timeout.
+func (c *Client) deregister(ctx context.Context, id string) error {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), c.options.timeout)
+	defer cancel()
+
+	err := c.Unregister(ctx, id)
+	if err != nil && !errors.Is(err, ErrServiceNotFound) {
+		return err
+	}
+	return nil
+}
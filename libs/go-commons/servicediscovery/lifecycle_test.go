@@ -0,0 +1,164 @@
+package servicediscovery
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRegistry records lifecycle calls and can forget the registered
+// instance, the way a restarted registry would.
+type fakeRegistry struct {
+	mu            sync.Mutex
+	registrations int
+	heartbeats    int
+	unregistered  bool
+	known         bool
+}
+
+func (f *fakeRegistry) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch {
+	case r.URL.Path == "/api/v1/services/register":
+		f.registrations++
+		f.known = true
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(&Service{ID: "instance-1", Name: "worker"})
+	case !f.known:
+		w.WriteHeader(http.StatusNotFound)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": "service not found"})
+	case strings.HasSuffix(r.URL.Path, "/heartbeat"):
+		f.heartbeats++
+		_ = json.NewEncoder(w).Encode(&HeartbeatResponse{Message: "heartbeat received"})
+	case strings.HasSuffix(r.URL.Path, "/unregister"):
+		f.unregistered = true
+		f.known = false
+		_ = json.NewEncoder(w).Encode(map[string]string{"message": "service unregistered successfully"})
+	}
+}
+
+func (f *fakeRegistry) forget() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.known = false
+}
+
+func (f *fakeRegistry) counts() (registrations, heartbeats int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.registrations, f.heartbeats
+}
+
+type stateRecorder struct {
+	mu     sync.Mutex
+	states []RegistrationState
+}
+
+func (s *stateRecorder) record(e RegistrationEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.states = append(s.states, e.State)
+}
+
+func (s *stateRecorder) get() []RegistrationState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]RegistrationState(nil), s.states...)
+}
+
+func TestRunLifecycle(t *testing.T) {
+	registry := &fakeRegistry{}
+	server := httptest.NewServer(registry)
+	defer server.Close()
+
+	states := &stateRecorder{}
+	client := NewClient(server.URL, WithRetries(0))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- client.Run(ctx,
+			WithName("worker"),
+			WithHeartbeatInterval(10*time.Millisecond),
+			WithStateHandler(states.record),
+		)
+	}()
+
+	require.Eventually(t, func() bool {
+		_, heartbeats := registry.counts()
+		return heartbeats >= 2
+	}, time.Second, 5*time.Millisecond)
+
+	registry.forget()
+
+	require.Eventually(t, func() bool {
+		registrations, _ := registry.counts()
+		return registrations == 2
+	}, time.Second, 5*time.Millisecond, "re-registers after the registry forgets the instance")
+
+	cancel()
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("Run did not return after cancellation")
+	}
+
+	assert.True(t, registry.unregistered)
+	assert.Equal(t, []RegistrationState{
+		StateRegistered,
+		StateExpired,
+		StateRegistered,
+		StateDeregistered,
+	}, states.get())
+}
+
+func TestRunRetriesWhileRegistryIsDown(t *testing.T) {
+	registry := &fakeRegistry{}
+	var available atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !available.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		registry.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	states := &stateRecorder{}
+	client := NewClient(server.URL, WithRetries(0))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		_ = client.Run(ctx,
+			WithName("worker"),
+			WithHeartbeatInterval(10*time.Millisecond),
+			WithStateHandler(states.record),
+		)
+	}()
+
+	require.Eventually(t, func() bool {
+		return len(states.get()) == 1
+	}, time.Second, 5*time.Millisecond)
+	assert.Equal(t, StateDisconnected, states.get()[0])
+
+	available.Store(true)
+
+	require.Eventually(t, func() bool {
+		s := states.get()
+		return len(s) == 2 && s[1] == StateRegistered
+	}, time.Second, 5*time.Millisecond)
+	assert.Equal(t, "instance-1", client.GetServiceID())
+}
@@ -1,6 +1,10 @@
 package servicediscovery
 
-import "time"
+import (
+	"encoding/json"
+	"errors"
+	"time"
+)
 
 type ServiceStatus string
 
@@ -9,13 +13,44 @@ const (
 	StatusUnhealthy ServiceStatus = "unhealthy"
 )
 
+// Duration is a time.Duration encoded in JSON as a Go duration string ("30s"),
+// matching the representation used by service-discover.
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+
+	switch v := value.(type) {
+	case float64:
+		*d = Duration(v * float64(time.Second))
+	case string:
+		parsed, err := time.ParseDuration(v)
+		if err != nil {
+			return err
+		}
+		*d = Duration(parsed)
+	default:
+		return errors.New("invalid duration")
+	}
+	return nil
+}
+
 type Route struct {
 	Path    string   `json:"path"`
 	Methods []string `json:"methods"`
+	Timeout Duration `json:"timeout,omitempty"`
 }
 
 type Service struct {
 	ID            string            `json:"id"`
+	Namespace     string            `json:"namespace"`
 	Name          string            `json:"name"`
 	Host          string            `json:"host"`
 	Port          int               `json:"port"`
@@ -26,11 +61,18 @@ type Service struct {
 	Tags          []string          `json:"tags,omitempty"`
 	Metadata      map[string]string `json:"metadata,omitempty"`
 	Status        ServiceStatus     `json:"status"`
+	TTL           Duration          `json:"ttl,omitempty"`
 	LastHeartbeat time.Time         `json:"last_heartbeat"`
 	RegisteredAt  time.Time         `json:"registered_at"`
+
+	// Stale is set when the registry was unreachable and the service was
+	// served from the client's stale cache; Age is how old that copy is.
+	Stale bool          `json:"-"`
+	Age   time.Duration `json:"-"`
 }
 
 type RegisterRequest struct {
+	ID          string            `json:"id,omitempty"`
 	Name        string            `json:"name"`
 	Host        string            `json:"host"`
 	Port        int               `json:"port"`
@@ -40,6 +82,7 @@ type RegisterRequest struct {
 	HealthCheck string            `json:"health_check,omitempty"`
 	Tags        []string          `json:"tags,omitempty"`
 	Metadata    map[string]string `json:"metadata,omitempty"`
+	TTL         Duration          `json:"ttl,omitempty"`
 }
 
 type UpdateRequest struct {
@@ -53,6 +96,23 @@ type UpdateRequest struct {
 	Metadata    map[string]string `json:"metadata,omitempty"`
 }
 
+// SearchQuery selects services in Search and Watch; empty fields match
+// every service.
+type SearchQuery struct {
+	// Route matches services with a route starting with it.
+	Route string
+	// Name matches services whose name contains it, ignoring case.
+	Name     string
+	Protocol string
+	Status   ServiceStatus
+	// Tags must all be present on a service, or at least one with AnyTag.
+	Tags   []string
+	AnyTag bool
+	// Selector is a label selector evaluated against tags and metadata,
+	// e.g. "env=prod,region in (us,eu),!deprecated".
+	Selector string
+}
+
 type ListResponse struct {
 	Services []*Service `json:"services"`
 	Count    int        `json:"count"`
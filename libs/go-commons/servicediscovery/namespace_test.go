@@ -0,0 +1,35 @@
+package servicediscovery
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithNamespace(t *testing.T) {
+	var seen []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = append(seen, r.Header.Get("X-Namespace"))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	require.NoError(t, NewClient(server.URL, WithNamespace("dev")).Heartbeat(ctx, "test-id"))
+	require.NoError(t, NewClient(server.URL).Heartbeat(ctx, "test-id"))
+
+	assert.Equal(t, []string{"dev", ""}, seen)
+}
+
+func TestNamespaceFromEnv(t *testing.T) {
+	_ = os.Setenv("SERVICE_NAMESPACE", "staging")
+	defer func() { _ = os.Unsetenv("SERVICE_NAMESPACE") }()
+
+	assert.Equal(t, "staging", NewClient("http://localhost:8080").GetNamespace())
+	assert.Equal(t, "dev", NewClient("http://localhost:8080", WithNamespace("dev")).GetNamespace())
+}
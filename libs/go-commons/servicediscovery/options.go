@@ -1,20 +1,46 @@
 package servicediscovery
 
-import "time"
+import (
+	"crypto/tls"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
 
 type ClientOption func(*clientOptions)
 
 type clientOptions struct {
-	timeout    time.Duration
-	retries    int
-	retryDelay time.Duration
+	timeout      time.Duration
+	maxStaleness time.Duration
+	cacheDir     string
+
+	apiKey      string
+	tokenSource TokenSource
+
+	httpClient *http.Client
+	tlsConfig  *tls.Config
+
+	namespace string
+
+	tracerProvider trace.TracerProvider
+	propagator     propagation.TextMapPropagator
+
+	retries              int
+	retryDelay           time.Duration
+	maxRetryDelay        time.Duration
+	maxRetryElapsed      time.Duration
+	retryableStatusCodes []int
 }
 
 func defaultOptions() *clientOptions {
 	return &clientOptions{
-		timeout:    10 * time.Second,
-		retries:    3,
-		retryDelay: 1 * time.Second,
+		timeout:       10 * time.Second,
+		retries:       3,
+		retryDelay:    1 * time.Second,
+		maxRetryDelay: 30 * time.Second,
+		propagator:    propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}),
 	}
 }
 
@@ -36,9 +62,101 @@ func WithRetryDelay(d time.Duration) ClientOption {
 	}
 }
 
+// WithRetryPolicy replaces the retry settings, including any set through
+// WithRetries or WithRetryDelay.
+func WithRetryPolicy(p RetryPolicy) ClientOption {
+	return func(o *clientOptions) {
+		o.retries = p.MaxRetries
+		o.retryDelay = p.InitialBackoff
+		o.maxRetryDelay = p.MaxBackoff
+		o.maxRetryElapsed = p.MaxElapsedTime
+		o.retryableStatusCodes = p.RetryableStatusCodes
+	}
+}
+
+// WithStaleCache keeps the last successful List, Search and Get results and
+// serves them, flagged as Stale, when the registry cannot be reached. Results
+// older than maxStaleness are not served.
+func WithStaleCache(maxStaleness time.Duration) ClientOption {
+	return func(o *clientOptions) {
+		o.maxStaleness = maxStaleness
+	}
+}
+
+// WithCacheDir also persists the stale cache under dir, so that a process
+// started while the registry is down can still find its dependencies. It has
+// no effect without WithStaleCache.
+func WithCacheDir(dir string) ClientOption {
+	return func(o *clientOptions) {
+		o.cacheDir = dir
+	}
+}
+
+// WithAPIKey sends key with every request to a registry that requires
+// authentication.
+func WithAPIKey(key string) ClientOption {
+	return func(o *clientOptions) {
+		o.apiKey = key
+	}
+}
+
+// WithTokenSource sends a bearer token from ts with every request to a
+// registry that requires authentication.
+func WithTokenSource(ts TokenSource) ClientOption {
+	return func(o *clientOptions) {
+		o.tokenSource = ts
+	}
+}
+
+// WithHTTPClient sends requests through client instead of one created by
+// NewClient. WithTimeout is ignored; the client's own Timeout applies.
+func WithHTTPClient(client *http.Client) ClientOption {
+	return func(o *clientOptions) {
+		o.httpClient = client
+	}
+}
+
+// WithTLSConfig connects to the registry over TLS with cfg, e.g. to trust a
+// private CA or to present a client certificate to a registry requiring
+// mTLS. It applies on top of WithHTTPClient when the client's transport is
+// an *http.Transport.
+func WithTLSConfig(cfg *tls.Config) ClientOption {
+	return func(o *clientOptions) {
+		o.tlsConfig = cfg
+	}
+}
+
+// WithNamespace scopes every request to namespace, isolating this client's
+// registrations and lookups from other environments sharing the registry.
+// Without it the SERVICE_NAMESPACE environment variable is used, and
+// without that the registry's default namespace.
+func WithNamespace(namespace string) ClientOption {
+	return func(o *clientOptions) {
+		o.namespace = namespace
+	}
+}
+
+// WithTracerProvider records a client span for every request to the
+// registry with tp. Without it the global provider registered through
+// otel.SetTracerProvider is used.
+func WithTracerProvider(tp trace.TracerProvider) ClientOption {
+	return func(o *clientOptions) {
+		o.tracerProvider = tp
+	}
+}
+
+// WithPropagator sets how the trace context is written into requests. By
+// default W3C trace context and baggage headers are sent.
+func WithPropagator(p propagation.TextMapPropagator) ClientOption {
+	return func(o *clientOptions) {
+		o.propagator = p
+	}
+}
+
 type RegisterOption func(*registerOptions)
 
 type registerOptions struct {
+	id          string
 	name        string
 	host        string
 	port        int
@@ -48,6 +166,19 @@ type registerOptions struct {
 	healthCheck string
 	tags        []string
 	metadata    map[string]string
+	ttl         time.Duration
+
+	heartbeatInterval time.Duration
+	onStateChange     func(RegistrationEvent)
+}
+
+// WithInstanceID sets a stable instance ID, so that re-registering after a
+// restart refreshes the same record instead of matching on name, host and
+// port.
+func WithInstanceID(id string) RegisterOption {
+	return func(o *registerOptions) {
+		o.id = id
+	}
 }
 
 func WithName(name string) RegisterOption {
@@ -103,3 +234,117 @@ func WithMetadata(metadata map[string]string) RegisterOption {
 		o.metadata = metadata
 	}
 }
+
+func WithTTL(ttl time.Duration) RegisterOption {
+	return func(o *registerOptions) {
+		o.ttl = ttl
+	}
+}
+
+// WithHeartbeatInterval sets how often Run heartbeats. By default it is a
+// third of the TTL, or 10s without one.
+func WithHeartbeatInterval(d time.Duration) RegisterOption {
+	return func(o *registerOptions) {
+		o.heartbeatInterval = d
+	}
+}
+
+// WithStateHandler registers fn to be called by Run whenever the
+// registration changes state. fn runs on Run's goroutine.
+func WithStateHandler(fn func(RegistrationEvent)) RegisterOption {
+	return func(o *registerOptions) {
+		o.onStateChange = fn
+	}
+}
+
+type ResolverOption func(*resolverOptions)
+
+type resolverOptions struct {
+	strategy        Strategy
+	refreshInterval time.Duration
+	ejectDuration   time.Duration
+	healthyOnly     bool
+}
+
+func defaultResolverOptions() *resolverOptions {
+	return &resolverOptions{
+		strategy:        NewRoundRobin(),
+		refreshInterval: 10 * time.Second,
+		ejectDuration:   30 * time.Second,
+		healthyOnly:     true,
+	}
+}
+
+func WithStrategy(strategy Strategy) ResolverOption {
+	return func(o *resolverOptions) {
+		o.strategy = strategy
+	}
+}
+
+func WithRefreshInterval(d time.Duration) ResolverOption {
+	return func(o *resolverOptions) {
+		o.refreshInterval = d
+	}
+}
+
+func WithEjectDuration(d time.Duration) ResolverOption {
+	return func(o *resolverOptions) {
+		o.ejectDuration = d
+	}
+}
+
+// WithHealthyOnly controls whether instances the registry reports as
+// unhealthy are skipped (the default).
+func WithHealthyOnly(healthyOnly bool) ResolverOption {
+	return func(o *resolverOptions) {
+		o.healthyOnly = healthyOnly
+	}
+}
+
+type TransportOption func(*transportOptions)
+
+type transportOptions struct {
+	base            http.RoundTripper
+	hostSuffix      string
+	maxAttempts     int
+	resolverOptions []ResolverOption
+}
+
+func defaultTransportOptions() *transportOptions {
+	return &transportOptions{
+		base:        http.DefaultTransport,
+		maxAttempts: 3,
+	}
+}
+
+// WithBaseTransport sets the RoundTripper that sends the rewritten requests.
+func WithBaseTransport(base http.RoundTripper) TransportOption {
+	return func(o *transportOptions) {
+		o.base = base
+	}
+}
+
+// WithHostSuffix also resolves hosts ending in suffix, e.g. with
+// ".sd.internal" a request to http://video-processor.sd.internal/jobs goes
+// to an instance of video-processor.
+func WithHostSuffix(suffix string) TransportOption {
+	return func(o *transportOptions) {
+		o.hostSuffix = suffix
+	}
+}
+
+// WithMaxAttempts sets how many instances a request is tried on when
+// connecting fails.
+func WithMaxAttempts(n int) TransportOption {
+	return func(o *transportOptions) {
+		o.maxAttempts = n
+	}
+}
+
+// WithResolverOptions configures the resolvers the transport creates for
+// each service.
+func WithResolverOptions(opts ...ResolverOption) TransportOption {
+	return func(o *transportOptions) {
+		o.resolverOptions = opts
+	}
+}
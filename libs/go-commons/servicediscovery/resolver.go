@@ -0,0 +1,182 @@
+package servicediscovery
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// Resolver picks instances of one service on the caller's side. It caches the
+// instance list, refreshing it from the registry once it is older than the
+// refresh interval, and temporarily ejects instances reported as failing.
+// Concurrent refreshes share one registry call, made without holding the
+// lock picks take; while the registry is unreachable, the last fetched
+// instances keep being served.
+type Resolver struct {
+	client  *Client
+	name    string
+	options *resolverOptions
+	now     func() time.Time
+	group   singleflight.Group
+
+	mu        sync.Mutex
+	instances []*Service
+	fetchedAt time.Time
+	ejected   map[string]time.Time
+	inFlight  map[string]int64
+}
+
+func NewResolver(client *Client, name string, opts ...ResolverOption) *Resolver {
+	options := defaultResolverOptions()
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	return &Resolver{
+		client:   client,
+		name:     name,
+		options:  options,
+		now:      time.Now,
+		ejected:  make(map[string]time.Time),
+		inFlight: make(map[string]int64),
+	}
+}
+
+// Pick returns an instance chosen by the configured strategy. Every picked
+// instance should be reported back with Done once the call finishes.
+func (r *Resolver) Pick(ctx context.Context) (*Service, error) {
+	if err := r.refresh(ctx); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	candidates := r.candidates()
+	if len(candidates) == 0 {
+		return nil, ErrNoInstanceAvailable
+	}
+
+	picked := r.options.strategy.Pick(candidates)
+	r.inFlight[picked.ID]++
+	return picked, nil
+}
+
+// Done reports the outcome of a call made to a picked instance. A non-nil
+// err ejects the instance for the eject duration.
+func (r *Resolver) Done(instance *Service, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.inFlight[instance.ID] > 0 {
+		r.inFlight[instance.ID]--
+	}
+	if err != nil {
+		r.ejected[instance.ID] = r.now().Add(r.options.ejectDuration)
+	}
+}
+
+// Instances returns the cached instances, refreshing them first if needed.
+func (r *Resolver) Instances(ctx context.Context) ([]*Service, error) {
+	if err := r.refresh(ctx); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]*Service(nil), r.instances...), nil
+}
+
+// refresh fetches the instances once the cached ones are older than the
+// refresh interval. A failed fetch only returns an error when nothing was
+// fetched before.
+func (r *Resolver) refresh(ctx context.Context) error {
+	r.mu.Lock()
+	fetched := !r.fetchedAt.IsZero()
+	fresh := fetched && r.now().Sub(r.fetchedAt) < r.options.refreshInterval
+	r.mu.Unlock()
+
+	if fresh {
+		return nil
+	}
+
+	// The fetch is shared by every caller waiting on it, so it must not be
+	// cut short when the caller that started it gives up.
+	_, err, _ := r.group.Do(r.name, func() (interface{}, error) {
+		return nil, r.fetch(context.WithoutCancel(ctx))
+	})
+	if err != nil && fetched {
+		return nil
+	}
+	return err
+}
+
+func (r *Resolver) fetch(ctx context.Context) error {
+	services, err := r.client.Search(ctx, SearchQuery{Name: r.name})
+	if err != nil {
+		return err
+	}
+
+	// Search matches names by substring; keep only this service.
+	instances := make([]*Service, 0, len(services))
+	ids := make(map[string]struct{}, len(services))
+	for _, svc := range services {
+		if svc.Name == r.name {
+			instances = append(instances, svc)
+			ids[svc.ID] = struct{}{}
+		}
+	}
+	sort.Slice(instances, func(i, j int) bool { return instances[i].ID < instances[j].ID })
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.instances = instances
+	r.fetchedAt = r.now()
+
+	// Forget instances that left the registry, or the maps would keep
+	// every instance ever seen.
+	for id := range r.ejected {
+		if _, ok := ids[id]; !ok {
+			delete(r.ejected, id)
+		}
+	}
+	for id := range r.inFlight {
+		if _, ok := ids[id]; !ok {
+			delete(r.inFlight, id)
+		}
+	}
+	return nil
+}
+
+// candidates returns the instances eligible for a pick. When every eligible
+// instance is ejected the ejections are ignored, since picking a suspect
+// instance beats failing outright.
+func (r *Resolver) candidates() []Candidate {
+	now := r.now()
+
+	var eligible, available []Candidate
+	for _, svc := range r.instances {
+		if r.options.healthyOnly && svc.Status != StatusHealthy {
+			continue
+		}
+		c := Candidate{Service: svc, InFlight: r.inFlight[svc.ID]}
+		eligible = append(eligible, c)
+
+		if until, ok := r.ejected[svc.ID]; ok {
+			if now.Before(until) {
+				continue
+			}
+			delete(r.ejected, svc.ID)
+		}
+		available = append(available, c)
+	}
+
+	if len(available) == 0 {
+		return eligible
+	}
+	return available
+}
@@ -0,0 +1,237 @@
+package servicediscovery
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newResolverServer(t *testing.T, services []*Service, searches *atomic.Int32) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/services/search", r.URL.Path)
+		assert.Equal(t, "transcoder", r.URL.Query().Get("name"))
+		if searches != nil {
+			searches.Add(1)
+		}
+
+		w.WriteHeader(http.StatusOK)
+		err := json.NewEncoder(w).Encode(&ListResponse{Services: services, Count: len(services)})
+		require.NoError(t, err)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestResolverRoundRobinHealthyOnly(t *testing.T) {
+	server := newResolverServer(t, []*Service{
+		{ID: "b", Name: "transcoder", Status: StatusHealthy},
+		{ID: "a", Name: "transcoder", Status: StatusHealthy},
+		{ID: "c", Name: "transcoder", Status: StatusUnhealthy},
+		{ID: "d", Name: "transcoder-legacy", Status: StatusHealthy},
+	}, nil)
+
+	resolver := NewResolver(NewClient(server.URL), "transcoder")
+
+	var picked []string
+	for i := 0; i < 4; i++ {
+		svc, err := resolver.Pick(context.Background())
+		require.NoError(t, err)
+		picked = append(picked, svc.ID)
+	}
+
+	assert.Equal(t, []string{"a", "b", "a", "b"}, picked)
+}
+
+func TestResolverCachesUntilRefreshInterval(t *testing.T) {
+	var searches atomic.Int32
+	server := newResolverServer(t, []*Service{{ID: "a", Name: "transcoder", Status: StatusHealthy}}, &searches)
+
+	now := time.Now()
+	resolver := NewResolver(NewClient(server.URL), "transcoder", WithRefreshInterval(time.Minute))
+	resolver.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		_, err := resolver.Pick(context.Background())
+		require.NoError(t, err)
+	}
+	assert.Equal(t, int32(1), searches.Load())
+
+	now = now.Add(time.Minute)
+	_, err := resolver.Pick(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), searches.Load())
+}
+
+func TestResolverEjectsFailedInstances(t *testing.T) {
+	server := newResolverServer(t, []*Service{
+		{ID: "a", Name: "transcoder", Status: StatusHealthy},
+		{ID: "b", Name: "transcoder", Status: StatusHealthy},
+	}, nil)
+
+	now := time.Now()
+	resolver := NewResolver(NewClient(server.URL), "transcoder",
+		WithStrategy(NewRandom()),
+		WithRefreshInterval(time.Hour),
+		WithEjectDuration(30*time.Second),
+	)
+	resolver.now = func() time.Time { return now }
+
+	resolver.Done(&Service{ID: "a"}, errors.New("connection refused"))
+
+	for i := 0; i < 20; i++ {
+		svc, err := resolver.Pick(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, "b", svc.ID)
+		resolver.Done(svc, nil)
+	}
+
+	resolver.Done(&Service{ID: "b"}, errors.New("timeout"))
+	_, err := resolver.Pick(context.Background())
+	require.NoError(t, err, "all instances ejected falls back to picking among them")
+
+	now = now.Add(31 * time.Second)
+	seen := map[string]bool{}
+	for i := 0; i < 50; i++ {
+		svc, err := resolver.Pick(context.Background())
+		require.NoError(t, err)
+		seen[svc.ID] = true
+	}
+	assert.True(t, seen["a"] && seen["b"], "ejection expires")
+}
+
+func TestResolverNoInstance(t *testing.T) {
+	server := newResolverServer(t, []*Service{{ID: "a", Name: "transcoder", Status: StatusUnhealthy}}, nil)
+
+	_, err := NewResolver(NewClient(server.URL), "transcoder").Pick(context.Background())
+	assert.ErrorIs(t, err, ErrNoInstanceAvailable)
+
+	svc, err := NewResolver(NewClient(server.URL), "transcoder", WithHealthyOnly(false)).Pick(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "a", svc.ID)
+}
+
+func TestResolverRegistryUnreachable(t *testing.T) {
+	resolver := NewResolver(NewClient("http://127.0.0.1:1", WithRetries(0)), "transcoder")
+
+	_, err := resolver.Pick(context.Background())
+
+	assert.ErrorIs(t, err, ErrConnectionFailed)
+}
+
+func TestResolverServesCachedInstancesWhenRegistryFails(t *testing.T) {
+	var down atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if down.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		services := []*Service{{ID: "a", Name: "transcoder", Status: StatusHealthy}}
+		_ = json.NewEncoder(w).Encode(&ListResponse{Services: services, Count: len(services)})
+	}))
+	defer server.Close()
+
+	now := time.Now()
+	resolver := NewResolver(NewClient(server.URL, WithRetries(0)), "transcoder", WithRefreshInterval(time.Minute))
+	resolver.now = func() time.Time { return now }
+
+	_, err := resolver.Pick(context.Background())
+	require.NoError(t, err)
+
+	down.Store(true)
+	now = now.Add(time.Minute)
+
+	svc, err := resolver.Pick(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "a", svc.ID)
+
+	instances, err := resolver.Instances(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, instances, 1)
+}
+
+func TestResolverCoalescesConcurrentRefreshes(t *testing.T) {
+	var searches atomic.Int32
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		searches.Add(1)
+		<-release
+		services := []*Service{{ID: "a", Name: "transcoder", Status: StatusHealthy}}
+		_ = json.NewEncoder(w).Encode(&ListResponse{Services: services, Count: len(services)})
+	}))
+	defer server.Close()
+
+	resolver := NewResolver(NewClient(server.URL), "transcoder")
+
+	errs := make(chan error, 10)
+	for i := 0; i < cap(errs); i++ {
+		go func() {
+			_, err := resolver.Pick(context.Background())
+			errs <- err
+		}()
+	}
+
+	require.Eventually(t, func() bool { return searches.Load() == 1 }, time.Second, time.Millisecond)
+	// Let the other picks join the pending refresh before it completes.
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+
+	for i := 0; i < cap(errs); i++ {
+		require.NoError(t, <-errs)
+	}
+	assert.Equal(t, int32(1), searches.Load())
+}
+
+func TestResolverForgetsRemovedInstances(t *testing.T) {
+	services := []*Service{
+		{ID: "a", Name: "transcoder", Status: StatusHealthy},
+		{ID: "b", Name: "transcoder", Status: StatusHealthy},
+	}
+	var removed atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		current := services
+		if removed.Load() {
+			current = services[1:]
+		}
+		_ = json.NewEncoder(w).Encode(&ListResponse{Services: current, Count: len(current)})
+	}))
+	defer server.Close()
+
+	now := time.Now()
+	resolver := NewResolver(NewClient(server.URL), "transcoder", WithRefreshInterval(time.Minute))
+	resolver.now = func() time.Time { return now }
+
+	svc, err := resolver.Pick(context.Background())
+	require.NoError(t, err)
+	resolver.Done(&Service{ID: "a"}, errors.New("connection refused"))
+	assert.Contains(t, resolver.ejected, "a")
+	assert.Contains(t, resolver.inFlight, svc.ID)
+
+	removed.Store(true)
+	now = now.Add(time.Minute)
+	_, err = resolver.Instances(context.Background())
+	require.NoError(t, err)
+
+	assert.NotContains(t, resolver.ejected, "a")
+	assert.NotContains(t, resolver.inFlight, "a")
+}
+
+func TestPowerOfTwoChoicesPrefersLessLoaded(t *testing.T) {
+	strategy := NewPowerOfTwoChoices()
+	candidates := []Candidate{
+		{Service: &Service{ID: "busy"}, InFlight: 10},
+		{Service: &Service{ID: "idle"}, InFlight: 0},
+	}
+
+	for i := 0; i < 20; i++ {
+		assert.Equal(t, "idle", strategy.Pick(candidates).ID)
+	}
+	assert.Equal(t, "busy", strategy.Pick(candidates[:1]).ID)
+}
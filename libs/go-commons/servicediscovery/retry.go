@@ -0,0 +1,98 @@
+package servicediscovery
+
+import (
+	"math"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy controls how the client retries failed requests. Transport
+// errors are retried for every method; responses with one of
+// RetryableStatusCodes only for idempotent methods. The delay before retry n
+// is drawn uniformly from [0, min(MaxBackoff, InitialBackoff*2^n)) unless
+// the response carries a Retry-After header, which is honoured up to
+// MaxBackoff. A call whose Retry-After exceeds the remaining MaxElapsedTime
+// gives up at once.
+type RetryPolicy struct {
+	MaxRetries     int
+	InitialBackoff time.Duration
+	// MaxBackoff caps the backoff ceiling; zero leaves it uncapped.
+	MaxBackoff time.Duration
+	// MaxElapsedTime bounds the time spent on one call, retries included.
+	// Zero means no bound besides MaxRetries.
+	MaxElapsedTime time.Duration
+	// RetryableStatusCodes defaults to 502, 503 and 504 when nil.
+	RetryableStatusCodes []int
+}
+
+var defaultRetryableStatusCodes = []int{
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+func (o *clientOptions) shouldRetryStatus(method string, status int) bool {
+	if !isIdempotent(method) {
+		return false
+	}
+	codes := o.retryableStatusCodes
+	if codes == nil {
+		codes = defaultRetryableStatusCodes
+	}
+	return slices.Contains(codes, status)
+}
+
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+// backoff returns the full-jitter delay before retry number attempt
+// (starting at 0).
+func (o *clientOptions) backoff(attempt int) time.Duration {
+	ceiling := o.retryDelay
+	for i := 0; i < attempt; i++ {
+		if (o.maxRetryDelay > 0 && ceiling >= o.maxRetryDelay) || ceiling > math.MaxInt64/2 {
+			break
+		}
+		ceiling *= 2
+	}
+	if o.maxRetryDelay > 0 && ceiling > o.maxRetryDelay {
+		ceiling = o.maxRetryDelay
+	}
+	if ceiling <= 0 {
+		return 0
+	}
+	return rand.N(ceiling)
+}
+
+// capDelay bounds a server-requested delay by maxRetryDelay, if set.
+func (o *clientOptions) capDelay(d time.Duration) time.Duration {
+	if o.maxRetryDelay > 0 && d > o.maxRetryDelay {
+		return o.maxRetryDelay
+	}
+	return d
+}
+
+// retryAfter parses a Retry-After header given in seconds or as an HTTP
+// date.
+func retryAfter(resp *http.Response, now time.Time) (time.Duration, bool) {
+	value := resp.Header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return max(at.Sub(now), 0), true
+	}
+	return 0, false
+}
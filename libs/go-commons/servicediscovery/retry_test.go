@@ -0,0 +1,145 @@
+package servicediscovery
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func fastRetries(maxRetries int) ClientOption {
+	return WithRetryPolicy(RetryPolicy{
+		MaxRetries:     maxRetries,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     5 * time.Millisecond,
+	})
+}
+
+// failingServer answers status to the first failures requests and 200
+// afterwards.
+func failingServer(t *testing.T, failures int32, status int, header http.Header) (*httptest.Server, *atomic.Int32) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) <= failures {
+			for k, v := range header {
+				w.Header()[k] = v
+			}
+			w.WriteHeader(status)
+			return
+		}
+		_, _ = w.Write([]byte(`{"services": [], "count": 0}`))
+	}))
+	t.Cleanup(server.Close)
+	return server, &calls
+}
+
+func TestRetryOnRetryableStatus(t *testing.T) {
+	server, calls := failingServer(t, 2, http.StatusServiceUnavailable, nil)
+
+	_, err := NewClient(server.URL, fastRetries(3)).List(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, int32(3), calls.Load())
+}
+
+func TestRetryGivesUpWithLastResponse(t *testing.T) {
+	server, calls := failingServer(t, 10, http.StatusBadGateway, nil)
+
+	_, err := NewClient(server.URL, fastRetries(2)).List(context.Background())
+
+	assert.EqualError(t, err, "unexpected status code: 502")
+	assert.Equal(t, int32(3), calls.Load())
+}
+
+func TestNoStatusRetryForNonIdempotentMethods(t *testing.T) {
+	server, calls := failingServer(t, 10, http.StatusServiceUnavailable, nil)
+
+	_, err := NewClient(server.URL, fastRetries(3)).Register(context.Background(), &RegisterRequest{Name: "worker"})
+
+	assert.Error(t, err)
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestNoRetryOnClientErrors(t *testing.T) {
+	server, calls := failingServer(t, 10, http.StatusBadRequest, nil)
+
+	_, err := NewClient(server.URL, fastRetries(3)).List(context.Background())
+
+	assert.Error(t, err)
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestRetryHonoursRetryAfter(t *testing.T) {
+	server, calls := failingServer(t, 1, http.StatusServiceUnavailable, http.Header{"Retry-After": {"1"}})
+
+	start := time.Now()
+	_, err := NewClient(server.URL, WithRetryPolicy(RetryPolicy{
+		MaxRetries:     1,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     5 * time.Second,
+	})).List(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), calls.Load())
+	assert.GreaterOrEqual(t, time.Since(start), time.Second)
+}
+
+func TestRetryAfterIsCappedAtMaxBackoff(t *testing.T) {
+	server, calls := failingServer(t, 1, http.StatusServiceUnavailable, http.Header{"Retry-After": {"3600"}})
+
+	start := time.Now()
+	_, err := NewClient(server.URL, fastRetries(1)).List(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), calls.Load())
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestRetryStopsAtMaxElapsedTime(t *testing.T) {
+	server, calls := failingServer(t, 10, http.StatusServiceUnavailable, http.Header{"Retry-After": {"60"}})
+
+	client := NewClient(server.URL, WithRetryPolicy(RetryPolicy{
+		MaxRetries:     5,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     5 * time.Millisecond,
+		MaxElapsedTime: time.Second,
+	}))
+	_, err := client.List(context.Background())
+
+	assert.EqualError(t, err, "unexpected status code: 503")
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestRetryAbortsWhenContextIsDone(t *testing.T) {
+	server, _ := failingServer(t, 10, http.StatusServiceUnavailable, nil)
+
+	client := NewClient(server.URL, WithRetryPolicy(RetryPolicy{
+		MaxRetries:     5,
+		InitialBackoff: time.Minute,
+	}))
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := client.List(ctx)
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.ErrorIs(t, err, ErrConnectionFailed)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestBackoffIsBounded(t *testing.T) {
+	o := &clientOptions{retryDelay: 100 * time.Millisecond, maxRetryDelay: time.Second}
+
+	for attempt := 0; attempt < 100; attempt++ {
+		d := o.backoff(attempt)
+		assert.GreaterOrEqual(t, d, time.Duration(0))
+		assert.Less(t, d, time.Second)
+	}
+	assert.Less(t, o.backoff(0), 100*time.Millisecond)
+}
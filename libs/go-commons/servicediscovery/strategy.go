@@ -0,0 +1,67 @@
+package servicediscovery
+
+import (
+	"math/rand/v2"
+	"sync/atomic"
+)
+
+// Candidate is an instance eligible for a pick, together with the number of
+// calls the Resolver has handed it that have not been reported Done yet.
+type Candidate struct {
+	*Service
+	InFlight int64
+}
+
+// Strategy chooses one of a non-empty list of candidates. Candidates are
+// always passed in the same order for an unchanged instance set.
+type Strategy interface {
+	Pick(candidates []Candidate) *Service
+}
+
+type roundRobin struct {
+	next atomic.Uint64
+}
+
+func NewRoundRobin() Strategy {
+	return &roundRobin{}
+}
+
+func (s *roundRobin) Pick(candidates []Candidate) *Service {
+	n := s.next.Add(1) - 1
+	return candidates[n%uint64(len(candidates))].Service
+}
+
+type random struct{}
+
+func NewRandom() Strategy {
+	return random{}
+}
+
+func (random) Pick(candidates []Candidate) *Service {
+	return candidates[rand.IntN(len(candidates))].Service
+}
+
+type powerOfTwoChoices struct{}
+
+// NewPowerOfTwoChoices samples two candidates at random and picks the one
+// with fewer calls in flight.
+func NewPowerOfTwoChoices() Strategy {
+	return powerOfTwoChoices{}
+}
+
+func (powerOfTwoChoices) Pick(candidates []Candidate) *Service {
+	if len(candidates) == 1 {
+		return candidates[0].Service
+	}
+
+	i := rand.IntN(len(candidates))
+	j := rand.IntN(len(candidates) - 1)
+	if j >= i {
+		j++
+	}
+
+	if candidates[j].InFlight < candidates[i].InFlight {
+		return candidates[j].Service
+	}
+	return candidates[i].Service
+}
@@ -0,0 +1,46 @@
+package servicediscovery
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithTLSConfig(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	// The test server's certificate is not trusted by default.
+	require.Error(t, NewClient(server.URL, WithRetries(0)).Heartbeat(context.Background(), "test-id"))
+
+	roots := x509.NewCertPool()
+	roots.AddCert(server.Certificate())
+	client := NewClient(server.URL, WithTLSConfig(&tls.Config{RootCAs: roots}))
+	require.NoError(t, client.Heartbeat(context.Background(), "test-id"))
+}
+
+func TestWithHTTPClient(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	httpClient := server.Client()
+	client := NewClient(server.URL, WithHTTPClient(httpClient))
+	require.NoError(t, client.Heartbeat(context.Background(), "test-id"))
+
+	// WithTLSConfig applies on a copy, leaving the caller's client untouched.
+	transport := httpClient.Transport
+	client = NewClient(server.URL, WithHTTPClient(httpClient), WithTLSConfig(httpClient.Transport.(*http.Transport).TLSClientConfig))
+	require.NoError(t, client.Heartbeat(context.Background(), "test-id"))
+	assert.Same(t, transport, httpClient.Transport)
+	assert.NotSame(t, httpClient, client.httpClient)
+}
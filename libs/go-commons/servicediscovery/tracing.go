@@ -0,0 +1,54 @@
+package servicediscovery
+
+import (
+	"fmt"
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.39.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/carlosealves2/video-ia/go-commons/servicediscovery"
+
+// startSpan starts a client span for one attempt at req and writes its
+// context into req's headers, so the registry continues the caller's trace.
+func (c *Client) startSpan(req *http.Request, attempt int) (*http.Request, trace.Span) {
+	tp := c.options.tracerProvider
+	if tp == nil {
+		tp = otel.GetTracerProvider()
+	}
+
+	attrs := []trace.SpanStartOption{
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.HTTPRequestMethodKey.String(req.Method),
+			semconv.URLFull(req.URL.String()),
+			semconv.ServerAddress(req.URL.Hostname()),
+		),
+	}
+	if attempt > 0 {
+		attrs = append(attrs, trace.WithAttributes(semconv.HTTPRequestResendCount(attempt)))
+	}
+
+	ctx, span := tp.Tracer(tracerName).Start(req.Context(), req.Method, attrs...)
+	c.options.propagator.Inject(ctx, propagation.HeaderCarrier(req.Header))
+	return req.WithContext(ctx), span
+}
+
+func endSpan(span trace.Span, resp *http.Response, err error) {
+	defer span.End()
+
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return
+	}
+
+	span.SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode))
+	if resp.StatusCode >= http.StatusBadRequest {
+		span.SetStatus(codes.Error, fmt.Sprintf("%d %s", resp.StatusCode, http.StatusText(resp.StatusCode)))
+	}
+}
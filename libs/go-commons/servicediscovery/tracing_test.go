@@ -0,0 +1,50 @@
+package servicediscovery
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestRequestsPropagateTraceContext(t *testing.T) {
+	var traceparents []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparents = append(traceparents, r.Header.Get("traceparent"))
+		if len(traceparents) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	client := NewClient(server.URL, WithTracerProvider(tp), WithRetryDelay(0))
+
+	ctx, parent := tp.Tracer("test").Start(context.Background(), "startup")
+	require.NoError(t, client.Heartbeat(ctx, "test-id"))
+	parent.End()
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 3, "one span per attempt, plus the parent")
+	assert.Len(t, traceparents, 2)
+
+	for i, span := range spans[:2] {
+		assert.Equal(t, "PUT", span.Name)
+		assert.Equal(t, trace.SpanKindClient, span.SpanKind)
+		assert.Equal(t, parent.SpanContext().TraceID(), span.SpanContext.TraceID())
+		assert.Equal(t, parent.SpanContext().SpanID(), span.Parent.SpanID())
+		assert.Contains(t, traceparents[i], span.SpanContext.SpanID().String())
+	}
+	assert.Equal(t, codes.Error, spans[0].Status.Code)
+	assert.Equal(t, codes.Unset, spans[1].Status.Code)
+}
@@ -0,0 +1,180 @@
+package servicediscovery
+
+import (
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Scheme marks URLs that Transport resolves through the registry, e.g.
+// sd://video-processor/jobs.
+const Scheme = "sd"
+
+// Transport is an http.RoundTripper that sends requests addressed to a
+// service name to one of its instances. A request is resolved when its URL
+// uses the sd scheme, or when its host ends with the configured suffix; the
+// rest of the host names the service. The instance's protocol, host, port
+// and base path replace those parts of the URL. Other requests pass through
+// unchanged.
+//
+// When an instance cannot be dialled, the instance is ejected and the
+// request is retried on another one, provided its body can be replayed.
+type Transport struct {
+	client  *Client
+	options *transportOptions
+
+	mu        sync.Mutex
+	resolvers map[string]*Resolver
+}
+
+func NewTransport(client *Client, opts ...TransportOption) *Transport {
+	options := defaultTransportOptions()
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	return &Transport{
+		client:    client,
+		options:   options,
+		resolvers: make(map[string]*Resolver),
+	}
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	name, ok := t.serviceName(req)
+	if !ok {
+		return t.options.base.RoundTrip(req)
+	}
+
+	resolver := t.resolver(name)
+
+	var lastErr error
+	for attempt := 0; attempt < max(t.options.maxAttempts, 1); attempt++ {
+		instance, err := resolver.Pick(req.Context())
+		if err != nil {
+			closeBody(req)
+			if lastErr != nil {
+				return nil, lastErr
+			}
+			return nil, err
+		}
+
+		out, err := rewrite(req, instance, attempt > 0)
+		if err != nil {
+			closeBody(req)
+			resolver.Done(instance, nil)
+			return nil, err
+		}
+
+		resp, err := t.options.base.RoundTrip(out)
+		if err == nil {
+			resp.Body = &doneBody{ReadCloser: resp.Body, done: func() { resolver.Done(instance, nil) }}
+			return resp, nil
+		}
+
+		resolver.Done(instance, err)
+		lastErr = err
+
+		if !isDialError(err) || req.Context().Err() != nil || (req.Body != nil && req.GetBody == nil) {
+			return nil, err
+		}
+	}
+	return nil, lastErr
+}
+
+func (t *Transport) serviceName(req *http.Request) (string, bool) {
+	if req.URL.Scheme == Scheme {
+		return req.URL.Hostname(), true
+	}
+	if suffix := t.options.hostSuffix; suffix != "" {
+		host := req.URL.Hostname()
+		if name, ok := strings.CutSuffix(host, suffix); ok && name != "" {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+func (t *Transport) resolver(name string) *Resolver {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	r, ok := t.resolvers[name]
+	if !ok {
+		r = NewResolver(t.client, name, t.options.resolverOptions...)
+		t.resolvers[name] = r
+	}
+	return r
+}
+
+// rewrite returns a copy of req addressed to instance. A retried request
+// gets a fresh body from GetBody.
+// closeBody closes the request body when RoundTrip returns without handing
+// the request to the base transport, as the RoundTripper contract requires.
+func closeBody(req *http.Request) {
+	if req.Body != nil {
+		_ = req.Body.Close()
+	}
+}
+
+func rewrite(req *http.Request, instance *Service, retry bool) (*http.Request, error) {
+	out := req.Clone(req.Context())
+	out.Host = ""
+
+	if retry && req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		out.Body = body
+	}
+
+	out.URL.Scheme = instance.Protocol
+	if out.URL.Scheme == "" {
+		out.URL.Scheme = "http"
+	}
+	out.URL.Host = net.JoinHostPort(instance.Host, strconv.Itoa(instance.Port))
+
+	if base := strings.TrimSuffix(instance.BasePath, "/"); base != "" {
+		if !strings.HasPrefix(base, "/") {
+			base = "/" + base
+		}
+		out.URL.Path = base + ensureLeadingSlash(req.URL.Path)
+		if req.URL.RawPath != "" {
+			out.URL.RawPath = base + ensureLeadingSlash(req.URL.RawPath)
+		}
+	}
+	return out, nil
+}
+
+func ensureLeadingSlash(p string) string {
+	if strings.HasPrefix(p, "/") {
+		return p
+	}
+	return "/" + p
+}
+
+// isDialError reports whether err happened while connecting, so the request
+// never reached the instance and is safe to send elsewhere.
+func isDialError(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// doneBody reports the call to the resolver once the response body is
+// closed, so in-flight counts cover the whole exchange.
+type doneBody struct {
+	io.ReadCloser
+	once sync.Once
+	done func()
+}
+
+func (b *doneBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.done)
+	return err
+}
@@ -0,0 +1,130 @@
+package servicediscovery
+
+import (
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func instanceOf(t *testing.T, id, name, basePath string, server *httptest.Server) *Service {
+	host, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	require.NoError(t, err)
+	p, err := strconv.Atoi(port)
+	require.NoError(t, err)
+	return &Service{ID: id, Name: name, Host: host, Port: p, Protocol: "http", BasePath: basePath, Status: StatusHealthy}
+}
+
+func newCatalog(t *testing.T, services ...*Service) *Client {
+	registry := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var matched []*Service
+		for _, svc := range services {
+			if strings.Contains(svc.Name, r.URL.Query().Get("name")) {
+				matched = append(matched, svc)
+			}
+		}
+		_ = json.NewEncoder(w).Encode(&ListResponse{Services: matched, Count: len(matched)})
+	}))
+	t.Cleanup(registry.Close)
+	return NewClient(registry.URL, WithRetries(0))
+}
+
+func echoServer(t *testing.T, name string) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		_, _ = w.Write([]byte(name + " " + r.Method + " " + r.URL.RequestURI() + " " + string(body)))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func fetch(t *testing.T, client *http.Client, method, url, body string) string {
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	require.NoError(t, err)
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	defer func() { _ = resp.Body.Close() }()
+	out, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return string(out)
+}
+
+func TestTransportResolvesSchemeWithBasePath(t *testing.T) {
+	backend := echoServer(t, "processor")
+	client := newCatalog(t, instanceOf(t, "1", "video-processor", "/api/v1/", backend))
+
+	httpClient := &http.Client{Transport: NewTransport(client)}
+
+	assert.Equal(t, "processor GET /api/v1/jobs?id=7 ", fetch(t, httpClient, http.MethodGet, "sd://video-processor/jobs?id=7", ""))
+}
+
+func TestTransportResolvesHostSuffix(t *testing.T) {
+	backend := echoServer(t, "processor")
+	client := newCatalog(t, instanceOf(t, "1", "video-processor", "", backend))
+
+	httpClient := &http.Client{Transport: NewTransport(client, WithHostSuffix(".sd.internal"))}
+
+	assert.Equal(t, "processor GET /jobs ", fetch(t, httpClient, http.MethodGet, "http://video-processor.sd.internal/jobs", ""))
+}
+
+func TestTransportPassesOtherRequestsThrough(t *testing.T) {
+	backend := echoServer(t, "plain")
+	client := newCatalog(t)
+
+	httpClient := &http.Client{Transport: NewTransport(client, WithHostSuffix(".sd.internal"))}
+
+	assert.Equal(t, "plain GET /status ", fetch(t, httpClient, http.MethodGet, backend.URL+"/status", ""))
+}
+
+func TestTransportRetriesOnAnotherInstance(t *testing.T) {
+	dead := httptest.NewServer(http.NotFoundHandler())
+	deadInstance := instanceOf(t, "a", "video-processor", "", dead)
+	dead.Close()
+
+	backend := echoServer(t, "alive")
+	client := newCatalog(t, deadInstance, instanceOf(t, "b", "video-processor", "", backend))
+
+	httpClient := &http.Client{Transport: NewTransport(client)}
+
+	for i := 0; i < 4; i++ {
+		assert.Equal(t, "alive POST /jobs payload", fetch(t, httpClient, http.MethodPost, "sd://video-processor/jobs", "payload"))
+	}
+}
+
+func TestTransportNoInstance(t *testing.T) {
+	client := newCatalog(t)
+
+	httpClient := &http.Client{Transport: NewTransport(client)}
+	_, err := httpClient.Get("sd://video-processor/jobs")
+
+	assert.ErrorIs(t, err, ErrNoInstanceAvailable)
+}
+
+type trackedBody struct {
+	io.Reader
+	closed bool
+}
+
+func (b *trackedBody) Close() error {
+	b.closed = true
+	return nil
+}
+
+func TestTransportClosesBodyWhenNoInstance(t *testing.T) {
+	client := newCatalog(t)
+
+	body := &trackedBody{Reader: strings.NewReader("payload")}
+	req, err := http.NewRequest(http.MethodPost, "sd://video-processor/jobs", body)
+	require.NoError(t, err)
+	_, err = NewTransport(client).RoundTrip(req)
+
+	assert.ErrorIs(t, err, ErrNoInstanceAvailable)
+	assert.True(t, body.closed)
+}
@@ -0,0 +1,83 @@
+package servicediscovery
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// indexHeader carries the registry's catalog index on list and search
+// responses.
+const indexHeader = "X-Registry-Index"
+
+// defaultWatchWait is the wait sent when Watch is given none. It matches the
+// registry's own default, but being explicit lets the client time the
+// request out on top of it.
+const defaultWatchWait = 30 * time.Second
+
+// Watch performs a blocking Search: with a non-zero index the registry holds
+// the request until its catalog changes past index or wait elapses (30s when
+// wait is not positive). It returns the matching services with the catalog
+// index to pass to the next call. Watch is not retried and bypasses the stale
+// cache, so callers see failures as they happen.
+func (c *Client) Watch(ctx context.Context, q SearchQuery, index uint64, wait time.Duration) ([]*Service, uint64, error) {
+	if wait <= 0 {
+		wait = defaultWatchWait
+	}
+
+	params := q.values()
+	if index > 0 {
+		params.Set("index", strconv.FormatUint(index, 10))
+	}
+	params.Set("wait", wait.String())
+
+	path := "/api/v1/services/search?" + params.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+path, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	c.scope(req)
+	if err := c.authorize(req); err != nil {
+		return nil, 0, err
+	}
+	req, span := c.startSpan(req, 0)
+
+	// The request may be held for the whole wait, so the client timeout only
+	// bounds the time on top of it.
+	httpClient := *c.httpClient
+	if httpClient.Timeout > 0 {
+		httpClient.Timeout += wait
+	}
+
+	resp, err := httpClient.Do(req)
+	endSpan(span, resp, err)
+	if err != nil {
+		return nil, 0, fmt.Errorf("%w: %w", ErrConnectionFailed, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, 0, errorFromBody(resp.StatusCode, body)
+	}
+
+	var listResp ListResponse
+	if err := json.Unmarshal(body, &listResp); err != nil {
+		return nil, 0, err
+	}
+
+	next, err := strconv.ParseUint(resp.Header.Get(indexHeader), 10, 64)
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid %s header: %q", indexHeader, resp.Header.Get(indexHeader))
+	}
+	return listResp.Services, next, nil
+}
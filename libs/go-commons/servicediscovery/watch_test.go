@@ -0,0 +1,67 @@
+package servicediscovery
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/services/search", r.URL.Path)
+		assert.Equal(t, "encoder", r.URL.Query().Get("name"))
+		assert.Equal(t, "7", r.URL.Query().Get("index"))
+		assert.Equal(t, "30s", r.URL.Query().Get("wait"))
+
+		w.Header().Set("X-Registry-Index", "8")
+		_ = json.NewEncoder(w).Encode(ListResponse{Services: []*Service{{ID: "a", Name: "encoder"}}, Count: 1})
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	services, index, err := client.Watch(context.Background(), SearchQuery{Name: "encoder"}, 7, 30*time.Second)
+	require.NoError(t, err)
+	assert.Len(t, services, 1)
+	assert.Equal(t, uint64(8), index)
+}
+
+func TestWatchOutlastsClientTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+		w.Header().Set("X-Registry-Index", "2")
+		_ = json.NewEncoder(w).Encode(ListResponse{})
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, WithTimeout(50*time.Millisecond))
+	_, index, err := client.Watch(context.Background(), SearchQuery{Name: "encoder"}, 1, time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), index)
+}
+
+func TestWatchDefaultWait(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "30s", r.URL.Query().Get("wait"), "the wait is always explicit")
+		time.Sleep(100 * time.Millisecond)
+		w.Header().Set("X-Registry-Index", "2")
+		_ = json.NewEncoder(w).Encode(ListResponse{})
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, WithTimeout(50*time.Millisecond))
+	_, index, err := client.Watch(context.Background(), SearchQuery{Name: "encoder"}, 1, 0)
+	require.NoError(t, err, "the client timeout covers the default wait")
+	assert.Equal(t, uint64(2), index)
+}
+
+func TestWatchRegistryUnreachable(t *testing.T) {
+	client := NewClient("http://127.0.0.1:1")
+	_, _, err := client.Watch(context.Background(), SearchQuery{Name: "encoder"}, 0, time.Second)
+	assert.ErrorIs(t, err, ErrConnectionFailed)
+}
@@ -1,25 +1,64 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"log"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/carlosealves2/video-ia/service-discover/internal/bootstrap"
 	"github.com/carlosealves2/video-ia/service-discover/internal/config"
 )
 
 func main() {
-	cfg, err := config.NewBuilder().WithEnv().Validate().Build()
+	args := os.Args[1:]
+	cl, err := config.ParseCommandLine(args)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	cfg, err := config.NewBuilder().
+		WithFile(cl.ConfigFile).
+		WithEnv().
+		WithFlags(args).
+		Validate().
+		Build()
 	if err != nil {
 		log.Fatal(err)
 	}
 
+	if cl.PrintConfig {
+		if err := config.Print(os.Stdout, cfg); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	app := bootstrap.New(cfg).
 		InitLogger().
+		InitTracing().
+		InitMetrics().
 		InitRepository().
+		InitAuth().
+		InitTLS().
+		InitCluster().
 		InitHandlers().
+		InitHealthChecker().
+		InitHeartbeatReaper().
+		InitGateway().
+		InitDNS().
 		InitRouter()
 
-	if err := app.Run(); err != nil {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	err = app.Run(ctx)
+	stop()
+	if err != nil {
 		log.Fatal(err)
 	}
 }
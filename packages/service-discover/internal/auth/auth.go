@@ -0,0 +1,175 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/carlosealves2/video-ia/service-discover/internal/config"
+)
+
+type Scope string
+
+const (
+	ScopeRead  Scope = "read"
+	ScopeWrite Scope = "write"
+	// ScopeAdmin grants every other scope and lets the caller modify
+	// instances it does not own.
+	ScopeAdmin Scope = "admin"
+
+	// APIKeyHeader carries an API key; keys are also accepted as bearer
+	// tokens.
+	APIKeyHeader = "X-API-Key"
+
+	// credentialTTL is the lifetime of tokens minted by Credential.
+	credentialTTL = time.Minute
+)
+
+var (
+	ErrMissingCredentials = errors.New("missing credentials")
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	ErrInsufficientScope  = errors.New("insufficient scope")
+)
+
+// Principal is an authenticated caller.
+type Principal struct {
+	// Subject is the token's sub claim, or "api-key" for API keys.
+	Subject string
+	Scopes  []Scope
+}
+
+func (p Principal) Has(scope Scope) bool {
+	return slices.Contains(p.Scopes, scope) || slices.Contains(p.Scopes, ScopeAdmin)
+}
+
+type principalKey struct{}
+
+// NewContext returns a copy of ctx carrying p.
+func NewContext(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// FromContext returns the principal stored in ctx by NewContext, if any.
+func FromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(Principal)
+	return p, ok
+}
+
+// Authenticator checks the API keys and JWT bearer tokens configured in
+// config.AuthConfig.
+type Authenticator struct {
+	keys     map[[sha256.Size]byte][]Scope
+	adminKey string
+	jwt      *jwtVerifier
+	now      func() time.Time
+}
+
+func New(cfg config.AuthConfig) *Authenticator {
+	a := &Authenticator{
+		keys: make(map[[sha256.Size]byte][]Scope, len(cfg.APIKeys)),
+		now:  time.Now,
+	}
+	for _, key := range cfg.APIKeys {
+		scopes := make([]Scope, 0, len(key.Scopes))
+		for _, s := range key.Scopes {
+			scopes = append(scopes, Scope(s))
+		}
+		// Keys are looked up by digest so that lookups do not leak how much
+		// of a key matched.
+		a.keys[sha256.Sum256([]byte(key.Key))] = scopes
+		if a.adminKey == "" && slices.Contains(scopes, ScopeAdmin) {
+			a.adminKey = key.Key
+		}
+	}
+	if cfg.JWTSecret != "" {
+		a.jwt = &jwtVerifier{
+			secret:   []byte(cfg.JWTSecret),
+			issuer:   cfg.JWTIssuer,
+			audience: cfg.JWTAudience,
+		}
+	}
+	return a
+}
+
+// Authenticate identifies the caller of r from its X-API-Key header or its
+// Authorization bearer token.
+func (a *Authenticator) Authenticate(r *http.Request) (Principal, error) {
+	if key := r.Header.Get(APIKeyHeader); key != "" {
+		return a.apiKey(key)
+	}
+
+	token, ok := bearerToken(r)
+	if !ok {
+		return Principal{}, ErrMissingCredentials
+	}
+	if p, err := a.apiKey(token); err == nil {
+		return p, nil
+	}
+	if a.jwt == nil {
+		return Principal{}, ErrInvalidCredentials
+	}
+
+	claims, err := a.jwt.verify(token, a.now())
+	if err != nil {
+		return Principal{}, err
+	}
+	var scopes []Scope
+	for _, s := range strings.Fields(claims.Scope) {
+		scopes = append(scopes, Scope(s))
+	}
+	return Principal{Subject: claims.Subject, Scopes: scopes}, nil
+}
+
+func (a *Authenticator) apiKey(key string) (Principal, error) {
+	scopes, ok := a.keys[sha256.Sum256([]byte(key))]
+	if !ok {
+		return Principal{}, ErrInvalidCredentials
+	}
+	return Principal{Subject: "api-key", Scopes: scopes}, nil
+}
+
+// Credential returns an admin-scoped credential for requests the registry
+// makes to its own peers, such as writes forwarded to the cluster leader:
+// the first API key with the admin scope, or else a short-lived JWT.
+func (a *Authenticator) Credential() string {
+	if a.adminKey != "" {
+		return a.adminKey
+	}
+	if a.jwt == nil {
+		return ""
+	}
+	now := a.now()
+	claims := jwtClaims{
+		Subject:   "service-discover",
+		Issuer:    a.jwt.issuer,
+		Scope:     string(ScopeAdmin),
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(credentialTTL).Unix(),
+	}
+	if a.jwt.audience != "" {
+		claims.Audience = audience{a.jwt.audience}
+	}
+	return a.jwt.sign(claims)
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+// ScopeFor returns the scope a request method needs: reads for GET and
+// HEAD, writes for everything else.
+func ScopeFor(method string) Scope {
+	if method == http.MethodGet || method == http.MethodHead {
+		return ScopeRead
+	}
+	return ScopeWrite
+}
@@ -0,0 +1,109 @@
+package auth
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/carlosealves2/video-ia/service-discover/internal/config"
+)
+
+const secret = "0123456789abcdef0123456789abcdef"
+
+func newRequest(header, value string) *http.Request {
+	r, _ := http.NewRequest(http.MethodGet, "/api/v1/services/list", nil)
+	if header != "" {
+		r.Header.Set(header, value)
+	}
+	return r
+}
+
+func TestAuthenticateAPIKey(t *testing.T) {
+	a := New(config.AuthConfig{APIKeys: []config.APIKey{{Key: "reader", Scopes: []string{"read"}}}})
+
+	p, err := a.Authenticate(newRequest(APIKeyHeader, "reader"))
+	require.NoError(t, err)
+	assert.True(t, p.Has(ScopeRead))
+	assert.False(t, p.Has(ScopeWrite))
+
+	p, err = a.Authenticate(newRequest("Authorization", "Bearer reader"))
+	require.NoError(t, err)
+	assert.True(t, p.Has(ScopeRead))
+
+	_, err = a.Authenticate(newRequest(APIKeyHeader, "unknown"))
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+
+	_, err = a.Authenticate(newRequest("", ""))
+	assert.ErrorIs(t, err, ErrMissingCredentials)
+}
+
+func TestAuthenticateJWT(t *testing.T) {
+	a := New(config.AuthConfig{JWTSecret: secret, JWTIssuer: "video-ia", JWTAudience: "service-discover"})
+	now := time.Now()
+	v := &jwtVerifier{secret: []byte(secret)}
+
+	token := v.sign(jwtClaims{
+		Subject:   "uploader",
+		Issuer:    "video-ia",
+		Audience:  audience{"service-discover"},
+		Scope:     "read write",
+		ExpiresAt: now.Add(time.Hour).Unix(),
+	})
+	p, err := a.Authenticate(newRequest("Authorization", "Bearer "+token))
+	require.NoError(t, err)
+	assert.Equal(t, "uploader", p.Subject)
+	assert.True(t, p.Has(ScopeRead))
+	assert.True(t, p.Has(ScopeWrite))
+
+	invalid := map[string]string{
+		"expired": v.sign(jwtClaims{Issuer: "video-ia", Audience: audience{"service-discover"},
+			ExpiresAt: now.Add(-time.Hour).Unix()}),
+		"not yet valid": v.sign(jwtClaims{Issuer: "video-ia", Audience: audience{"service-discover"},
+			NotBefore: now.Add(time.Hour).Unix()}),
+		"wrong issuer":   v.sign(jwtClaims{Issuer: "other", Audience: audience{"service-discover"}}),
+		"wrong audience": v.sign(jwtClaims{Issuer: "video-ia", Audience: audience{"other"}}),
+		"wrong secret": (&jwtVerifier{secret: []byte("another-secret-another-secret-xx")}).
+			sign(jwtClaims{Issuer: "video-ia", Audience: audience{"service-discover"}}),
+		"unsigned": "eyJhbGciOiJub25lIn0.eyJzY29wZSI6InJlYWQifQ.",
+		"garbage":  "not-a-token",
+	}
+	for name, token := range invalid {
+		t.Run(name, func(t *testing.T) {
+			_, err := a.Authenticate(newRequest("Authorization", "Bearer "+token))
+			assert.ErrorIs(t, err, ErrInvalidCredentials)
+		})
+	}
+}
+
+func TestCredential(t *testing.T) {
+	withKey := New(config.AuthConfig{
+		APIKeys: []config.APIKey{
+			{Key: "reader", Scopes: []string{"read"}},
+			{Key: "writer", Scopes: []string{"write"}},
+			{Key: "ops", Scopes: []string{"admin"}},
+		},
+		JWTSecret: secret,
+	})
+	assert.Equal(t, "ops", withKey.Credential())
+
+	jwtOnly := New(config.AuthConfig{JWTSecret: secret, JWTAudience: "service-discover"})
+	p, err := jwtOnly.Authenticate(newRequest("Authorization", "Bearer "+jwtOnly.Credential()))
+	require.NoError(t, err)
+	assert.True(t, p.Has(ScopeAdmin))
+}
+
+func TestScopeFor(t *testing.T) {
+	assert.Equal(t, ScopeRead, ScopeFor(http.MethodGet))
+	assert.Equal(t, ScopeWrite, ScopeFor(http.MethodPost))
+	assert.Equal(t, ScopeWrite, ScopeFor(http.MethodDelete))
+}
+
+func TestAdminScopeGrantsAll(t *testing.T) {
+	p := Principal{Scopes: []Scope{ScopeAdmin}}
+	assert.True(t, p.Has(ScopeRead))
+	assert.True(t, p.Has(ScopeWrite))
+	assert.True(t, p.Has(ScopeAdmin))
+}
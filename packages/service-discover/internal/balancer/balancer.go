@@ -0,0 +1,183 @@
+package balancer
+
+import (
+	"errors"
+	"hash/crc32"
+	"math/rand/v2"
+	"net"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/carlosealves2/video-ia/service-discover/internal/domain"
+)
+
+type Strategy string
+
+const (
+	RoundRobin     Strategy = "round-robin"
+	Random         Strategy = "random"
+	Weighted       Strategy = "weighted"
+	ConsistentHash Strategy = "consistent-hash"
+
+	// WeightKey is the metadata entry read by the weighted strategy. Missing
+	// or malformed weights count as 1; a weight of 0 drains the instance.
+	WeightKey = "weight"
+
+	// replicas is the number of points each instance owns on the hash ring.
+	replicas = 64
+)
+
+var (
+	ErrUnknownStrategy   = errors.New("unknown load-balancing strategy")
+	ErrKeyRequired       = errors.New("consistent-hash strategy requires a key")
+	ErrNoHealthyInstance = errors.New("no healthy instance available")
+)
+
+func ParseStrategy(s string) (Strategy, error) {
+	switch strategy := Strategy(s); strategy {
+	case "":
+		return RoundRobin, nil
+	case RoundRobin, Random, Weighted, ConsistentHash:
+		return strategy, nil
+	default:
+		return "", ErrUnknownStrategy
+	}
+}
+
+// Balancer picks one instance out of a service's healthy instances. It keeps
+// round-robin cursors per service name so successive calls rotate.
+type Balancer struct {
+	mu      sync.Mutex
+	cursors map[string]*atomic.Uint64
+}
+
+func New() *Balancer {
+	return &Balancer{
+		cursors: make(map[string]*atomic.Uint64),
+	}
+}
+
+// Pick chooses an instance of name using strategy. Unhealthy instances are
+// ignored; key is only used by ConsistentHash.
+func (b *Balancer) Pick(name string, strategy Strategy, key string, instances []*domain.Service) (*domain.Service, error) {
+	if strategy == ConsistentHash && key == "" {
+		return nil, ErrKeyRequired
+	}
+
+	healthy := make([]*domain.Service, 0, len(instances))
+	for _, svc := range instances {
+		if svc.Status == domain.StatusHealthy {
+			healthy = append(healthy, svc)
+		}
+	}
+	if len(healthy) == 0 {
+		return nil, ErrNoHealthyInstance
+	}
+
+	// Repositories return instances in no particular order; sorting keeps
+	// round-robin rotation and weighted draws independent of it.
+	sort.Slice(healthy, func(i, j int) bool { return healthy[i].ID < healthy[j].ID })
+
+	switch strategy {
+	case RoundRobin:
+		n := b.cursor(name).Add(1) - 1
+		return healthy[n%uint64(len(healthy))], nil
+	case Random:
+		return healthy[rand.IntN(len(healthy))], nil
+	case Weighted:
+		return pickWeighted(healthy)
+	case ConsistentHash:
+		return pickHashed(healthy, key), nil
+	default:
+		return nil, ErrUnknownStrategy
+	}
+}
+
+func (b *Balancer) cursor(name string) *atomic.Uint64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	c, ok := b.cursors[name]
+	if !ok {
+		c = &atomic.Uint64{}
+		b.cursors[name] = c
+	}
+	return c
+}
+
+// Retain drops the round-robin cursors of names not in live, so services
+// that left the catalog do not keep their cursor forever.
+func (b *Balancer) Retain(live map[string]bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for name := range b.cursors {
+		if !live[name] {
+			delete(b.cursors, name)
+		}
+	}
+}
+
+func weight(svc *domain.Service) int {
+	raw, ok := svc.Metadata[WeightKey]
+	if !ok {
+		return 1
+	}
+	w, err := strconv.Atoi(raw)
+	if err != nil || w < 0 {
+		return 1
+	}
+	return w
+}
+
+func pickWeighted(instances []*domain.Service) (*domain.Service, error) {
+	total := 0
+	for _, svc := range instances {
+		total += weight(svc)
+	}
+	if total == 0 {
+		return nil, ErrNoHealthyInstance
+	}
+
+	n := rand.IntN(total)
+	for _, svc := range instances {
+		n -= weight(svc)
+		if n < 0 {
+			return svc, nil
+		}
+	}
+	return instances[len(instances)-1], nil
+}
+
+type point struct {
+	hash     uint32
+	instance *domain.Service
+}
+
+// pickHashed places every instance on a hash ring at several points and
+// returns the owner of the first point at or after the key's hash. Adding or
+// removing an instance only moves the keys adjacent to its points. Points
+// are derived from host and port rather than the ID so that an instance that
+// re-registers keeps its keys.
+func pickHashed(instances []*domain.Service, key string) *domain.Service {
+	ring := make([]point, 0, len(instances)*replicas)
+	for _, svc := range instances {
+		addr := net.JoinHostPort(svc.Host, strconv.Itoa(svc.Port))
+		for i := 0; i < replicas; i++ {
+			ring = append(ring, point{
+				hash:     crc32.ChecksumIEEE([]byte(addr + "#" + strconv.Itoa(i))),
+				instance: svc,
+			})
+		}
+	}
+	sort.Slice(ring, func(i, j int) bool { return ring[i].hash < ring[j].hash })
+
+	h := crc32.ChecksumIEEE([]byte(key))
+	i := sort.Search(len(ring), func(i int) bool { return ring[i].hash >= h })
+	if i == len(ring) {
+		i = 0
+	}
+	return ring[i].instance
+}
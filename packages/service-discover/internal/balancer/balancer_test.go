@@ -0,0 +1,138 @@
+package balancer
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/carlosealves2/video-ia/service-discover/internal/domain"
+)
+
+func instance(id string, status domain.ServiceStatus, metadata map[string]string) *domain.Service {
+	return &domain.Service{
+		ID:       id,
+		Name:     "transcoder",
+		Host:     "10.0.0." + id,
+		Port:     8080,
+		Status:   status,
+		Metadata: metadata,
+	}
+}
+
+func TestParseStrategy(t *testing.T) {
+	s, err := ParseStrategy("")
+	require.NoError(t, err)
+	assert.Equal(t, RoundRobin, s)
+
+	s, err = ParseStrategy("consistent-hash")
+	require.NoError(t, err)
+	assert.Equal(t, ConsistentHash, s)
+
+	_, err = ParseStrategy("least-connections")
+	assert.ErrorIs(t, err, ErrUnknownStrategy)
+}
+
+func TestPickRoundRobinSkipsUnhealthy(t *testing.T) {
+	b := New()
+	instances := []*domain.Service{
+		instance("2", domain.StatusHealthy, nil),
+		instance("1", domain.StatusHealthy, nil),
+		instance("3", domain.StatusUnhealthy, nil),
+	}
+
+	var picked []string
+	for i := 0; i < 4; i++ {
+		svc, err := b.Pick("transcoder", RoundRobin, "", instances)
+		require.NoError(t, err)
+		picked = append(picked, svc.ID)
+	}
+
+	assert.Equal(t, []string{"1", "2", "1", "2"}, picked)
+}
+
+func TestPickNoHealthyInstance(t *testing.T) {
+	b := New()
+
+	_, err := b.Pick("transcoder", Random, "", []*domain.Service{instance("1", domain.StatusUnhealthy, nil)})
+	assert.ErrorIs(t, err, ErrNoHealthyInstance)
+
+	_, err = b.Pick("transcoder", RoundRobin, "", nil)
+	assert.ErrorIs(t, err, ErrNoHealthyInstance)
+}
+
+func TestRetainDropsCursorsOfRemovedNames(t *testing.T) {
+	b := New()
+	instances := []*domain.Service{
+		instance("1", domain.StatusHealthy, nil),
+		instance("2", domain.StatusHealthy, nil),
+	}
+
+	_, err := b.Pick("transcoder", RoundRobin, "", instances)
+	require.NoError(t, err)
+	_, err = b.Pick("thumbnailer", RoundRobin, "", instances)
+	require.NoError(t, err)
+
+	b.Retain(map[string]bool{"transcoder": true})
+
+	assert.Len(t, b.cursors, 1)
+	svc, err := b.Pick("transcoder", RoundRobin, "", instances)
+	require.NoError(t, err)
+	assert.Equal(t, "2", svc.ID, "retained cursors keep their position")
+}
+
+func TestPickWeighted(t *testing.T) {
+	b := New()
+	instances := []*domain.Service{
+		instance("1", domain.StatusHealthy, map[string]string{WeightKey: "3"}),
+		instance("2", domain.StatusHealthy, map[string]string{WeightKey: "1"}),
+		instance("3", domain.StatusHealthy, map[string]string{WeightKey: "0"}),
+	}
+
+	seen := map[string]int{}
+	for i := 0; i < 4000; i++ {
+		svc, err := b.Pick("transcoder", Weighted, "", instances)
+		require.NoError(t, err)
+		seen[svc.ID]++
+	}
+
+	assert.Zero(t, seen["3"], "weight 0 drains the instance")
+	assert.InDelta(t, 3.0, float64(seen["1"])/float64(seen["2"]), 0.6)
+
+	_, err := b.Pick("transcoder", Weighted, "", instances[2:])
+	assert.ErrorIs(t, err, ErrNoHealthyInstance)
+}
+
+func TestPickConsistentHash(t *testing.T) {
+	b := New()
+	instances := []*domain.Service{
+		instance("1", domain.StatusHealthy, nil),
+		instance("2", domain.StatusHealthy, nil),
+		instance("3", domain.StatusHealthy, nil),
+	}
+
+	_, err := b.Pick("transcoder", ConsistentHash, "", instances)
+	assert.ErrorIs(t, err, ErrKeyRequired)
+
+	owners := map[string]string{}
+	for i := 0; i < 200; i++ {
+		key := "video-" + strconv.Itoa(i)
+		svc, err := b.Pick("transcoder", ConsistentHash, key, instances)
+		require.NoError(t, err)
+		owners[key] = svc.ID
+
+		again, err := b.Pick("transcoder", ConsistentHash, key, instances)
+		require.NoError(t, err)
+		assert.Equal(t, svc.ID, again.ID)
+	}
+
+	// Losing instance 3 only moves the keys it owned.
+	for key, owner := range owners {
+		svc, err := b.Pick("transcoder", ConsistentHash, key, instances[:2])
+		require.NoError(t, err)
+		if owner != "3" {
+			assert.Equal(t, owner, svc.ID, key)
+		}
+	}
+}
@@ -1,24 +1,60 @@
 package bootstrap
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net/http"
+	"sync"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 
+	"github.com/carlosealves2/video-ia/service-discover/internal/auth"
+	"github.com/carlosealves2/video-ia/service-discover/internal/certs"
+	"github.com/carlosealves2/video-ia/service-discover/internal/cluster"
 	"github.com/carlosealves2/video-ia/service-discover/internal/config"
+	"github.com/carlosealves2/video-ia/service-discover/internal/dnsserver"
+	"github.com/carlosealves2/video-ia/service-discover/internal/events"
+	"github.com/carlosealves2/video-ia/service-discover/internal/gateway"
 	"github.com/carlosealves2/video-ia/service-discover/internal/handler"
+	"github.com/carlosealves2/video-ia/service-discover/internal/health"
 	"github.com/carlosealves2/video-ia/service-discover/internal/logger"
+	"github.com/carlosealves2/video-ia/service-discover/internal/metrics"
 	"github.com/carlosealves2/video-ia/service-discover/internal/middleware"
 	"github.com/carlosealves2/video-ia/service-discover/internal/repository"
+	"github.com/carlosealves2/video-ia/service-discover/internal/tracing"
 )
 
 type App struct {
-	config  *config.Config
-	logger  *zap.Logger
-	router  *gin.Engine
-	repo    repository.ServiceRepository
-	handler *handler.ServiceHandler
+	config *config.Config
+	logger *zap.Logger
+	router *gin.Engine
+	repo   repository.ServiceRepository
+	index  *repository.IndexedRepository
+	broker *events.Broker
+	node   *cluster.Node
+	auth   *auth.Authenticator
+	certs  *certs.Reloader
+
+	// closeRepository releases the storage backend, when it holds any.
+	closeRepository func() error
+
+	metrics        *metrics.Metrics
+	tracerProvider trace.TracerProvider
+	stopTracing    func(context.Context) error
+
+	handler        *handler.ServiceHandler
+	eventHandler   *handler.EventHandler
+	resolveHandler *handler.ResolveHandler
+	clusterHandler *handler.ClusterHandler
+
+	checker *health.Checker
+	reaper  *health.Reaper
+	gateway *gateway.Gateway
+	dns     *dnsserver.Server
 }
 
 func New(cfg *config.Config) *App {
@@ -36,13 +72,183 @@ func (a *App) InitLogger() *App {
 	return a
 }
 
+func (a *App) InitTracing() *App {
+	if !a.config.Tracing.Enabled {
+		return a
+	}
+
+	tp, err := tracing.New(context.Background(), a.config.Tracing)
+	if err != nil {
+		panic(fmt.Sprintf("failed to set up tracing: %v", err))
+	}
+	a.tracerProvider = tp
+	a.stopTracing = tp.Shutdown
+
+	a.logger.Info("Tracing enabled",
+		zap.String("otlp_endpoint", a.config.Tracing.Endpoint),
+	)
+	return a
+}
+
+// WithTracerProvider traces requests with tp instead of a provider built
+// from the configuration, e.g. to record spans in tests.
+func (a *App) WithTracerProvider(tp trace.TracerProvider) *App {
+	a.tracerProvider = tp
+	return a
+}
+
+// InitMetrics must run before InitRepository, which wraps the catalog to
+// record its activity.
+func (a *App) InitMetrics() *App {
+	if a.config.Metrics.Enabled {
+		a.metrics = metrics.New()
+	}
+	return a
+}
+
 func (a *App) InitRepository() *App {
-	a.repo = repository.NewMemoryRepository()
+	var base repository.ServiceRepository
+	switch a.config.Storage.Backend {
+	case "bolt":
+		repo, err := repository.NewBoltRepository(a.config.Storage.Path, a.logger)
+		if err != nil {
+			panic(fmt.Sprintf("failed to open bolt repository: %v", err))
+		}
+		base = repo
+		a.closeRepository = repo.Close
+	default:
+		base = repository.NewMemoryRepository()
+	}
+	if a.metrics != nil {
+		base = metrics.NewRepository(base, a.metrics)
+	}
+
+	a.broker = events.NewBroker(a.config.Events.BufferSize)
+	a.index = repository.NewIndexedRepository(events.NewRepository(base, a.broker))
+	a.repo = a.index
+
+	a.logger.Info("Repository initialized",
+		zap.String("backend", a.config.Storage.Backend),
+	)
+	return a
+}
+
+func (a *App) InitAuth() *App {
+	if !a.config.Auth.Enabled {
+		return a
+	}
+
+	a.auth = auth.New(a.config.Auth)
+
+	a.logger.Info("API authentication enabled",
+		zap.Int("api_keys", len(a.config.Auth.APIKeys)),
+		zap.Bool("jwt", a.config.Auth.JWTSecret != ""),
+	)
+	return a
+}
+
+func (a *App) InitTLS() *App {
+	if !a.config.TLS.Enabled {
+		return a
+	}
+
+	reloader, err := certs.NewReloader(a.config.TLS, a.logger)
+	if err != nil {
+		panic(fmt.Sprintf("failed to load TLS certificates: %v", err))
+	}
+	a.certs = reloader
+
+	a.logger.Info("TLS enabled",
+		zap.String("cert_file", a.config.TLS.CertFile),
+		zap.String("client_auth", a.config.TLS.ClientAuth),
+	)
+	return a
+}
+
+func (a *App) InitCluster() *App {
+	if !a.config.Cluster.Enabled {
+		return a
+	}
+
+	// Raft peers authenticate each other with the API certificates, which
+	// needs a CA bundle to verify them against.
+	var transportTLS *cluster.TransportTLS
+	if a.certs != nil && a.config.TLS.ClientCAFile != "" {
+		transportTLS = &cluster.TransportTLS{
+			Server: a.certs.PeerServerConfig(),
+			Client: a.certs.ClientConfig,
+		}
+	}
+
+	node, err := cluster.NewNode(a.config.Cluster, a.repo, transportTLS, a.logger)
+	if err != nil {
+		panic(fmt.Sprintf("failed to start cluster node: %v", err))
+	}
+	a.node = node
+	a.repo = node.Repository()
+	if a.auth != nil {
+		node.SetCredentials(a.auth.Credential)
+	}
+	if a.certs != nil {
+		node.SetTLSConfig(a.certs.ClientConfig)
+	}
+
+	a.logger.Info("Cluster node started",
+		zap.String("node_id", a.config.Cluster.NodeID),
+		zap.Int("peers", len(a.config.Cluster.Peers)),
+		zap.Bool("transport_tls", transportTLS != nil),
+	)
 	return a
 }
 
 func (a *App) InitHandlers() *App {
-	a.handler = handler.NewServiceHandler(a.repo, a.logger)
+	a.handler = handler.NewServiceHandler(a.repo, a.index, a.logger)
+	if a.auth != nil {
+		a.handler.RequireOwnership()
+	}
+	if a.config.HealthCheck.Enabled {
+		a.handler.DeferToHealthChecks()
+	}
+	a.eventHandler = handler.NewEventHandler(a.broker, a.logger)
+	a.resolveHandler = handler.NewResolveHandler(a.repo, a.logger)
+	if a.node != nil {
+		a.clusterHandler = handler.NewClusterHandler(a.node, a.logger)
+	}
+	return a
+}
+
+func (a *App) InitHealthChecker() *App {
+	if a.config.HealthCheck.Enabled {
+		a.checker = health.NewChecker(a.repo, a.logger, a.config.HealthCheck)
+		if a.node != nil {
+			a.checker.OnlyWhen(a.node.IsLeader)
+		}
+	}
+	return a
+}
+
+func (a *App) InitHeartbeatReaper() *App {
+	a.reaper = health.NewReaper(a.repo, a.logger, a.config.Heartbeat)
+	if a.node != nil {
+		a.reaper.OnlyWhen(a.node.IsLeader)
+	}
+	if a.checker != nil {
+		a.checker.KeepExpired(a.reaper.Expired)
+	}
+	return a
+}
+
+func (a *App) InitGateway() *App {
+	if a.config.Gateway.Enabled {
+		a.gateway = gateway.New(a.repo, a.index, a.logger, a.config.Gateway.Namespace, a.config.Gateway.Timeout)
+	}
+	return a
+}
+
+func (a *App) InitDNS() *App {
+	if a.config.DNS.Enabled {
+		a.dns = dnsserver.NewServer(a.repo, a.logger, a.config.DNS)
+	}
 	return a
 }
 
@@ -51,38 +257,214 @@ func (a *App) InitRouter() *App {
 
 	router := gin.New()
 	router.Use(gin.Recovery())
+	if a.tracerProvider != nil {
+		router.Use(middleware.Tracing(a.tracerProvider, tracing.Propagator))
+	}
 	router.Use(middleware.Logging(a.logger))
+	if a.metrics != nil {
+		router.Use(middleware.Metrics(a.metrics))
+	}
 
 	router.GET("/health", func(c *gin.Context) {
 		c.JSON(200, gin.H{"status": "ok"})
 	})
 
-	api := router.Group("/api/v1")
-	{
-		services := api.Group("/services")
+	// Everything registered on protected requires credentials; /health
+	// stays open for probes.
+	protected := router.Group("")
+	if a.auth != nil {
+		protected.Use(middleware.Auth(a.auth, a.logger))
+	}
+
+	if a.metrics != nil {
+		protected.GET("/metrics", gin.WrapH(a.metrics.Handler()))
+	}
+
+	if a.clusterHandler != nil {
+		// Applied commands bypass the API's ownership and certificate
+		// checks, so only admins and peers (see auth.Credential) may send
+		// them.
+		internal := protected.Group("/internal/cluster")
+		if a.auth != nil {
+			internal.Use(middleware.RequireScope(auth.ScopeAdmin, a.logger))
+		}
 		{
-			services.POST("/register", a.handler.Register)
-			services.GET("/list", a.handler.List)
-			services.GET("/search", a.handler.Search)
-			services.GET("/:id", a.handler.Get)
-			services.PUT("/:id/update", a.handler.Update)
-			services.DELETE("/:id/unregister", a.handler.Unregister)
-			services.PUT("/:id/heartbeat", a.handler.Heartbeat)
+			internal.POST("/apply", a.clusterHandler.Apply)
+			internal.GET("/status", a.clusterHandler.Status)
 		}
 	}
 
+	// The API is served both at /api/v1, in the namespace named by the
+	// X-Namespace header or the default one, and at
+	// /api/v1/namespaces/:namespace.
+	api := protected.Group("/api/v1")
+	a.apiRoutes(api.Group("", handler.Namespace()))
+	a.apiRoutes(api.Group("/namespaces/:namespace", handler.Namespace()))
+
 	a.router = router
 	return a
 }
 
-func (a *App) Run() error {
+func (a *App) apiRoutes(api *gin.RouterGroup) {
+	services := api.Group("/services")
+	{
+		services.POST("/register", a.handler.Register)
+		services.GET("/list", a.handler.List)
+		services.GET("/search", a.handler.Search)
+		services.GET("/events", a.eventHandler.Stream)
+		services.GET("/:id", a.handler.Get)
+		services.PUT("/:id/update", a.handler.Update)
+		services.DELETE("/:id/unregister", a.handler.Unregister)
+		services.PUT("/:id/heartbeat", a.handler.Heartbeat)
+	}
+
+	api.GET("/resolve/:name", a.resolveHandler.Resolve)
+}
+
+// Run serves the API, and the gateway and DNS server when enabled, until
+// ctx is done or the API server fails. It then stops accepting connections,
+// gives in-flight requests up to Server.ShutdownTimeout to complete, and
+// stops the background workers, the cluster node and the repository.
+func (a *App) Run(ctx context.Context) error {
 	a.logger.Info("Starting service-discover",
 		zap.Int("port", a.config.Port),
 		zap.String("log_level", a.config.LogLevel),
 	)
+	defer a.stop()
+
+	if a.checker != nil {
+		a.checker.Start(context.Background())
+	}
+	if a.reaper != nil {
+		a.reaper.Start(context.Background())
+	}
+	if a.certs != nil {
+		a.certs.Start(context.Background())
+	}
+
+	if a.dns != nil {
+		if err := a.dns.Start(); err != nil {
+			return err
+		}
+	}
+
+	server := a.httpServer(a.config.Port, a.router)
+	// Blocking queries and event streams would otherwise keep their
+	// connections busy until the drain deadline.
+	server.RegisterOnShutdown(a.handler.Close)
+	server.RegisterOnShutdown(a.eventHandler.Close)
+	servers := []*http.Server{server}
+
+	failed := make(chan error, 1)
+	go func() {
+		var err error
+		if a.certs != nil {
+			server.TLSConfig = a.certs.ServerConfig()
+			err = server.ListenAndServeTLS("", "")
+		} else {
+			err = server.ListenAndServe()
+		}
+		if !errors.Is(err, http.ErrServerClosed) {
+			failed <- err
+		}
+	}()
+
+	if a.gateway != nil {
+		gatewayServer := a.httpServer(a.config.Gateway.Port, a.gateway)
+		servers = append(servers, gatewayServer)
+		go func() {
+			a.logger.Info("Starting gateway", zap.Int("port", a.config.Gateway.Port))
+			if err := gatewayServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				a.logger.Error("Gateway stopped", zap.Error(err))
+			}
+		}()
+	}
+
+	var err error
+	select {
+	case <-ctx.Done():
+		a.logger.Info("Shutting down, draining connections",
+			zap.Duration("timeout", a.config.Server.ShutdownTimeout),
+		)
+	case err = <-failed:
+	}
+
+	a.drain(servers)
+	return err
+}
+
+func (a *App) httpServer(port int, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:              fmt.Sprintf(":%d", port),
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       a.config.Server.ReadTimeout,
+		WriteTimeout:      a.config.Server.WriteTimeout,
+		IdleTimeout:       a.config.Server.IdleTimeout,
+	}
+}
+
+// drain shuts the servers down together, waiting for their in-flight
+// requests until Server.ShutdownTimeout; connections still open after that
+// are closed.
+func (a *App) drain(servers []*http.Server) {
+	ctx, cancel := context.WithTimeout(context.Background(), a.config.Server.ShutdownTimeout)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for _, server := range servers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := server.Shutdown(ctx); err != nil {
+				a.logger.Warn("Connections not drained in time, closing them",
+					zap.String("addr", server.Addr),
+					zap.Error(err),
+				)
+				_ = server.Close()
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// stop runs once the servers are down, so that nothing writes to the
+// repository after it is closed.
+func (a *App) stop() {
+	if a.dns != nil {
+		a.dns.Stop()
+	}
+	if a.checker != nil {
+		a.checker.Stop()
+	}
+	if a.reaper != nil {
+		a.reaper.Stop()
+	}
+	if a.certs != nil {
+		a.certs.Stop()
+	}
+
+	if a.node != nil {
+		if err := a.node.Shutdown(); err != nil {
+			a.logger.Warn("Failed to shut down cluster node", zap.Error(err))
+		}
+	}
+	if a.closeRepository != nil {
+		if err := a.closeRepository(); err != nil {
+			a.logger.Warn("Failed to close repository", zap.Error(err))
+		}
+	}
+
+	if a.stopTracing != nil {
+		// Flush the spans still buffered by the batcher.
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := a.stopTracing(ctx); err != nil {
+			a.logger.Warn("Failed to flush traces", zap.Error(err))
+		}
+	}
 
-	addr := fmt.Sprintf(":%d", a.config.Port)
-	return a.router.Run(addr)
+	a.logger.Info("Shutdown complete")
 }
 
 func (a *App) GetRouter() *gin.Engine {
@@ -0,0 +1,39 @@
+package certs
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"path"
+	"slices"
+)
+
+// Identities returns the service names a client certificate speaks for: its
+// subject common name, its DNS SANs and the last path segment of its URI
+// SANs, so that spiffe://video-ia/ns/prod/sa/uploader maps to "uploader".
+func Identities(cert *x509.Certificate) []string {
+	var names []string
+	if cert.Subject.CommonName != "" {
+		names = append(names, cert.Subject.CommonName)
+	}
+	names = append(names, cert.DNSNames...)
+	for _, uri := range cert.URIs {
+		if name := path.Base(uri.Path); name != "." && name != "/" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// VerifiedClient returns the client certificate of a connection whose chain
+// was verified, if any.
+func VerifiedClient(state *tls.ConnectionState) (*x509.Certificate, bool) {
+	if state == nil || len(state.VerifiedChains) == 0 || len(state.VerifiedChains[0]) == 0 {
+		return nil, false
+	}
+	return state.VerifiedChains[0][0], true
+}
+
+// Allows reports whether cert may register a service called name.
+func Allows(cert *x509.Certificate, name string) bool {
+	return slices.Contains(Identities(cert), name)
+}
@@ -0,0 +1,228 @@
+package certs
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/carlosealves2/video-ia/service-discover/internal/config"
+)
+
+// Reloader serves the certificate, key and client CA bundle configured in
+// config.TLSConfig, re-reading them when the files change so certificates
+// can be rotated without a restart. A failed reload keeps the previous
+// material in use.
+type Reloader struct {
+	cfg        config.TLSConfig
+	clientAuth tls.ClientAuthType
+	logger     *zap.Logger
+
+	mu        sync.RWMutex
+	cert      *tls.Certificate
+	clientCAs *x509.CertPool
+	stamps    map[string]fileStamp
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// NewReloader loads the configured files, failing if they are unusable.
+func NewReloader(cfg config.TLSConfig, logger *zap.Logger) (*Reloader, error) {
+	r := &Reloader{
+		cfg:    cfg,
+		logger: logger,
+	}
+
+	switch cfg.ClientAuth {
+	case "", "none":
+		r.clientAuth = tls.NoClientCert
+	case "optional":
+		r.clientAuth = tls.VerifyClientCertIfGiven
+	case "require":
+		r.clientAuth = tls.RequireAndVerifyClientCert
+	default:
+		return nil, fmt.Errorf("unknown client auth mode %q", cfg.ClientAuth)
+	}
+
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// ServerConfig returns a TLS configuration that always uses the latest
+// loaded material.
+func (r *Reloader) ServerConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			r.mu.RLock()
+			defer r.mu.RUnlock()
+
+			return &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				Certificates: []tls.Certificate{*r.cert},
+				ClientAuth:   r.clientAuth,
+				ClientCAs:    r.clientCAs,
+			}, nil
+		},
+	}
+}
+
+// PeerServerConfig is like ServerConfig, but always requires a client
+// certificate signed by the client CA bundle, whatever the configured
+// client auth mode. It secures traffic that only cluster peers may send.
+func (r *Reloader) PeerServerConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			r.mu.RLock()
+			defer r.mu.RUnlock()
+
+			return &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				Certificates: []tls.Certificate{*r.cert},
+				ClientAuth:   tls.RequireAndVerifyClientCert,
+				ClientCAs:    r.clientCAs,
+			}, nil
+		},
+	}
+}
+
+// ClientConfig returns a TLS configuration for calls to cluster peers: it
+// presents the server certificate, and trusts the client CA bundle (or the
+// system roots without one) as loaded at the time of the call.
+func (r *Reloader) ClientConfig() *tls.Config {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		RootCAs:    r.clientCAs,
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			r.mu.RLock()
+			defer r.mu.RUnlock()
+			return r.cert, nil
+		},
+	}
+}
+
+// Start checks the files for changes every ReloadInterval until Stop.
+func (r *Reloader) Start(ctx context.Context) {
+	ctx, r.cancel = context.WithCancel(ctx)
+	r.done = make(chan struct{})
+
+	go func() {
+		defer close(r.done)
+
+		ticker := time.NewTicker(r.cfg.ReloadInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				r.check()
+			}
+		}
+	}()
+}
+
+func (r *Reloader) Stop() {
+	if r.cancel == nil {
+		return
+	}
+	r.cancel()
+	<-r.done
+}
+
+// check reloads the material when any file changed since the last load.
+func (r *Reloader) check() {
+	r.mu.RLock()
+	changed := false
+	for path, stamp := range r.stamps {
+		current, err := stat(path)
+		if err != nil || current != stamp {
+			changed = true
+			break
+		}
+	}
+	r.mu.RUnlock()
+
+	if !changed {
+		return
+	}
+	if err := r.load(); err != nil {
+		r.logger.Error("Failed to reload TLS certificates, keeping the current ones",
+			zap.String("cert_file", r.cfg.CertFile),
+			zap.Error(err),
+		)
+		return
+	}
+	r.logger.Info("TLS certificates reloaded",
+		zap.String("cert_file", r.cfg.CertFile),
+	)
+}
+
+func (r *Reloader) load() error {
+	paths := []string{r.cfg.CertFile, r.cfg.KeyFile}
+	if r.cfg.ClientCAFile != "" {
+		paths = append(paths, r.cfg.ClientCAFile)
+	}
+
+	// Stamps are taken before reading, so a write racing with the load is
+	// seen as a change on the next check.
+	stamps := make(map[string]fileStamp, len(paths))
+	for _, path := range paths {
+		stamp, err := stat(path)
+		if err != nil {
+			return err
+		}
+		stamps[path] = stamp
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.cfg.CertFile, r.cfg.KeyFile)
+	if err != nil {
+		return fmt.Errorf("load key pair: %w", err)
+	}
+
+	var clientCAs *x509.CertPool
+	if r.cfg.ClientCAFile != "" {
+		pem, err := os.ReadFile(r.cfg.ClientCAFile)
+		if err != nil {
+			return err
+		}
+		clientCAs = x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(pem) {
+			return errors.New("client CA file contains no certificates")
+		}
+	}
+
+	r.mu.Lock()
+	r.cert = &cert
+	r.clientCAs = clientCAs
+	r.stamps = stamps
+	r.mu.Unlock()
+	return nil
+}
+
+func stat(path string) (fileStamp, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileStamp{}, err
+	}
+	return fileStamp{modTime: info.ModTime(), size: info.Size()}, nil
+}
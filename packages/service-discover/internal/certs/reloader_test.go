@@ -0,0 +1,196 @@
+package certs
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/carlosealves2/video-ia/service-discover/internal/config"
+)
+
+type authority struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newAuthority(t *testing.T) *authority {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return &authority{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue returns a PEM certificate and key for commonName, valid for the
+// loopback addresses.
+func (a *authority) issue(t *testing.T, serial int64, commonName string) (certPEM, keyPEM []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: commonName},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, a.cert, &key.PublicKey, a.key)
+	require.NoError(t, err)
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func writeFile(t *testing.T, path string, data []byte, modTime time.Time) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+	require.NoError(t, os.Chtimes(path, modTime, modTime))
+}
+
+func setup(t *testing.T, clientAuth string) (*Reloader, *authority, config.TLSConfig) {
+	t.Helper()
+	dir := t.TempDir()
+	ca := newAuthority(t)
+
+	cfg := config.TLSConfig{
+		Enabled:        true,
+		CertFile:       filepath.Join(dir, "tls.crt"),
+		KeyFile:        filepath.Join(dir, "tls.key"),
+		ClientCAFile:   filepath.Join(dir, "ca.crt"),
+		ClientAuth:     clientAuth,
+		ReloadInterval: time.Hour,
+	}
+	certPEM, keyPEM := ca.issue(t, 2, "server-1")
+	past := time.Now().Add(-time.Minute)
+	writeFile(t, cfg.CertFile, certPEM, past)
+	writeFile(t, cfg.KeyFile, keyPEM, past)
+	writeFile(t, cfg.ClientCAFile, ca.pem, past)
+
+	r, err := NewReloader(cfg, zap.NewNop())
+	require.NoError(t, err)
+	return r, ca, cfg
+}
+
+func servedCommonName(t *testing.T, r *Reloader) string {
+	t.Helper()
+	cfg, err := r.ServerConfig().GetConfigForClient(&tls.ClientHelloInfo{})
+	require.NoError(t, err)
+	leaf, err := x509.ParseCertificate(cfg.Certificates[0].Certificate[0])
+	require.NoError(t, err)
+	return leaf.Subject.CommonName
+}
+
+func TestReloaderPicksUpChangedFiles(t *testing.T) {
+	r, ca, cfg := setup(t, "none")
+	assert.Equal(t, "server-1", servedCommonName(t, r))
+
+	r.check()
+	assert.Equal(t, "server-1", servedCommonName(t, r))
+
+	certPEM, keyPEM := ca.issue(t, 3, "server-2")
+	now := time.Now()
+	writeFile(t, cfg.CertFile, certPEM, now)
+	writeFile(t, cfg.KeyFile, keyPEM, now)
+
+	r.check()
+	assert.Equal(t, "server-2", servedCommonName(t, r))
+}
+
+func TestReloaderKeepsCurrentCertificateOnBadFiles(t *testing.T) {
+	r, _, cfg := setup(t, "none")
+
+	writeFile(t, cfg.CertFile, []byte("not a certificate"), time.Now())
+
+	r.check()
+	assert.Equal(t, "server-1", servedCommonName(t, r))
+}
+
+func TestNewReloaderRejectsMissingFiles(t *testing.T) {
+	_, err := NewReloader(config.TLSConfig{CertFile: "/nonexistent.crt", KeyFile: "/nonexistent.key"}, zap.NewNop())
+	assert.Error(t, err)
+}
+
+func TestMutualTLSHandshake(t *testing.T) {
+	r, ca, _ := setup(t, "require")
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		cert, ok := VerifiedClient(req.TLS)
+		if assert.True(t, ok) {
+			_, _ = w.Write([]byte(cert.Subject.CommonName))
+		}
+	}))
+	server.TLS = r.ServerConfig()
+	server.StartTLS()
+	defer server.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+
+	certPEM, keyPEM := ca.issue(t, 4, "uploader")
+	clientCert, err := tls.X509KeyPair(certPEM, keyPEM)
+	require.NoError(t, err)
+
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+		RootCAs:      roots,
+		Certificates: []tls.Certificate{clientCert},
+	}}}
+	resp, err := client.Get(server.URL)
+	require.NoError(t, err)
+	defer func() { _ = resp.Body.Close() }()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	anonymous := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}}}
+	_, err = anonymous.Get(server.URL)
+	assert.Error(t, err)
+}
+
+func TestIdentities(t *testing.T) {
+	spiffe, _ := url.Parse("spiffe://video-ia/ns/prod/sa/encoder")
+	cert := &x509.Certificate{
+		Subject:  pkix.Name{CommonName: "uploader"},
+		DNSNames: []string{"thumbnailer"},
+		URIs:     []*url.URL{spiffe},
+	}
+
+	assert.Equal(t, []string{"uploader", "thumbnailer", "encoder"}, Identities(cert))
+	assert.True(t, Allows(cert, "encoder"))
+	assert.False(t, Allows(cert, "transcoder"))
+}
@@ -0,0 +1,230 @@
+package cluster_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/carlosealves2/video-ia/service-discover/internal/cluster"
+	"github.com/carlosealves2/video-ia/service-discover/internal/config"
+	"github.com/carlosealves2/video-ia/service-discover/internal/domain"
+	"github.com/carlosealves2/video-ia/service-discover/internal/handler"
+	"github.com/carlosealves2/video-ia/service-discover/internal/repository"
+)
+
+type testNode struct {
+	node  *cluster.Node
+	repo  *cluster.Repository
+	local repository.ServiceRepository
+}
+
+// lazyHandler lets the API servers start before the nodes they route to
+// exist, since every node needs all peer API URLs up front.
+type lazyHandler struct {
+	mu      sync.RWMutex
+	handler http.Handler
+}
+
+func (h *lazyHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if h.handler == nil {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	h.handler.ServeHTTP(w, r)
+}
+
+func freeAddr(t *testing.T) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := l.Addr().String()
+	require.NoError(t, l.Close())
+	return addr
+}
+
+func startCluster(t *testing.T, size int, dataDir func(i int) string) []*testNode {
+	gin.SetMode(gin.TestMode)
+
+	handlers := make([]*lazyHandler, size)
+	peers := make([]config.Peer, size)
+	for i := range peers {
+		handlers[i] = &lazyHandler{}
+		server := httptest.NewServer(handlers[i])
+		t.Cleanup(server.Close)
+
+		peers[i] = config.Peer{
+			ID:       fmt.Sprintf("sd-%d", i),
+			RaftAddr: freeAddr(t),
+			APIAddr:  server.URL,
+		}
+	}
+
+	nodes := make([]*testNode, size)
+	for i := range nodes {
+		cfg := config.ClusterConfig{
+			Enabled:      true,
+			NodeID:       peers[i].ID,
+			BindAddr:     peers[i].RaftAddr,
+			Peers:        peers,
+			ApplyTimeout: 5 * time.Second,
+		}
+		if dataDir != nil {
+			cfg.DataDir = dataDir(i)
+		}
+
+		local := repository.NewMemoryRepository()
+		node, err := cluster.NewNode(cfg, local, nil, zap.NewNop())
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = node.Shutdown() })
+
+		router := gin.New()
+		h := handler.NewClusterHandler(node, zap.NewNop())
+		router.POST(cluster.ApplyPath, h.Apply)
+
+		handlers[i].mu.Lock()
+		handlers[i].handler = router
+		handlers[i].mu.Unlock()
+
+		nodes[i] = &testNode{node: node, repo: node.Repository(), local: local}
+	}
+
+	return nodes
+}
+
+func waitForLeader(t *testing.T, nodes []*testNode) (leader *testNode, followers []*testNode) {
+	require.Eventually(t, func() bool {
+		for _, n := range nodes {
+			if n.node.IsLeader() {
+				return true
+			}
+		}
+		return false
+	}, 10*time.Second, 50*time.Millisecond, "no leader elected")
+
+	for _, n := range nodes {
+		if n.node.IsLeader() {
+			leader = n
+		} else {
+			followers = append(followers, n)
+		}
+	}
+	return leader, followers
+}
+
+func newService(id string) *domain.Service {
+	return &domain.Service{
+		ID:            id,
+		Name:          "video-processor",
+		Host:          "10.0.0.1",
+		Port:          8080,
+		Protocol:      "http",
+		Status:        domain.StatusHealthy,
+		LastHeartbeat: time.Now(),
+		RegisteredAt:  time.Now(),
+	}
+}
+
+func replicated(nodes []*testNode, id string, want bool) func() bool {
+	return func() bool {
+		for _, n := range nodes {
+			if n.local.Exists(id) != want {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+func TestClusterReplicatesWrites(t *testing.T) {
+	nodes := startCluster(t, 3, nil)
+	leader, followers := waitForLeader(t, nodes)
+	require.Len(t, followers, 2)
+
+	require.NoError(t, leader.repo.Create(newService("from-leader")))
+	assert.Eventually(t, replicated(nodes, "from-leader", true), 5*time.Second, 20*time.Millisecond)
+
+	require.NoError(t, followers[0].repo.Create(newService("from-follower")))
+	assert.Eventually(t, replicated(nodes, "from-follower", true), 5*time.Second, 20*time.Millisecond)
+
+	svc := newService("from-follower")
+	svc.Status = domain.StatusUnhealthy
+	require.NoError(t, followers[1].repo.Update(svc))
+	assert.Eventually(t, func() bool {
+		for _, n := range nodes {
+			got, err := n.local.GetByID("from-follower")
+			if err != nil || got.Status != domain.StatusUnhealthy {
+				return false
+			}
+		}
+		return true
+	}, 5*time.Second, 20*time.Millisecond)
+
+	require.NoError(t, followers[1].repo.Delete("from-leader"))
+	assert.Eventually(t, replicated(nodes, "from-leader", false), 5*time.Second, 20*time.Millisecond)
+}
+
+func TestClusterForwardsRepositoryErrors(t *testing.T) {
+	nodes := startCluster(t, 3, nil)
+	leader, followers := waitForLeader(t, nodes)
+
+	require.NoError(t, leader.repo.Create(newService("1")))
+
+	assert.ErrorIs(t, followers[0].repo.Create(newService("1")), repository.ErrServiceAlreadyExists)
+	assert.ErrorIs(t, followers[0].repo.Delete("missing"), repository.ErrServiceNotFound)
+	assert.ErrorIs(t, leader.repo.Update(newService("missing")), repository.ErrServiceNotFound)
+}
+
+func TestClusterConsistentReads(t *testing.T) {
+	nodes := startCluster(t, 3, nil)
+	leader, _ := waitForLeader(t, nodes)
+
+	require.NoError(t, leader.repo.Create(newService("1")))
+
+	svc, err := leader.repo.GetByID("1")
+	require.NoError(t, err)
+	assert.Equal(t, "video-processor", svc.Name)
+	assert.Len(t, leader.repo.GetAll(), 1)
+}
+
+func TestClusterPersistsLog(t *testing.T) {
+	dirs := []string{t.TempDir()}
+	nodes := startCluster(t, 1, func(i int) string { return dirs[i] })
+	leader, _ := waitForLeader(t, nodes)
+
+	require.NoError(t, leader.repo.Create(newService("durable")))
+	require.NoError(t, leader.node.Shutdown())
+
+	restarted := startCluster(t, 1, func(i int) string { return dirs[i] })
+	assert.Eventually(t, replicated(restarted, "durable", true), 10*time.Second, 50*time.Millisecond)
+}
+
+func TestApplyHandlerRejectsOnFollower(t *testing.T) {
+	nodes := startCluster(t, 3, nil)
+	_, followers := waitForLeader(t, nodes)
+
+	router := gin.New()
+	router.POST(cluster.ApplyPath, handler.NewClusterHandler(followers[0].node, zap.NewNop()).Apply)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	body, _ := json.Marshal(cluster.Command{Type: cluster.CommandCreate, Service: newService("1")})
+	resp, err := http.Post(server.URL+cluster.ApplyPath, "application/json", bytes.NewReader(body))
+	require.NoError(t, err)
+	defer func() { _ = resp.Body.Close() }()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+}
@@ -0,0 +1,85 @@
+package cluster
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/hashicorp/raft"
+
+	"github.com/carlosealves2/video-ia/service-discover/internal/domain"
+	"github.com/carlosealves2/video-ia/service-discover/internal/repository"
+)
+
+type CommandType string
+
+const (
+	CommandCreate CommandType = "create"
+	CommandUpdate CommandType = "update"
+	CommandDelete CommandType = "delete"
+)
+
+// Command is a single repository mutation replicated through the Raft log.
+type Command struct {
+	Type    CommandType     `json:"type"`
+	Service *domain.Service `json:"service,omitempty"`
+	ID      string          `json:"id,omitempty"` // key of the service to delete
+}
+
+// fsm applies committed commands to the node's local repository.
+type fsm struct {
+	repo repository.ServiceRepository
+}
+
+func (f *fsm) Apply(log *raft.Log) interface{} {
+	var cmd Command
+	if err := json.Unmarshal(log.Data, &cmd); err != nil {
+		return err
+	}
+	return f.apply(cmd)
+}
+
+func (f *fsm) apply(cmd Command) error {
+	switch cmd.Type {
+	case CommandCreate:
+		return f.repo.Create(cmd.Service)
+	case CommandUpdate:
+		return f.repo.Update(cmd.Service)
+	case CommandDelete:
+		return f.repo.Delete(cmd.ID)
+	default:
+		return fmt.Errorf("unknown command type: %q", cmd.Type)
+	}
+}
+
+func (f *fsm) Snapshot() (raft.FSMSnapshot, error) {
+	return &snapshot{services: f.repo.GetAll()}, nil
+}
+
+// Restore replaces the local catalog with a snapshot as one change, so the
+// repository wrappers report a reset rather than replaying every service as
+// deregistered and registered again.
+func (f *fsm) Restore(rc io.ReadCloser) error {
+	defer func() { _ = rc.Close() }()
+
+	var services []*domain.Service
+	if err := json.NewDecoder(rc).Decode(&services); err != nil {
+		return err
+	}
+
+	return repository.Restore(f.repo, services)
+}
+
+type snapshot struct {
+	services []*domain.Service
+}
+
+func (s *snapshot) Persist(sink raft.SnapshotSink) error {
+	if err := json.NewEncoder(sink).Encode(s.services); err != nil {
+		_ = sink.Cancel()
+		return err
+	}
+	return sink.Close()
+}
+
+func (s *snapshot) Release() {}
@@ -0,0 +1,49 @@
+package cluster
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/hashicorp/raft"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/carlosealves2/video-ia/service-discover/internal/domain"
+	"github.com/carlosealves2/video-ia/service-discover/internal/repository"
+)
+
+type bufferSink struct {
+	bytes.Buffer
+}
+
+func (s *bufferSink) ID() string    { return "test" }
+func (s *bufferSink) Cancel() error { return nil }
+func (s *bufferSink) Close() error  { return nil }
+
+func TestFSMSnapshotRestore(t *testing.T) {
+	source := &fsm{repo: repository.NewMemoryRepository()}
+	require.NoError(t, source.apply(Command{Type: CommandCreate, Service: &domain.Service{ID: "1", Name: "a"}}))
+	require.NoError(t, source.apply(Command{Type: CommandCreate, Service: &domain.Service{ID: "2", Name: "b"}}))
+
+	snap, err := source.Snapshot()
+	require.NoError(t, err)
+	sink := &bufferSink{}
+	require.NoError(t, snap.Persist(sink))
+
+	target := &fsm{repo: repository.NewMemoryRepository()}
+	require.NoError(t, target.apply(Command{Type: CommandCreate, Service: &domain.Service{ID: "stale", Name: "c"}}))
+	require.NoError(t, target.Restore(io.NopCloser(&sink.Buffer)))
+
+	assert.Len(t, target.repo.GetAll(), 2)
+	assert.True(t, target.repo.Exists("1"))
+	assert.False(t, target.repo.Exists("stale"))
+}
+
+func TestFSMApplyUnknownCommand(t *testing.T) {
+	f := &fsm{repo: repository.NewMemoryRepository()}
+
+	resp := f.Apply(&raft.Log{Data: []byte(`{"type": "truncate"}`)})
+
+	assert.Error(t, resp.(error))
+}
@@ -0,0 +1,305 @@
+package cluster
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/raft"
+	"go.uber.org/zap"
+
+	"github.com/carlosealves2/video-ia/service-discover/internal/config"
+	"github.com/carlosealves2/video-ia/service-discover/internal/repository"
+)
+
+const ApplyPath = "/internal/cluster/apply"
+
+var (
+	ErrNoLeader  = errors.New("no cluster leader elected")
+	ErrNotLeader = errors.New("node is not the cluster leader")
+)
+
+type Node struct {
+	config    config.ClusterConfig
+	raft      *raft.Raft
+	fsm       *fsm
+	transport *raft.NetworkTransport
+	store     *boltStore
+	peers     map[raft.ServerID]config.Peer
+	client    *http.Client
+	logger    *zap.Logger
+
+	credential func() string
+}
+
+// NewNode starts a Raft node that applies committed mutations to local. On
+// first start every node bootstraps the cluster from the configured peers;
+// nodes with existing state rejoin from their log instead. Raft traffic is
+// secured with transportTLS; without it the node only binds to a loopback
+// address, unless cfg.InsecureTransport is set.
+func NewNode(cfg config.ClusterConfig, local repository.ServiceRepository, transportTLS *TransportTLS, logger *zap.Logger) (*Node, error) {
+	self, ok := cfg.Self()
+	if !ok {
+		return nil, fmt.Errorf("node %q is not listed in cluster peers", cfg.NodeID)
+	}
+
+	raftLogger := hclog.New(&hclog.LoggerOptions{
+		Name:       "raft",
+		Level:      hclog.Warn,
+		Output:     os.Stderr,
+		JSONFormat: true,
+	})
+
+	raftConfig := raft.DefaultConfig()
+	raftConfig.LocalID = raft.ServerID(cfg.NodeID)
+	raftConfig.Logger = raftLogger
+
+	advertise, err := net.ResolveTCPAddr("tcp", self.RaftAddr)
+	if err != nil {
+		return nil, err
+	}
+	var transport *raft.NetworkTransport
+	if transportTLS != nil {
+		stream, err := newTLSStreamLayer(cfg.BindAddr, advertise, transportTLS)
+		if err != nil {
+			return nil, err
+		}
+		transport = raft.NewNetworkTransportWithLogger(stream, 3, 10*time.Second, raftLogger)
+	} else {
+		if !cfg.InsecureTransport {
+			if err := checkPlaintextBind(cfg.BindAddr); err != nil {
+				return nil, err
+			}
+		}
+		transport, err = raft.NewTCPTransportWithLogger(cfg.BindAddr, advertise, 3, 10*time.Second, raftLogger)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	node := &Node{
+		config:    cfg,
+		fsm:       &fsm{repo: local},
+		transport: transport,
+		peers:     make(map[raft.ServerID]config.Peer, len(cfg.Peers)),
+		client:    &http.Client{Timeout: cfg.ApplyTimeout},
+		logger:    logger,
+	}
+
+	var (
+		logs     raft.LogStore
+		stable   raft.StableStore
+		snapshot raft.SnapshotStore
+	)
+	if cfg.DataDir != "" {
+		store, err := newBoltStore(filepath.Join(cfg.DataDir, "raft.db"))
+		if err != nil {
+			_ = transport.Close()
+			return nil, err
+		}
+		node.store = store
+		logs, stable = store, store
+
+		snapshot, err = raft.NewFileSnapshotStoreWithLogger(cfg.DataDir, 2, raftLogger)
+		if err != nil {
+			_ = node.closeStores()
+			return nil, err
+		}
+	} else {
+		inmem := raft.NewInmemStore()
+		logs, stable = inmem, inmem
+		snapshot = raft.NewInmemSnapshotStore()
+	}
+
+	r, err := raft.NewRaft(raftConfig, node.fsm, logs, stable, snapshot, transport)
+	if err != nil {
+		_ = node.closeStores()
+		return nil, err
+	}
+	node.raft = r
+
+	servers := make([]raft.Server, 0, len(cfg.Peers))
+	for _, p := range cfg.Peers {
+		node.peers[raft.ServerID(p.ID)] = p
+		servers = append(servers, raft.Server{
+			ID:      raft.ServerID(p.ID),
+			Address: raft.ServerAddress(p.RaftAddr),
+		})
+	}
+
+	err = r.BootstrapCluster(raft.Configuration{Servers: servers}).Error()
+	if err != nil && !errors.Is(err, raft.ErrCantBootstrap) {
+		_ = node.Shutdown()
+		return nil, err
+	}
+
+	return node, nil
+}
+
+// SetTLSConfig sets the TLS configuration used to forward writes to a leader
+// served over HTTPS. cfg is called for every new connection, so rotated
+// certificates and CA bundles are picked up, as with TransportTLS.Client.
+func (n *Node) SetTLSConfig(cfg func() *tls.Config) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialTLSContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		dialer := &tls.Dialer{Config: cfg()}
+		return dialer.DialContext(ctx, network, addr)
+	}
+	n.client.Transport = transport
+}
+
+// SetCredentials makes writes forwarded to the leader carry the bearer token
+// returned by credential, for clusters whose API requires authentication.
+func (n *Node) SetCredentials(credential func() string) {
+	n.credential = credential
+}
+
+func (n *Node) ID() string {
+	return n.config.NodeID
+}
+
+func (n *Node) IsLeader() bool {
+	return n.raft.State() == raft.Leader
+}
+
+func (n *Node) Leader() (config.Peer, bool) {
+	_, id := n.raft.LeaderWithID()
+	peer, ok := n.peers[id]
+	return peer, ok
+}
+
+func (n *Node) State() string {
+	return n.raft.State().String()
+}
+
+func (n *Node) AppliedIndex() uint64 {
+	return n.raft.AppliedIndex()
+}
+
+// Apply replicates cmd, forwarding it to the leader's API when this node is
+// a follower.
+func (n *Node) Apply(cmd Command) error {
+	if n.IsLeader() {
+		return n.ApplyLocal(cmd)
+	}
+
+	leader, ok := n.Leader()
+	if !ok {
+		return ErrNoLeader
+	}
+	return n.forward(leader, cmd)
+}
+
+// ApplyLocal appends cmd to the Raft log and waits until it is applied here.
+// It only succeeds on the leader.
+func (n *Node) ApplyLocal(cmd Command) error {
+	data, err := json.Marshal(cmd)
+	if err != nil {
+		return err
+	}
+
+	future := n.raft.Apply(data, n.config.ApplyTimeout)
+	if err := future.Error(); err != nil {
+		if errors.Is(err, raft.ErrNotLeader) || errors.Is(err, raft.ErrLeadershipLost) {
+			return ErrNotLeader
+		}
+		return err
+	}
+
+	if err, ok := future.Response().(error); ok {
+		return err
+	}
+	return nil
+}
+
+func (n *Node) forward(leader config.Peer, cmd Command) error {
+	body, err := json.Marshal(cmd)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, leader.APIAddr+ApplyPath, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if n.credential != nil {
+		req.Header.Set("Authorization", "Bearer "+n.credential())
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("forward to leader %s: %w", leader.ID, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusNotFound:
+		return repository.ErrServiceNotFound
+	case http.StatusConflict:
+		return repository.ErrServiceAlreadyExists
+	case http.StatusServiceUnavailable:
+		return ErrNotLeader
+	}
+
+	data, _ := io.ReadAll(resp.Body)
+	var errResp struct {
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(data, &errResp); err == nil && errResp.Error != "" {
+		return fmt.Errorf("leader %s: %s", leader.ID, errResp.Error)
+	}
+	return fmt.Errorf("leader %s: unexpected status code: %d", leader.ID, resp.StatusCode)
+}
+
+// waitConsistent blocks until this node has applied every entry it knows to
+// be committed. On the leader it also confirms leadership, so reads reflect
+// every acknowledged write; on a follower the view may still trail the
+// leader by one replication round.
+func (n *Node) waitConsistent() {
+	if n.IsLeader() && n.raft.VerifyLeader().Error() == nil {
+		return
+	}
+
+	deadline := time.Now().Add(n.config.ApplyTimeout)
+	for n.raft.AppliedIndex() < n.raft.CommitIndex() {
+		if time.Now().After(deadline) {
+			n.logger.Warn("Timed out waiting for consistent read, serving local state",
+				zap.Uint64("applied_index", n.raft.AppliedIndex()),
+				zap.Uint64("commit_index", n.raft.CommitIndex()),
+			)
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func (n *Node) Shutdown() error {
+	err := n.raft.Shutdown().Error()
+	if closeErr := n.closeStores(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+func (n *Node) closeStores() error {
+	err := n.transport.Close()
+	if n.store != nil {
+		if closeErr := n.store.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
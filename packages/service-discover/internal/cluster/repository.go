@@ -0,0 +1,53 @@
+package cluster
+
+import (
+	"github.com/carlosealves2/video-ia/service-discover/internal/domain"
+	"github.com/carlosealves2/video-ia/service-discover/internal/repository"
+)
+
+// Repository is a repository.ServiceRepository whose writes go through the
+// Raft log and whose reads are served from the node's local replica.
+type Repository struct {
+	node  *Node
+	local repository.ServiceRepository
+}
+
+func (n *Node) Repository() *Repository {
+	return &Repository{
+		node:  n,
+		local: n.fsm.repo,
+	}
+}
+
+func (r *Repository) Create(service *domain.Service) error {
+	return r.node.Apply(Command{Type: CommandCreate, Service: service})
+}
+
+func (r *Repository) GetByID(id string) (*domain.Service, error) {
+	r.beforeRead()
+	return r.local.GetByID(id)
+}
+
+func (r *Repository) GetAll() []*domain.Service {
+	r.beforeRead()
+	return r.local.GetAll()
+}
+
+func (r *Repository) Update(service *domain.Service) error {
+	return r.node.Apply(Command{Type: CommandUpdate, Service: service})
+}
+
+func (r *Repository) Delete(id string) error {
+	return r.node.Apply(Command{Type: CommandDelete, ID: id})
+}
+
+func (r *Repository) Exists(id string) bool {
+	r.beforeRead()
+	return r.local.Exists(id)
+}
+
+func (r *Repository) beforeRead() {
+	if r.node.config.ConsistentReads {
+		r.node.waitConsistent()
+	}
+}
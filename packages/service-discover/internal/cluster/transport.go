@@ -0,0 +1,67 @@
+package cluster
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/hashicorp/raft"
+)
+
+// TransportTLS secures Raft traffic between nodes with mutual TLS. Server
+// must require and verify client certificates; Client is called for every
+// connection to a peer, so rotated certificates are picked up.
+type TransportTLS struct {
+	Server *tls.Config
+	Client func() *tls.Config
+}
+
+// tlsStreamLayer is a raft.StreamLayer whose connections, in both
+// directions, only carry Raft messages once the peer has presented a
+// certificate signed by a trusted CA: the handshake runs on the first read,
+// so a connection without one fails before Raft sees any data.
+type tlsStreamLayer struct {
+	net.Listener
+	advertise net.Addr
+	client    func() *tls.Config
+}
+
+func newTLSStreamLayer(bindAddr string, advertise net.Addr, cfg *TransportTLS) (*tlsStreamLayer, error) {
+	listener, err := net.Listen("tcp", bindAddr)
+	if err != nil {
+		return nil, err
+	}
+	return &tlsStreamLayer{
+		Listener:  tls.NewListener(listener, cfg.Server),
+		advertise: advertise,
+		client:    cfg.Client,
+	}, nil
+}
+
+func (l *tlsStreamLayer) Dial(address raft.ServerAddress, timeout time.Duration) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: timeout}
+	return tls.DialWithDialer(dialer, "tcp", string(address), l.client())
+}
+
+func (l *tlsStreamLayer) Addr() net.Addr {
+	return l.advertise
+}
+
+// checkPlaintextBind refuses to serve unauthenticated Raft traffic on an
+// address other hosts can reach, since anyone able to connect could append
+// entries to the log.
+func checkPlaintextBind(bindAddr string) error {
+	host, _, err := net.SplitHostPort(bindAddr)
+	if err != nil {
+		return err
+	}
+	if host == "localhost" {
+		return nil
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		return nil
+	}
+	return fmt.Errorf("refusing plaintext Raft transport on non-loopback address %q: "+
+		"configure tls.client_ca_file or set cluster.insecure_transport", bindAddr)
+}
@@ -0,0 +1,248 @@
+package cluster
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/raft"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/carlosealves2/video-ia/service-discover/internal/config"
+	"github.com/carlosealves2/video-ia/service-discover/internal/domain"
+	"github.com/carlosealves2/video-ia/service-discover/internal/repository"
+)
+
+// newPeerTLS returns a transport configuration for a peer certificate valid
+// for 127.0.0.1, issued by a fresh CA that is trusted on both sides.
+func newPeerTLS(t *testing.T) *TransportTLS {
+	t.Helper()
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	caTmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTmpl, caTmpl, &caKey.PublicKey, caKey)
+	require.NoError(t, err)
+	ca, err := x509.ParseCertificate(caDER)
+	require.NoError(t, err)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "sd-0"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca, &key.PublicKey, caKey)
+	require.NoError(t, err)
+
+	cert := tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+	pool := x509.NewCertPool()
+	pool.AddCert(ca)
+
+	return &TransportTLS{
+		Server: &tls.Config{
+			MinVersion:   tls.VersionTLS12,
+			Certificates: []tls.Certificate{cert},
+			ClientAuth:   tls.RequireAndVerifyClientCert,
+			ClientCAs:    pool,
+		},
+		Client: func() *tls.Config {
+			return &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				Certificates: []tls.Certificate{cert},
+				RootCAs:      pool,
+			}
+		},
+	}
+}
+
+func listenTLS(t *testing.T, cfg *TransportTLS) *tlsStreamLayer {
+	t.Helper()
+	advertise, err := net.ResolveTCPAddr("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	stream, err := newTLSStreamLayer("127.0.0.1:0", advertise, cfg)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = stream.Close() })
+	return stream
+}
+
+func TestTLSStreamLayerAcceptsTrustedPeer(t *testing.T) {
+	cfg := newPeerTLS(t)
+	stream := listenTLS(t, cfg)
+
+	received := make(chan []byte, 1)
+	go func() {
+		conn, err := stream.Accept()
+		if err != nil {
+			return
+		}
+		defer func() { _ = conn.Close() }()
+		buf := make([]byte, 4)
+		if _, err := io.ReadFull(conn, buf); err == nil {
+			received <- buf
+		}
+	}()
+
+	conn, err := stream.Dial(raft.ServerAddress(stream.Listener.Addr().String()), time.Second)
+	require.NoError(t, err)
+	defer func() { _ = conn.Close() }()
+	_, err = conn.Write([]byte("raft"))
+	require.NoError(t, err)
+
+	select {
+	case data := <-received:
+		assert.Equal(t, "raft", string(data))
+	case <-time.After(5 * time.Second):
+		t.Fatal("message not received")
+	}
+}
+
+func TestTLSStreamLayerRejectsPeerWithoutCertificate(t *testing.T) {
+	cfg := newPeerTLS(t)
+	stream := listenTLS(t, cfg)
+
+	readErr := make(chan error, 1)
+	go func() {
+		conn, err := stream.Accept()
+		if err != nil {
+			readErr <- err
+			return
+		}
+		defer func() { _ = conn.Close() }()
+		_, err = conn.Read(make([]byte, 1))
+		readErr <- err
+	}()
+
+	client := cfg.Client()
+	client.Certificates = nil
+	conn, err := tls.Dial("tcp", stream.Listener.Addr().String(), client)
+	if err == nil {
+		_, _ = conn.Write([]byte("raft"))
+		defer func() { _ = conn.Close() }()
+	}
+
+	select {
+	case err := <-readErr:
+		assert.Error(t, err, "a peer without a client certificate must not be read from")
+	case <-time.After(5 * time.Second):
+		t.Fatal("connection not rejected")
+	}
+}
+
+func TestNewNodeRefusesPlaintextOnNonLoopback(t *testing.T) {
+	cfg := config.ClusterConfig{
+		Enabled:      true,
+		NodeID:       "sd-0",
+		BindAddr:     ":0",
+		Peers:        []config.Peer{{ID: "sd-0", RaftAddr: "127.0.0.1:7000", APIAddr: "http://127.0.0.1:8080"}},
+		ApplyTimeout: time.Second,
+	}
+
+	_, err := NewNode(cfg, repository.NewMemoryRepository(), nil, zap.NewNop())
+	assert.ErrorContains(t, err, "cluster.insecure_transport")
+}
+
+func TestCheckPlaintextBind(t *testing.T) {
+	for _, addr := range []string{"127.0.0.1:7000", "[::1]:7000", "localhost:7000"} {
+		assert.NoError(t, checkPlaintextBind(addr), addr)
+	}
+	for _, addr := range []string{":7000", "0.0.0.0:7000", "10.0.0.1:7000", "[::]:7000"} {
+		assert.Error(t, checkPlaintextBind(addr), addr)
+	}
+}
+
+func TestClusterReplicatesOverTLS(t *testing.T) {
+	cfg := newPeerTLS(t)
+
+	peers := make([]config.Peer, 2)
+	for i := range peers {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		peers[i] = config.Peer{ID: fmt.Sprintf("sd-%d", i), RaftAddr: l.Addr().String(), APIAddr: "http://127.0.0.1:0"}
+		require.NoError(t, l.Close())
+	}
+
+	nodes := make([]*Node, len(peers))
+	locals := make([]repository.ServiceRepository, len(peers))
+	for i := range nodes {
+		_, port, err := net.SplitHostPort(peers[i].RaftAddr)
+		require.NoError(t, err)
+		locals[i] = repository.NewMemoryRepository()
+		node, err := NewNode(config.ClusterConfig{
+			Enabled:      true,
+			NodeID:       peers[i].ID,
+			BindAddr:     "0.0.0.0:" + port,
+			Peers:        peers,
+			ApplyTimeout: 5 * time.Second,
+		}, locals[i], cfg, zap.NewNop())
+		require.NoError(t, err, "TLS lifts the loopback restriction")
+		t.Cleanup(func() { _ = node.Shutdown() })
+		nodes[i] = node
+	}
+
+	var leader *Node
+	require.Eventually(t, func() bool {
+		for _, n := range nodes {
+			if n.IsLeader() {
+				leader = n
+				return true
+			}
+		}
+		return false
+	}, 10*time.Second, 50*time.Millisecond, "no leader elected")
+
+	svc := &domain.Service{ID: "1", Name: "video-processor", Host: "10.0.0.1", Port: 8080}
+	require.NoError(t, leader.ApplyLocal(Command{Type: CommandCreate, Service: svc}))
+	assert.Eventually(t, func() bool {
+		return locals[0].Exists("1") && locals[1].Exists("1")
+	}, 5*time.Second, 20*time.Millisecond)
+}
+
+func TestForwardingPicksUpRotatedCA(t *testing.T) {
+	before, after := newPeerTLS(t), newPeerTLS(t)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.TLS = after.Server
+	server.StartTLS()
+	t.Cleanup(server.Close)
+
+	var current atomic.Pointer[TransportTLS]
+	current.Store(before)
+	node := &Node{client: &http.Client{Timeout: 5 * time.Second}}
+	node.SetTLSConfig(func() *tls.Config { return current.Load().Client() })
+
+	_, err := node.client.Get(server.URL)
+	require.Error(t, err, "the server certificate is not yet trusted")
+
+	current.Store(after)
+	resp, err := node.client.Get(server.URL)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
@@ -2,16 +2,173 @@ package config
 
 import (
 	"errors"
+	"fmt"
+	"net/url"
 	"os"
-	"strconv"
+	"regexp"
+	"strings"
+	"time"
 )
 
+// namespacePattern is the rule the API applies to namespace names.
+var namespacePattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
+
 type Config struct {
-	Port     int
-	LogLevel string
-	GinMode  string
+	Port        int
+	LogLevel    string
+	GinMode     string
+	Server      ServerConfig
+	HealthCheck HealthCheckConfig
+	Heartbeat   HeartbeatConfig
+	Storage     StorageConfig
+	Cluster     ClusterConfig
+	Events      EventsConfig
+	Gateway     GatewayConfig
+	DNS         DNSConfig
+	Auth        AuthConfig
+	TLS         TLSConfig
+	Metrics     MetricsConfig
+	Tracing     TracingConfig
+}
+
+// ServerConfig holds the timeouts of the API and gateway servers. A zero
+// ReadTimeout, WriteTimeout or IdleTimeout disables it; WriteTimeout is off
+// by default because it also cuts off event streams and blocking queries. On
+// SIGINT or SIGTERM in-flight requests get up to ShutdownTimeout to complete.
+type ServerConfig struct {
+	ReadTimeout     time.Duration
+	WriteTimeout    time.Duration
+	IdleTimeout     time.Duration
+	ShutdownTimeout time.Duration
+}
+
+type HealthCheckConfig struct {
+	Enabled          bool
+	Interval         time.Duration
+	Timeout          time.Duration
+	SuccessThreshold int
+	FailureThreshold int
+}
+
+// HeartbeatConfig controls heartbeat expiry. TTL is applied to registrations
+// that do not set their own; zero disables expiry for them. An expired
+// instance is marked unhealthy and removed once DeregisterAfter has also
+// elapsed.
+type HeartbeatConfig struct {
+	TTL             time.Duration
+	DeregisterAfter time.Duration
+	ReapInterval    time.Duration
+}
+
+type StorageConfig struct {
+	Backend string
+	Path    string
+}
+
+// ClusterConfig replicates the catalog with Raft. Raft traffic uses mutual
+// TLS when TLS is enabled with a ClientCAFile; otherwise it is plaintext and
+// BindAddr must be a loopback address unless InsecureTransport is set.
+type ClusterConfig struct {
+	Enabled           bool
+	NodeID            string
+	BindAddr          string
+	DataDir           string
+	Peers             []Peer
+	ConsistentReads   bool
+	ApplyTimeout      time.Duration
+	InsecureTransport bool
+}
+
+// EventsConfig sets how many recent registry events are kept so that
+// reconnecting stream subscribers can resume with Last-Event-ID.
+type EventsConfig struct {
+	BufferSize int
+}
+
+// GatewayConfig enables the built-in reverse proxy that routes requests to
+// the routes of services registered in Namespace. Timeout applies to routes
+// that do not set their own.
+type GatewayConfig struct {
+	Enabled   bool
+	Port      int
+	Namespace string
+	Timeout   time.Duration
+}
+
+// DNSConfig enables the embedded DNS server answering for
+// <name>.service.<Domain> on Port (UDP and TCP). TTL is set on every record.
+type DNSConfig struct {
+	Enabled bool
+	Port    int
+	Domain  string
+	TTL     time.Duration
+}
+
+// AuthConfig protects the registry API. Callers present one of APIKeys or a
+// JWT signed with JWTSecret (HS256, HS384 or HS512); either grants the read
+// and write scopes, or admin, which also lifts instance ownership checks.
+// JWTIssuer and JWTAudience, when set, must match the token's iss and aud
+// claims.
+type AuthConfig struct {
+	Enabled     bool
+	APIKeys     []APIKey
+	JWTSecret   string
+	JWTIssuer   string
+	JWTAudience string
+}
+
+type APIKey struct {
+	Key    string
+	Scopes []string
 }
 
+// TLSConfig serves the API over HTTPS with CertFile and KeyFile. With
+// ClientAuth set to "optional" or "require", client certificates are
+// verified against ClientCAFile, and a caller presenting one may only
+// register services named after it. The files are re-read when they change,
+// checked every ReloadInterval.
+type TLSConfig struct {
+	Enabled        bool
+	CertFile       string
+	KeyFile        string
+	ClientCAFile   string
+	ClientAuth     string
+	ReloadInterval time.Duration
+}
+
+// MetricsConfig exposes Prometheus metrics on /metrics.
+type MetricsConfig struct {
+	Enabled bool
+}
+
+// TracingConfig exports OpenTelemetry traces over OTLP/HTTP to Endpoint, a
+// URL such as http://otel-collector:4318. Without one, the exporter's
+// OTEL_EXPORTER_OTLP_* environment variables and defaults apply.
+type TracingConfig struct {
+	Enabled  bool
+	Endpoint string
+}
+
+// Peer describes a cluster member: its Raft address and the base URL of its
+// HTTP API, used to forward writes to the leader.
+type Peer struct {
+	ID       string
+	RaftAddr string
+	APIAddr  string
+}
+
+func (c ClusterConfig) Self() (Peer, bool) {
+	for _, p := range c.Peers {
+		if p.ID == c.NodeID {
+			return p, true
+		}
+	}
+	return Peer{}, false
+}
+
+// Builder assembles a Config from its defaults and the sources applied to
+// it, each overriding the previous ones. cmd/main applies them in the
+// documented order of precedence: defaults < WithFile < WithEnv < WithFlags.
 type Builder struct {
 	config *Config
 	errors []error
@@ -23,35 +180,122 @@ func NewBuilder() *Builder {
 			Port:     8080,
 			LogLevel: "info",
 			GinMode:  "release",
+			Server: ServerConfig{
+				ReadTimeout:     30 * time.Second,
+				IdleTimeout:     2 * time.Minute,
+				ShutdownTimeout: 25 * time.Second,
+			},
+			HealthCheck: HealthCheckConfig{
+				Enabled:          true,
+				Interval:         10 * time.Second,
+				Timeout:          2 * time.Second,
+				SuccessThreshold: 1,
+				FailureThreshold: 3,
+			},
+			Heartbeat: HeartbeatConfig{
+				TTL:             0,
+				DeregisterAfter: time.Minute,
+				ReapInterval:    5 * time.Second,
+			},
+			Storage: StorageConfig{
+				Backend: "memory",
+				Path:    "data/service-discover.db",
+			},
+			Cluster: ClusterConfig{
+				BindAddr:     ":7000",
+				ApplyTimeout: 5 * time.Second,
+			},
+			Events: EventsConfig{
+				BufferSize: 1024,
+			},
+			Gateway: GatewayConfig{
+				Port:      8000,
+				Namespace: "default",
+				Timeout:   30 * time.Second,
+			},
+			DNS: DNSConfig{
+				Port:   8600,
+				Domain: "video-ia",
+				TTL:    30 * time.Second,
+			},
+			TLS: TLSConfig{
+				ClientAuth:     "none",
+				ReloadInterval: 10 * time.Second,
+			},
+			Metrics: MetricsConfig{
+				Enabled: true,
+			},
 		},
 		errors: []error{},
 	}
 }
 
+// WithEnv reads every setting from the environment variable named after its
+// path, e.g. HEALTH_CHECK_INTERVAL for health_check.interval. Empty
+// variables are ignored. Invalid values are reported by path, like Validate
+// does, with the variable in parentheses: "health_check.interval
+// (HEALTH_CHECK_INTERVAL): must be a valid duration".
 func (b *Builder) WithEnv() *Builder {
-	if port := os.Getenv("PORT"); port != "" {
-		p, err := strconv.Atoi(port)
-		if err != nil {
-			b.errors = append(b.errors, errors.New("PORT must be a valid integer"))
-		} else {
-			b.config.Port = p
+	for _, s := range settings(b.config) {
+		name := s.env()
+		value := os.Getenv(name)
+		if value == "" {
+			continue
+		}
+		if err := s.set(value); err != nil {
+			b.errors = append(b.errors, fmt.Errorf("%s (%s): %w", s.path, name, err))
 		}
 	}
+	return b
+}
 
-	if logLevel := os.Getenv("LOG_LEVEL"); logLevel != "" {
-		b.config.LogLevel = logLevel
+// ParsePeers parses a comma-separated list of id@raft_addr@api_url entries,
+// e.g. "sd-0@10.0.0.1:7000@http://10.0.0.1:8080".
+func ParsePeers(value string) ([]Peer, error) {
+	var peers []Peer
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.Split(entry, "@")
+		if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+			return nil, errors.New("must be a comma-separated list of id@raft_addr@api_url")
+		}
+		peers = append(peers, Peer{ID: parts[0], RaftAddr: parts[1], APIAddr: strings.TrimSuffix(parts[2], "/")})
 	}
+	return peers, nil
+}
 
-	if ginMode := os.Getenv("GIN_MODE"); ginMode != "" {
-		b.config.GinMode = ginMode
+// ParseAPIKeys parses a comma-separated list of key:scopes entries, where
+// scopes joins read, write and admin with +, e.g. "k1:read,k2:read+write".
+func ParseAPIKeys(value string) ([]APIKey, error) {
+	var keys []APIKey
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		i := strings.LastIndex(entry, ":")
+		if i <= 0 || i == len(entry)-1 {
+			return nil, errors.New("must be a comma-separated list of key:scopes")
+		}
+		scopes := strings.Split(entry[i+1:], "+")
+		for _, scope := range scopes {
+			if scope != "read" && scope != "write" && scope != "admin" {
+				return nil, errors.New("scopes must be read, write or admin, joined with +")
+			}
+		}
+		keys = append(keys, APIKey{Key: entry[:i], Scopes: scopes})
 	}
-
-	return b
+	return keys, nil
 }
 
+// Validate reports every invalid setting by its path, e.g.
+// "gateway.port must differ from port".
 func (b *Builder) Validate() *Builder {
 	if b.config.Port <= 0 || b.config.Port > 65535 {
-		b.errors = append(b.errors, errors.New("PORT must be between 1 and 65535"))
+		b.errors = append(b.errors, errors.New("port must be between 1 and 65535"))
 	}
 
 	validLogLevels := map[string]bool{
@@ -61,7 +305,7 @@ func (b *Builder) Validate() *Builder {
 		"error": true,
 	}
 	if !validLogLevels[b.config.LogLevel] {
-		b.errors = append(b.errors, errors.New("LOG_LEVEL must be one of: debug, info, warn, error"))
+		b.errors = append(b.errors, errors.New("log_level must be one of: debug, info, warn, error"))
 	}
 
 	validGinModes := map[string]bool{
@@ -70,7 +314,141 @@ func (b *Builder) Validate() *Builder {
 		"test":    true,
 	}
 	if !validGinModes[b.config.GinMode] {
-		b.errors = append(b.errors, errors.New("GIN_MODE must be one of: debug, release, test"))
+		b.errors = append(b.errors, errors.New("gin_mode must be one of: debug, release, test"))
+	}
+
+	if b.config.Server.ReadTimeout < 0 {
+		b.errors = append(b.errors, errors.New("server.read_timeout must not be negative"))
+	}
+	if b.config.Server.WriteTimeout < 0 {
+		b.errors = append(b.errors, errors.New("server.write_timeout must not be negative"))
+	}
+	if b.config.Server.IdleTimeout < 0 {
+		b.errors = append(b.errors, errors.New("server.idle_timeout must not be negative"))
+	}
+	if b.config.Server.ShutdownTimeout <= 0 {
+		b.errors = append(b.errors, errors.New("server.shutdown_timeout must be greater than zero"))
+	}
+
+	if hc := b.config.HealthCheck; hc.Enabled {
+		if hc.Interval <= 0 {
+			b.errors = append(b.errors, errors.New("health_check.interval must be greater than zero"))
+		}
+		if hc.Timeout <= 0 {
+			b.errors = append(b.errors, errors.New("health_check.timeout must be greater than zero"))
+		} else if hc.Timeout > hc.Interval {
+			b.errors = append(b.errors, errors.New("health_check.timeout must not exceed health_check.interval"))
+		}
+		if hc.SuccessThreshold < 1 {
+			b.errors = append(b.errors, errors.New("health_check.success_threshold must be at least 1"))
+		}
+		if hc.FailureThreshold < 1 {
+			b.errors = append(b.errors, errors.New("health_check.failure_threshold must be at least 1"))
+		}
+	}
+
+	if b.config.Heartbeat.TTL < 0 {
+		b.errors = append(b.errors, errors.New("heartbeat.ttl must not be negative"))
+	}
+	if b.config.Heartbeat.DeregisterAfter < 0 {
+		b.errors = append(b.errors, errors.New("heartbeat.deregister_after must not be negative"))
+	}
+	if b.config.Heartbeat.ReapInterval <= 0 {
+		b.errors = append(b.errors, errors.New("heartbeat.reap_interval must be greater than zero"))
+	}
+
+	validStorageBackends := map[string]bool{
+		"memory": true,
+		"bolt":   true,
+	}
+	if !validStorageBackends[b.config.Storage.Backend] {
+		b.errors = append(b.errors, errors.New("storage.backend must be one of: memory, bolt"))
+	}
+	if b.config.Storage.Backend == "bolt" && b.config.Storage.Path == "" {
+		b.errors = append(b.errors, errors.New("storage.path is required when storage.backend is bolt"))
+	}
+
+	if cl := b.config.Cluster; cl.Enabled {
+		if cl.NodeID == "" {
+			b.errors = append(b.errors, errors.New("cluster.node_id is required when the cluster is enabled"))
+		} else if _, ok := cl.Self(); !ok {
+			b.errors = append(b.errors, errors.New("cluster.peers must include cluster.node_id"))
+		}
+		if cl.BindAddr == "" {
+			b.errors = append(b.errors, errors.New("cluster.bind_addr is required when the cluster is enabled"))
+		}
+		if cl.ApplyTimeout <= 0 {
+			b.errors = append(b.errors, errors.New("cluster.apply_timeout must be greater than zero"))
+		}
+	}
+
+	if b.config.Events.BufferSize < 0 {
+		b.errors = append(b.errors, errors.New("events.buffer_size must not be negative"))
+	}
+
+	if gw := b.config.Gateway; gw.Enabled {
+		if gw.Port <= 0 || gw.Port > 65535 {
+			b.errors = append(b.errors, errors.New("gateway.port must be between 1 and 65535"))
+		} else if gw.Port == b.config.Port {
+			b.errors = append(b.errors, errors.New("gateway.port must differ from port"))
+		}
+		if !namespacePattern.MatchString(gw.Namespace) {
+			b.errors = append(b.errors, errors.New("gateway.namespace must be lowercase letters, digits and hyphens (at most 63 characters)"))
+		}
+		if gw.Timeout <= 0 {
+			b.errors = append(b.errors, errors.New("gateway.timeout must be greater than zero"))
+		}
+	}
+
+	if d := b.config.DNS; d.Enabled {
+		if d.Port <= 0 || d.Port > 65535 {
+			b.errors = append(b.errors, errors.New("dns.port must be between 1 and 65535"))
+		} else if d.Port == b.config.Port || (b.config.Gateway.Enabled && d.Port == b.config.Gateway.Port) {
+			b.errors = append(b.errors, errors.New("dns.port must differ from port and gateway.port"))
+		}
+		if strings.Trim(d.Domain, ".") == "" {
+			b.errors = append(b.errors, errors.New("dns.domain is required when DNS is enabled"))
+		}
+		if d.TTL < 0 {
+			b.errors = append(b.errors, errors.New("dns.ttl must not be negative"))
+		}
+	}
+
+	if a := b.config.Auth; a.Enabled {
+		if len(a.APIKeys) == 0 && a.JWTSecret == "" {
+			b.errors = append(b.errors, errors.New("auth.api_keys or auth.jwt_secret is required when auth is enabled"))
+		}
+		if a.JWTSecret != "" && len(a.JWTSecret) < 32 {
+			b.errors = append(b.errors, errors.New("auth.jwt_secret must be at least 32 bytes"))
+		}
+	}
+
+	if t := b.config.TLS; t.Enabled {
+		if t.CertFile == "" {
+			b.errors = append(b.errors, errors.New("tls.cert_file is required when TLS is enabled"))
+		}
+		if t.KeyFile == "" {
+			b.errors = append(b.errors, errors.New("tls.key_file is required when TLS is enabled"))
+		}
+		switch t.ClientAuth {
+		case "none":
+		case "optional", "require":
+			if t.ClientCAFile == "" {
+				b.errors = append(b.errors, errors.New("tls.client_ca_file is required when tls.client_auth is optional or require"))
+			}
+		default:
+			b.errors = append(b.errors, errors.New("tls.client_auth must be one of: none, optional, require"))
+		}
+		if t.ReloadInterval <= 0 {
+			b.errors = append(b.errors, errors.New("tls.reload_interval must be greater than zero"))
+		}
+	}
+
+	if endpoint := b.config.Tracing.Endpoint; b.config.Tracing.Enabled && endpoint != "" {
+		u, err := url.Parse(endpoint)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			b.errors = append(b.errors, errors.New("tracing.otlp_endpoint must be an http or https URL"))
+		}
 	}
 
 	return b
@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -58,7 +59,7 @@ func TestWithEnvInvalidPort(t *testing.T) {
 	_, err := NewBuilder().WithEnv().Validate().Build()
 
 	require.Error(t, err)
-	assert.Contains(t, err.Error(), "PORT must be a valid integer")
+	assert.Contains(t, err.Error(), "port (PORT): must be a valid integer")
 }
 
 func TestValidatePortRange(t *testing.T) {
@@ -71,9 +72,9 @@ func TestValidatePortRange(t *testing.T) {
 		{"valid port", "8080", false, ""},
 		{"port 1", "1", false, ""},
 		{"port 65535", "65535", false, ""},
-		{"port 0", "0", true, "PORT must be between 1 and 65535"},
-		{"port negative", "-1", true, "PORT must be between 1 and 65535"},
-		{"port too high", "65536", true, "PORT must be between 1 and 65535"},
+		{"port 0", "0", true, "port must be between 1 and 65535"},
+		{"port negative", "-1", true, "port must be between 1 and 65535"},
+		{"port too high", "65536", true, "port must be between 1 and 65535"},
 	}
 
 	for _, tt := range tests {
@@ -125,7 +126,7 @@ func TestValidateLogLevel(t *testing.T) {
 
 			if tt.wantErr {
 				require.Error(t, err)
-				assert.Contains(t, err.Error(), "LOG_LEVEL must be one of")
+				assert.Contains(t, err.Error(), "log_level must be one of")
 			} else {
 				require.NoError(t, err)
 			}
@@ -158,7 +159,7 @@ func TestValidateGinMode(t *testing.T) {
 
 			if tt.wantErr {
 				require.Error(t, err)
-				assert.Contains(t, err.Error(), "GIN_MODE must be one of")
+				assert.Contains(t, err.Error(), "gin_mode must be one of")
 			} else {
 				require.NoError(t, err)
 			}
@@ -179,9 +180,9 @@ func TestMultipleErrors(t *testing.T) {
 	_, err := NewBuilder().WithEnv().Validate().Build()
 
 	require.Error(t, err)
-	assert.Contains(t, err.Error(), "PORT must be between 1 and 65535")
-	assert.Contains(t, err.Error(), "LOG_LEVEL must be one of")
-	assert.Contains(t, err.Error(), "GIN_MODE must be one of")
+	assert.Contains(t, err.Error(), "port must be between 1 and 65535")
+	assert.Contains(t, err.Error(), "log_level must be one of")
+	assert.Contains(t, err.Error(), "gin_mode must be one of")
 }
 
 func TestBuildWithoutValidate(t *testing.T) {
@@ -204,3 +205,438 @@ func TestChainedCalls(t *testing.T) {
 	require.NoError(t, err)
 	assert.NotNil(t, cfg)
 }
+
+func TestHealthCheckDefaults(t *testing.T) {
+	cfg, err := NewBuilder().Validate().Build()
+
+	require.NoError(t, err)
+	assert.True(t, cfg.HealthCheck.Enabled)
+	assert.Equal(t, 10*time.Second, cfg.HealthCheck.Interval)
+	assert.Equal(t, 2*time.Second, cfg.HealthCheck.Timeout)
+	assert.Equal(t, 1, cfg.HealthCheck.SuccessThreshold)
+	assert.Equal(t, 3, cfg.HealthCheck.FailureThreshold)
+}
+
+func TestHealthCheckFromEnv(t *testing.T) {
+	_ = os.Setenv("HEALTH_CHECK_ENABLED", "false")
+	_ = os.Setenv("HEALTH_CHECK_INTERVAL", "30s")
+	_ = os.Setenv("HEALTH_CHECK_TIMEOUT", "5s")
+	_ = os.Setenv("HEALTH_CHECK_SUCCESS_THRESHOLD", "2")
+	_ = os.Setenv("HEALTH_CHECK_FAILURE_THRESHOLD", "5")
+	defer func() {
+		_ = os.Unsetenv("HEALTH_CHECK_ENABLED")
+		_ = os.Unsetenv("HEALTH_CHECK_INTERVAL")
+		_ = os.Unsetenv("HEALTH_CHECK_TIMEOUT")
+		_ = os.Unsetenv("HEALTH_CHECK_SUCCESS_THRESHOLD")
+		_ = os.Unsetenv("HEALTH_CHECK_FAILURE_THRESHOLD")
+	}()
+
+	cfg, err := NewBuilder().WithEnv().Validate().Build()
+
+	require.NoError(t, err)
+	assert.False(t, cfg.HealthCheck.Enabled)
+	assert.Equal(t, 30*time.Second, cfg.HealthCheck.Interval)
+	assert.Equal(t, 5*time.Second, cfg.HealthCheck.Timeout)
+	assert.Equal(t, 2, cfg.HealthCheck.SuccessThreshold)
+	assert.Equal(t, 5, cfg.HealthCheck.FailureThreshold)
+}
+
+func TestHealthCheckInvalidEnv(t *testing.T) {
+	_ = os.Setenv("HEALTH_CHECK_ENABLED", "maybe")
+	_ = os.Setenv("HEALTH_CHECK_INTERVAL", "often")
+	defer func() {
+		_ = os.Unsetenv("HEALTH_CHECK_ENABLED")
+		_ = os.Unsetenv("HEALTH_CHECK_INTERVAL")
+	}()
+
+	_, err := NewBuilder().WithEnv().Validate().Build()
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "health_check.enabled (HEALTH_CHECK_ENABLED): must be a valid boolean")
+	assert.Contains(t, err.Error(), "health_check.interval (HEALTH_CHECK_INTERVAL): must be a valid duration")
+}
+
+func TestValidateHealthCheck(t *testing.T) {
+	_ = os.Setenv("HEALTH_CHECK_INTERVAL", "1s")
+	_ = os.Setenv("HEALTH_CHECK_TIMEOUT", "2s")
+	_ = os.Setenv("HEALTH_CHECK_FAILURE_THRESHOLD", "0")
+	defer func() {
+		_ = os.Unsetenv("HEALTH_CHECK_INTERVAL")
+		_ = os.Unsetenv("HEALTH_CHECK_TIMEOUT")
+		_ = os.Unsetenv("HEALTH_CHECK_FAILURE_THRESHOLD")
+	}()
+
+	_, err := NewBuilder().WithEnv().Validate().Build()
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "health_check.timeout must not exceed health_check.interval")
+	assert.Contains(t, err.Error(), "health_check.failure_threshold must be at least 1")
+}
+
+func TestHeartbeatFromEnv(t *testing.T) {
+	_ = os.Setenv("HEARTBEAT_TTL", "30s")
+	_ = os.Setenv("HEARTBEAT_DEREGISTER_AFTER", "2m")
+	_ = os.Setenv("HEARTBEAT_REAP_INTERVAL", "1s")
+	defer func() {
+		_ = os.Unsetenv("HEARTBEAT_TTL")
+		_ = os.Unsetenv("HEARTBEAT_DEREGISTER_AFTER")
+		_ = os.Unsetenv("HEARTBEAT_REAP_INTERVAL")
+	}()
+
+	cfg, err := NewBuilder().WithEnv().Validate().Build()
+
+	require.NoError(t, err)
+	assert.Equal(t, 30*time.Second, cfg.Heartbeat.TTL)
+	assert.Equal(t, 2*time.Minute, cfg.Heartbeat.DeregisterAfter)
+	assert.Equal(t, time.Second, cfg.Heartbeat.ReapInterval)
+}
+
+func TestValidateHeartbeat(t *testing.T) {
+	_ = os.Setenv("HEARTBEAT_TTL", "-1s")
+	_ = os.Setenv("HEARTBEAT_REAP_INTERVAL", "0s")
+	defer func() {
+		_ = os.Unsetenv("HEARTBEAT_TTL")
+		_ = os.Unsetenv("HEARTBEAT_REAP_INTERVAL")
+	}()
+
+	_, err := NewBuilder().WithEnv().Validate().Build()
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "heartbeat.ttl must not be negative")
+	assert.Contains(t, err.Error(), "heartbeat.reap_interval must be greater than zero")
+}
+
+func TestStorageFromEnv(t *testing.T) {
+	_ = os.Setenv("STORAGE_BACKEND", "bolt")
+	_ = os.Setenv("STORAGE_PATH", "/var/lib/service-discover/services.db")
+	defer func() {
+		_ = os.Unsetenv("STORAGE_BACKEND")
+		_ = os.Unsetenv("STORAGE_PATH")
+	}()
+
+	cfg, err := NewBuilder().WithEnv().Validate().Build()
+
+	require.NoError(t, err)
+	assert.Equal(t, "bolt", cfg.Storage.Backend)
+	assert.Equal(t, "/var/lib/service-discover/services.db", cfg.Storage.Path)
+}
+
+func TestValidateStorageBackend(t *testing.T) {
+	_ = os.Setenv("STORAGE_BACKEND", "postgres")
+	defer func() {
+		_ = os.Unsetenv("STORAGE_BACKEND")
+	}()
+
+	_, err := NewBuilder().WithEnv().Validate().Build()
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "storage.backend must be one of: memory, bolt")
+}
+
+func TestClusterFromEnv(t *testing.T) {
+	_ = os.Setenv("CLUSTER_ENABLED", "true")
+	_ = os.Setenv("CLUSTER_NODE_ID", "sd-1")
+	_ = os.Setenv("CLUSTER_BIND_ADDR", "0.0.0.0:7000")
+	_ = os.Setenv("CLUSTER_DATA_DIR", "/var/lib/raft")
+	_ = os.Setenv("CLUSTER_PEERS", "sd-0@10.0.0.1:7000@http://10.0.0.1:8080, sd-1@10.0.0.2:7000@http://10.0.0.2:8080/")
+	_ = os.Setenv("CLUSTER_CONSISTENT_READS", "true")
+	defer func() {
+		_ = os.Unsetenv("CLUSTER_ENABLED")
+		_ = os.Unsetenv("CLUSTER_NODE_ID")
+		_ = os.Unsetenv("CLUSTER_BIND_ADDR")
+		_ = os.Unsetenv("CLUSTER_DATA_DIR")
+		_ = os.Unsetenv("CLUSTER_PEERS")
+		_ = os.Unsetenv("CLUSTER_CONSISTENT_READS")
+	}()
+
+	cfg, err := NewBuilder().WithEnv().Validate().Build()
+
+	require.NoError(t, err)
+	assert.True(t, cfg.Cluster.Enabled)
+	assert.Equal(t, "sd-1", cfg.Cluster.NodeID)
+	assert.Equal(t, "/var/lib/raft", cfg.Cluster.DataDir)
+	assert.True(t, cfg.Cluster.ConsistentReads)
+	require.Len(t, cfg.Cluster.Peers, 2)
+	assert.Equal(t, Peer{ID: "sd-1", RaftAddr: "10.0.0.2:7000", APIAddr: "http://10.0.0.2:8080"}, cfg.Cluster.Peers[1])
+
+	self, ok := cfg.Cluster.Self()
+	assert.True(t, ok)
+	assert.Equal(t, "10.0.0.2:7000", self.RaftAddr)
+}
+
+func TestValidateCluster(t *testing.T) {
+	tests := []struct {
+		name   string
+		env    map[string]string
+		errMsg string
+	}{
+		{"missing node id", map[string]string{"CLUSTER_ENABLED": "true"}, "cluster.node_id is required"},
+		{"node not in peers", map[string]string{
+			"CLUSTER_ENABLED": "true",
+			"CLUSTER_NODE_ID": "sd-9",
+			"CLUSTER_PEERS":   "sd-0@10.0.0.1:7000@http://10.0.0.1:8080",
+		}, "cluster.peers must include cluster.node_id"},
+		{"malformed peers", map[string]string{"CLUSTER_PEERS": "sd-0@10.0.0.1:7000"}, "cluster.peers (CLUSTER_PEERS): must be a comma-separated list"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				_ = os.Setenv(k, v)
+			}
+			defer func() {
+				for k := range tt.env {
+					_ = os.Unsetenv(k)
+				}
+			}()
+
+			_, err := NewBuilder().WithEnv().Validate().Build()
+
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.errMsg)
+		})
+	}
+}
+
+func TestGatewayFromEnv(t *testing.T) {
+	_ = os.Setenv("GATEWAY_ENABLED", "true")
+	_ = os.Setenv("GATEWAY_PORT", "9000")
+	_ = os.Setenv("GATEWAY_NAMESPACE", "prod")
+	_ = os.Setenv("GATEWAY_TIMEOUT", "5s")
+	defer func() {
+		_ = os.Unsetenv("GATEWAY_ENABLED")
+		_ = os.Unsetenv("GATEWAY_PORT")
+		_ = os.Unsetenv("GATEWAY_NAMESPACE")
+		_ = os.Unsetenv("GATEWAY_TIMEOUT")
+	}()
+
+	cfg, err := NewBuilder().WithEnv().Validate().Build()
+
+	require.NoError(t, err)
+	assert.True(t, cfg.Gateway.Enabled)
+	assert.Equal(t, 9000, cfg.Gateway.Port)
+	assert.Equal(t, "prod", cfg.Gateway.Namespace)
+	assert.Equal(t, 5*time.Second, cfg.Gateway.Timeout)
+}
+
+func TestValidateGateway(t *testing.T) {
+	_ = os.Setenv("GATEWAY_ENABLED", "true")
+	_ = os.Setenv("GATEWAY_PORT", "8080")
+	_ = os.Setenv("GATEWAY_NAMESPACE", "Prod")
+	_ = os.Setenv("GATEWAY_TIMEOUT", "0s")
+	defer func() {
+		_ = os.Unsetenv("GATEWAY_ENABLED")
+		_ = os.Unsetenv("GATEWAY_PORT")
+		_ = os.Unsetenv("GATEWAY_NAMESPACE")
+		_ = os.Unsetenv("GATEWAY_TIMEOUT")
+	}()
+
+	_, err := NewBuilder().WithEnv().Validate().Build()
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "gateway.port must differ from port")
+	assert.Contains(t, err.Error(), "gateway.namespace must be lowercase letters")
+	assert.Contains(t, err.Error(), "gateway.timeout must be greater than zero")
+}
+
+func TestDNSFromEnv(t *testing.T) {
+	_ = os.Setenv("DNS_ENABLED", "true")
+	_ = os.Setenv("DNS_PORT", "53")
+	_ = os.Setenv("DNS_DOMAIN", "cluster.local")
+	_ = os.Setenv("DNS_TTL", "5s")
+	defer func() {
+		_ = os.Unsetenv("DNS_ENABLED")
+		_ = os.Unsetenv("DNS_PORT")
+		_ = os.Unsetenv("DNS_DOMAIN")
+		_ = os.Unsetenv("DNS_TTL")
+	}()
+
+	cfg, err := NewBuilder().WithEnv().Validate().Build()
+
+	require.NoError(t, err)
+	assert.True(t, cfg.DNS.Enabled)
+	assert.Equal(t, 53, cfg.DNS.Port)
+	assert.Equal(t, "cluster.local", cfg.DNS.Domain)
+	assert.Equal(t, 5*time.Second, cfg.DNS.TTL)
+}
+
+func TestValidateDNS(t *testing.T) {
+	_ = os.Setenv("DNS_ENABLED", "true")
+	_ = os.Setenv("DNS_PORT", "8080")
+	_ = os.Setenv("DNS_DOMAIN", ".")
+	_ = os.Setenv("DNS_TTL", "-1s")
+	defer func() {
+		_ = os.Unsetenv("DNS_ENABLED")
+		_ = os.Unsetenv("DNS_PORT")
+		_ = os.Unsetenv("DNS_DOMAIN")
+		_ = os.Unsetenv("DNS_TTL")
+	}()
+
+	_, err := NewBuilder().WithEnv().Validate().Build()
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "dns.port must differ from port and gateway.port")
+	assert.Contains(t, err.Error(), "dns.domain is required")
+	assert.Contains(t, err.Error(), "dns.ttl must not be negative")
+}
+
+func TestAuthFromEnv(t *testing.T) {
+	_ = os.Setenv("AUTH_ENABLED", "true")
+	_ = os.Setenv("AUTH_API_KEYS", "reader:read, ci:key:read+write")
+	_ = os.Setenv("AUTH_JWT_SECRET", "0123456789abcdef0123456789abcdef")
+	_ = os.Setenv("AUTH_JWT_ISSUER", "video-ia")
+	defer func() {
+		_ = os.Unsetenv("AUTH_ENABLED")
+		_ = os.Unsetenv("AUTH_API_KEYS")
+		_ = os.Unsetenv("AUTH_JWT_SECRET")
+		_ = os.Unsetenv("AUTH_JWT_ISSUER")
+	}()
+
+	cfg, err := NewBuilder().WithEnv().Validate().Build()
+
+	require.NoError(t, err)
+	assert.True(t, cfg.Auth.Enabled)
+	assert.Equal(t, []APIKey{
+		{Key: "reader", Scopes: []string{"read"}},
+		{Key: "ci:key", Scopes: []string{"read", "write"}},
+	}, cfg.Auth.APIKeys)
+	assert.Equal(t, "video-ia", cfg.Auth.JWTIssuer)
+}
+
+func TestValidateAuth(t *testing.T) {
+	_ = os.Setenv("AUTH_ENABLED", "true")
+	defer func() {
+		_ = os.Unsetenv("AUTH_ENABLED")
+		_ = os.Unsetenv("AUTH_JWT_SECRET")
+	}()
+
+	_, err := NewBuilder().WithEnv().Validate().Build()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "auth.api_keys or auth.jwt_secret is required")
+
+	_ = os.Setenv("AUTH_JWT_SECRET", "short")
+	_, err = NewBuilder().WithEnv().Validate().Build()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "auth.jwt_secret must be at least 32 bytes")
+}
+
+func TestParseAPIKeysInvalid(t *testing.T) {
+	_, err := ParseAPIKeys("no-scopes")
+	assert.Error(t, err)
+
+	_, err = ParseAPIKeys("key:root")
+	assert.Error(t, err)
+}
+
+func TestParseAPIKeysAdmin(t *testing.T) {
+	keys, err := ParseAPIKeys("ops:admin")
+	require.NoError(t, err)
+	assert.Equal(t, []APIKey{{Key: "ops", Scopes: []string{"admin"}}}, keys)
+}
+
+func TestTLSFromEnv(t *testing.T) {
+	_ = os.Setenv("TLS_ENABLED", "true")
+	_ = os.Setenv("TLS_CERT_FILE", "/etc/sd/tls.crt")
+	_ = os.Setenv("TLS_KEY_FILE", "/etc/sd/tls.key")
+	_ = os.Setenv("TLS_CLIENT_CA_FILE", "/etc/sd/ca.crt")
+	_ = os.Setenv("TLS_CLIENT_AUTH", "require")
+	_ = os.Setenv("TLS_RELOAD_INTERVAL", "1m")
+	defer func() {
+		_ = os.Unsetenv("TLS_ENABLED")
+		_ = os.Unsetenv("TLS_CERT_FILE")
+		_ = os.Unsetenv("TLS_KEY_FILE")
+		_ = os.Unsetenv("TLS_CLIENT_CA_FILE")
+		_ = os.Unsetenv("TLS_CLIENT_AUTH")
+		_ = os.Unsetenv("TLS_RELOAD_INTERVAL")
+	}()
+
+	cfg, err := NewBuilder().WithEnv().Validate().Build()
+
+	require.NoError(t, err)
+	assert.Equal(t, TLSConfig{
+		Enabled:        true,
+		CertFile:       "/etc/sd/tls.crt",
+		KeyFile:        "/etc/sd/tls.key",
+		ClientCAFile:   "/etc/sd/ca.crt",
+		ClientAuth:     "require",
+		ReloadInterval: time.Minute,
+	}, cfg.TLS)
+}
+
+func TestValidateTLS(t *testing.T) {
+	_ = os.Setenv("TLS_ENABLED", "true")
+	_ = os.Setenv("TLS_CLIENT_AUTH", "optional")
+	defer func() {
+		_ = os.Unsetenv("TLS_ENABLED")
+		_ = os.Unsetenv("TLS_CLIENT_AUTH")
+	}()
+
+	_, err := NewBuilder().WithEnv().Validate().Build()
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "tls.cert_file is required")
+	assert.Contains(t, err.Error(), "tls.key_file is required")
+	assert.Contains(t, err.Error(), "tls.client_ca_file is required")
+
+	_ = os.Setenv("TLS_CLIENT_AUTH", "always")
+	_, err = NewBuilder().WithEnv().Validate().Build()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "tls.client_auth must be one of")
+}
+
+func TestMetricsFromEnv(t *testing.T) {
+	cfg, err := NewBuilder().WithEnv().Validate().Build()
+	require.NoError(t, err)
+	assert.True(t, cfg.Metrics.Enabled)
+
+	_ = os.Setenv("METRICS_ENABLED", "false")
+	defer func() {
+		_ = os.Unsetenv("METRICS_ENABLED")
+	}()
+
+	cfg, err = NewBuilder().WithEnv().Validate().Build()
+	require.NoError(t, err)
+	assert.False(t, cfg.Metrics.Enabled)
+}
+
+func TestTracingFromEnv(t *testing.T) {
+	_ = os.Setenv("TRACING_ENABLED", "true")
+	_ = os.Setenv("TRACING_OTLP_ENDPOINT", "http://otel-collector:4318")
+	defer func() {
+		_ = os.Unsetenv("TRACING_ENABLED")
+		_ = os.Unsetenv("TRACING_OTLP_ENDPOINT")
+	}()
+
+	cfg, err := NewBuilder().WithEnv().Validate().Build()
+	require.NoError(t, err)
+	assert.True(t, cfg.Tracing.Enabled)
+	assert.Equal(t, "http://otel-collector:4318", cfg.Tracing.Endpoint)
+
+	_ = os.Setenv("TRACING_OTLP_ENDPOINT", "otel-collector:4318")
+	_, err = NewBuilder().WithEnv().Validate().Build()
+	assert.ErrorContains(t, err, "tracing.otlp_endpoint")
+}
+
+func TestServerTimeoutsFromEnv(t *testing.T) {
+	_ = os.Setenv("SERVER_READ_TIMEOUT", "5s")
+	_ = os.Setenv("SERVER_WRITE_TIMEOUT", "10m")
+	_ = os.Setenv("SERVER_IDLE_TIMEOUT", "1m")
+	_ = os.Setenv("SERVER_SHUTDOWN_TIMEOUT", "45s")
+	defer func() {
+		_ = os.Unsetenv("SERVER_READ_TIMEOUT")
+		_ = os.Unsetenv("SERVER_WRITE_TIMEOUT")
+		_ = os.Unsetenv("SERVER_IDLE_TIMEOUT")
+		_ = os.Unsetenv("SERVER_SHUTDOWN_TIMEOUT")
+	}()
+
+	cfg, err := NewBuilder().WithEnv().Validate().Build()
+	require.NoError(t, err)
+	assert.Equal(t, 5*time.Second, cfg.Server.ReadTimeout)
+	assert.Equal(t, 10*time.Minute, cfg.Server.WriteTimeout)
+	assert.Equal(t, time.Minute, cfg.Server.IdleTimeout)
+	assert.Equal(t, 45*time.Second, cfg.Server.ShutdownTimeout)
+
+	_ = os.Setenv("SERVER_SHUTDOWN_TIMEOUT", "0s")
+	_, err = NewBuilder().WithEnv().Validate().Build()
+	assert.ErrorContains(t, err, "server.shutdown_timeout must be greater than zero")
+}
@@ -0,0 +1,166 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+const redacted = "REDACTED"
+
+// WithFile reads settings from a YAML (.yaml, .yml) or TOML (.toml) file.
+// Keys follow the settings' paths, e.g. health_check.interval is interval
+// in the health_check section; unknown keys are reported. cluster.peers and
+// auth.api_keys take a list of the entries their environment variables
+// join with commas. An empty path is ignored.
+func (b *Builder) WithFile(path string) *Builder {
+	if path == "" {
+		return b
+	}
+
+	values, err := readFile(path)
+	if err != nil {
+		b.errors = append(b.errors, err)
+		return b
+	}
+
+	byPath := make(map[string]setting)
+	for _, s := range settings(b.config) {
+		byPath[s.path] = s
+	}
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		s, ok := byPath[key]
+		if !ok {
+			b.errors = append(b.errors, fmt.Errorf("%s: unknown setting %s", path, key))
+			continue
+		}
+		if err := s.set(values[key]); err != nil {
+			b.errors = append(b.errors, fmt.Errorf("%s: %s %w", path, key, err))
+		}
+	}
+	return b
+}
+
+// readFile decodes the file at path into its settings' values, keyed by
+// path.
+func readFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading config file: %w", err)
+	}
+
+	var doc map[string]any
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &doc)
+	case ".toml":
+		err = toml.Unmarshal(data, &doc)
+	default:
+		return nil, fmt.Errorf("%s: unsupported config file extension %q (use .yaml, .yml or .toml)", path, ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	values := make(map[string]string)
+	if err := flatten("", doc, values); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return values, nil
+}
+
+func flatten(prefix string, doc map[string]any, values map[string]string) error {
+	for key, value := range doc {
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		switch v := value.(type) {
+		case map[string]any:
+			if err := flatten(key, v, values); err != nil {
+				return err
+			}
+		case []any:
+			entries := make([]string, len(v))
+			for i, entry := range v {
+				if _, ok := entry.(map[string]any); ok {
+					return errors.New(key + " must be a list of strings")
+				}
+				entries[i] = fmt.Sprint(entry)
+			}
+			values[key] = strings.Join(entries, ",")
+		case nil:
+			values[key] = ""
+		default:
+			values[key] = fmt.Sprint(v)
+		}
+	}
+	return nil
+}
+
+// Print writes cfg as YAML in the layout WithFile reads, with secrets
+// replaced by REDACTED.
+func Print(w io.Writer, cfg *Config) error {
+	root := &yaml.Node{Kind: yaml.MappingNode}
+	sections := make(map[string]*yaml.Node)
+
+	for _, s := range settings(cfg) {
+		parent, key := root, s.path
+		if i := strings.Index(s.path, "."); i >= 0 {
+			section := s.path[:i]
+			key = s.path[i+1:]
+			if sections[section] == nil {
+				sections[section] = &yaml.Node{Kind: yaml.MappingNode}
+				root.Content = append(root.Content, scalar(section), sections[section])
+			}
+			parent = sections[section]
+		}
+		parent.Content = append(parent.Content, scalar(key), value(s))
+	}
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(root); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+func scalar(v string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Value: v}
+}
+
+func value(s setting) *yaml.Node {
+	v := s.get()
+	if s.redact != nil {
+		v = s.redact()
+	}
+
+	switch {
+	case s.isList:
+		list := &yaml.Node{Kind: yaml.SequenceNode}
+		for _, entry := range strings.Split(v, ",") {
+			if entry != "" {
+				list.Content = append(list.Content, scalar(entry))
+			}
+		}
+		return list
+	case v == "":
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: v}
+	default:
+		return scalar(v)
+	}
+}
@@ -0,0 +1,169 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeConfig(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestWithFileYAML(t *testing.T) {
+	path := writeConfig(t, "config.yaml", `
+port: 9090
+log_level: debug
+server:
+  shutdown_timeout: 40s
+health_check:
+  enabled: false
+cluster:
+  enabled: true
+  node_id: sd-0
+  peers:
+    - sd-0@10.0.0.1:7000@http://10.0.0.1:8080
+    - sd-1@10.0.0.2:7000@http://10.0.0.2:8080
+auth:
+  enabled: true
+  api_keys: ["k1:read", "k2:read+write"]
+`)
+
+	cfg, err := NewBuilder().WithFile(path).Validate().Build()
+
+	require.NoError(t, err)
+	assert.Equal(t, 9090, cfg.Port)
+	assert.Equal(t, "debug", cfg.LogLevel)
+	assert.Equal(t, 40*time.Second, cfg.Server.ShutdownTimeout)
+	assert.False(t, cfg.HealthCheck.Enabled)
+	assert.True(t, cfg.Cluster.Enabled)
+	require.Len(t, cfg.Cluster.Peers, 2)
+	assert.Equal(t, "10.0.0.2:7000", cfg.Cluster.Peers[1].RaftAddr)
+	assert.Equal(t, []APIKey{
+		{Key: "k1", Scopes: []string{"read"}},
+		{Key: "k2", Scopes: []string{"read", "write"}},
+	}, cfg.Auth.APIKeys)
+	assert.Equal(t, "release", cfg.GinMode, "unset keys keep their defaults")
+}
+
+func TestWithFileTOML(t *testing.T) {
+	path := writeConfig(t, "config.toml", `
+port = 9090
+
+[gateway]
+enabled = true
+port = 9000
+timeout = "5s"
+
+[tls]
+client_auth = "optional"
+`)
+
+	cfg, err := NewBuilder().WithFile(path).Validate().Build()
+
+	require.NoError(t, err)
+	assert.Equal(t, 9090, cfg.Port)
+	assert.True(t, cfg.Gateway.Enabled)
+	assert.Equal(t, 9000, cfg.Gateway.Port)
+	assert.Equal(t, 5*time.Second, cfg.Gateway.Timeout)
+	assert.Equal(t, "optional", cfg.TLS.ClientAuth)
+}
+
+func TestWithFileReportsBadKeys(t *testing.T) {
+	path := writeConfig(t, "config.yaml", `
+port: eighty
+gateway:
+  prot: 9000
+dns:
+  ttl: soon
+`)
+
+	_, err := NewBuilder().WithFile(path).Validate().Build()
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), path+": port must be a valid integer")
+	assert.Contains(t, err.Error(), path+": unknown setting gateway.prot")
+	assert.Contains(t, err.Error(), path+": dns.ttl must be a valid duration")
+}
+
+func TestWithFileRejectsUnknownFormat(t *testing.T) {
+	path := writeConfig(t, "config.json", `{"port": 9090}`)
+
+	_, err := NewBuilder().WithFile(path).Build()
+
+	assert.ErrorContains(t, err, "unsupported config file extension")
+}
+
+func TestWithFileMissing(t *testing.T) {
+	_, err := NewBuilder().WithFile(filepath.Join(t.TempDir(), "missing.yaml")).Build()
+
+	assert.ErrorContains(t, err, "reading config file")
+}
+
+func TestPrecedence(t *testing.T) {
+	path := writeConfig(t, "config.yaml", `
+port: 9000
+log_level: debug
+gin_mode: debug
+`)
+	_ = os.Setenv("PORT", "9100")
+	_ = os.Setenv("GIN_MODE", "test")
+	defer func() {
+		_ = os.Unsetenv("PORT")
+		_ = os.Unsetenv("GIN_MODE")
+	}()
+
+	cfg, err := NewBuilder().
+		WithFile(path).
+		WithEnv().
+		WithFlags([]string{"--port=9200"}).
+		Validate().
+		Build()
+
+	require.NoError(t, err)
+	assert.Equal(t, 9200, cfg.Port, "flags override env")
+	assert.Equal(t, "test", cfg.GinMode, "env overrides the file")
+	assert.Equal(t, "debug", cfg.LogLevel, "the file overrides defaults")
+	assert.Equal(t, 10*time.Second, cfg.HealthCheck.Interval)
+}
+
+func TestPrintRedactsSecrets(t *testing.T) {
+	cfg := NewBuilder().config
+	cfg.Auth.JWTSecret = "a-secret-that-is-long-enough-for-hs256"
+	cfg.Auth.APIKeys = []APIKey{{Key: "k1", Scopes: []string{"read", "write"}}}
+	cfg.Cluster.Peers = []Peer{{ID: "sd-0", RaftAddr: "10.0.0.1:7000", APIAddr: "http://10.0.0.1:8080"}}
+
+	var out bytes.Buffer
+	require.NoError(t, Print(&out, cfg))
+
+	assert.NotContains(t, out.String(), "a-secret")
+	assert.NotContains(t, out.String(), "k1")
+	assert.Contains(t, out.String(), "jwt_secret: REDACTED")
+	assert.Contains(t, out.String(), "- REDACTED:read+write")
+	assert.Contains(t, out.String(), "- sd-0@10.0.0.1:7000@http://10.0.0.1:8080")
+	assert.Contains(t, out.String(), "shutdown_timeout: 25s")
+}
+
+func TestPrintRoundTrips(t *testing.T) {
+	cfg := NewBuilder().config
+	cfg.Port = 9090
+	cfg.Tracing.Endpoint = "http://otel-collector:4318"
+	cfg.Cluster.Peers = []Peer{{ID: "sd-0", RaftAddr: "10.0.0.1:7000", APIAddr: "http://10.0.0.1:8080"}}
+
+	var out bytes.Buffer
+	require.NoError(t, Print(&out, cfg))
+	path := writeConfig(t, "printed.yaml", out.String())
+
+	read, err := NewBuilder().WithFile(path).Build()
+
+	require.NoError(t, err)
+	assert.Equal(t, cfg, read)
+}
@@ -0,0 +1,62 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+// CommandLine holds the flags that control the program rather than
+// override a setting.
+type CommandLine struct {
+	// ConfigFile is the file given with --config, or else in CONFIG_FILE.
+	ConfigFile  string
+	PrintConfig bool
+}
+
+// ParseCommandLine reads --config and --print-config from args. Flags
+// overriding settings are checked here too, so that a mistake in them
+// prints the usage, but are applied by WithFlags. It returns flag.ErrHelp
+// for -h and --help.
+func ParseCommandLine(args []string) (CommandLine, error) {
+	cl := CommandLine{ConfigFile: os.Getenv("CONFIG_FILE")}
+	fs := newFlagSet(NewBuilder().config, &cl)
+	fs.SetOutput(os.Stderr)
+	if err := fs.Parse(args); err != nil {
+		return cl, err
+	}
+	if fs.NArg() > 0 {
+		return cl, fmt.Errorf("unexpected argument %q", fs.Arg(0))
+	}
+	return cl, nil
+}
+
+// WithFlags applies the settings given in args, one flag per setting named
+// after its path, e.g. --health-check-interval=5s for
+// health_check.interval. Boolean flags may omit the value to enable the
+// setting.
+func (b *Builder) WithFlags(args []string) *Builder {
+	fs := newFlagSet(b.config, &CommandLine{})
+	fs.SetOutput(io.Discard)
+	if err := fs.Parse(args); err != nil {
+		b.errors = append(b.errors, err)
+	}
+	return b
+}
+
+func newFlagSet(c *Config, cl *CommandLine) *flag.FlagSet {
+	fs := flag.NewFlagSet("service-discover", flag.ContinueOnError)
+	fs.StringVar(&cl.ConfigFile, "config", cl.ConfigFile, "YAML or TOML config file (env CONFIG_FILE)")
+	fs.BoolVar(&cl.PrintConfig, "print-config", false, "print the effective configuration, secrets redacted, and exit")
+
+	for _, s := range settings(c) {
+		usage := fmt.Sprintf("%s (env %s)", s.usage, s.env())
+		if s.isBool {
+			fs.BoolFunc(s.flag(), usage, s.set)
+		} else {
+			fs.Func(s.flag(), usage, s.set)
+		}
+	}
+	return fs
+}
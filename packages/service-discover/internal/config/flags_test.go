@@ -0,0 +1,71 @@
+package config
+
+import (
+	"flag"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithFlags(t *testing.T) {
+	cfg, err := NewBuilder().
+		WithFlags([]string{
+			"--port=9090",
+			"--tls-enabled",
+			"--tls-cert-file", "/etc/tls/tls.crt",
+			"--tls-key-file=/etc/tls/tls.key",
+			"--health-check-enabled=false",
+			"--heartbeat-ttl=30s",
+			"--cluster-peers=sd-0@10.0.0.1:7000@http://10.0.0.1:8080",
+			"--config=ignored.yaml",
+		}).
+		Validate().
+		Build()
+
+	require.NoError(t, err)
+	assert.Equal(t, 9090, cfg.Port)
+	assert.True(t, cfg.TLS.Enabled)
+	assert.Equal(t, "/etc/tls/tls.crt", cfg.TLS.CertFile)
+	assert.Equal(t, "/etc/tls/tls.key", cfg.TLS.KeyFile)
+	assert.False(t, cfg.HealthCheck.Enabled)
+	assert.Equal(t, 30*time.Second, cfg.Heartbeat.TTL)
+	require.Len(t, cfg.Cluster.Peers, 1)
+}
+
+func TestWithFlagsInvalid(t *testing.T) {
+	_, err := NewBuilder().WithFlags([]string{"--port=eighty"}).Build()
+	assert.ErrorContains(t, err, "must be a valid integer")
+
+	_, err = NewBuilder().WithFlags([]string{"--no-such-flag"}).Build()
+	assert.ErrorContains(t, err, "no-such-flag")
+}
+
+func TestParseCommandLine(t *testing.T) {
+	cl, err := ParseCommandLine([]string{"--config", "/etc/service-discover.yaml", "--print-config", "--port=9090"})
+	require.NoError(t, err)
+	assert.Equal(t, CommandLine{ConfigFile: "/etc/service-discover.yaml", PrintConfig: true}, cl)
+
+	_ = os.Setenv("CONFIG_FILE", "/etc/from-env.toml")
+	defer func() {
+		_ = os.Unsetenv("CONFIG_FILE")
+	}()
+
+	cl, err = ParseCommandLine(nil)
+	require.NoError(t, err)
+	assert.Equal(t, "/etc/from-env.toml", cl.ConfigFile)
+
+	_, err = ParseCommandLine([]string{"serve"})
+	assert.ErrorContains(t, err, `unexpected argument "serve"`)
+}
+
+func TestParseCommandLineHelp(t *testing.T) {
+	stderr := os.Stderr
+	os.Stderr, _ = os.Open(os.DevNull)
+	defer func() { os.Stderr = stderr }()
+
+	_, err := ParseCommandLine([]string{"-h"})
+	assert.ErrorIs(t, err, flag.ErrHelp)
+}
@@ -0,0 +1,212 @@
+package config
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// setting is one configurable field. Its path names it in config files
+// (health_check.interval) and derives its environment variable
+// (HEALTH_CHECK_INTERVAL) and command-line flag (--health-check-interval).
+type setting struct {
+	path   string
+	usage  string
+	isBool bool
+	isList bool
+	set    func(value string) error
+	get    func() string
+	// redact, set for secrets, returns the value with the secret parts
+	// replaced.
+	redact func() string
+}
+
+func (s setting) env() string {
+	return strings.ToUpper(strings.ReplaceAll(s.path, ".", "_"))
+}
+
+func (s setting) flag() string {
+	return strings.NewReplacer(".", "-", "_", "-").Replace(s.path)
+}
+
+// settings lists every field of c in the order they are documented and
+// printed.
+func settings(c *Config) []setting {
+	return []setting{
+		intSetting("port", "API port", &c.Port),
+		stringSetting("log_level", "log level: debug, info, warn or error", &c.LogLevel),
+		stringSetting("gin_mode", "gin mode: debug, release or test", &c.GinMode),
+
+		durationSetting("server.read_timeout", "maximum time to read a request (0 disables)", &c.Server.ReadTimeout),
+		durationSetting("server.write_timeout", "maximum time to write a response (0 disables)", &c.Server.WriteTimeout),
+		durationSetting("server.idle_timeout", "how long idle keep-alive connections are kept (0 disables)", &c.Server.IdleTimeout),
+		durationSetting("server.shutdown_timeout", "how long in-flight requests may take to complete on shutdown", &c.Server.ShutdownTimeout),
+
+		boolSetting("health_check.enabled", "probe the health check URL of registered instances", &c.HealthCheck.Enabled),
+		durationSetting("health_check.interval", "time between health probes", &c.HealthCheck.Interval),
+		durationSetting("health_check.timeout", "timeout of each health probe", &c.HealthCheck.Timeout),
+		intSetting("health_check.success_threshold", "consecutive successes to mark an instance healthy", &c.HealthCheck.SuccessThreshold),
+		intSetting("health_check.failure_threshold", "consecutive failures to mark an instance unhealthy", &c.HealthCheck.FailureThreshold),
+
+		durationSetting("heartbeat.ttl", "default heartbeat TTL of registrations (0 disables expiry)", &c.Heartbeat.TTL),
+		durationSetting("heartbeat.deregister_after", "how long an expired instance is kept before removal", &c.Heartbeat.DeregisterAfter),
+		durationSetting("heartbeat.reap_interval", "time between heartbeat expiry checks", &c.Heartbeat.ReapInterval),
+
+		stringSetting("storage.backend", "storage backend: memory or bolt", &c.Storage.Backend),
+		stringSetting("storage.path", "bolt database file", &c.Storage.Path),
+
+		boolSetting("cluster.enabled", "replicate the catalog with Raft", &c.Cluster.Enabled),
+		stringSetting("cluster.node_id", "this node's ID among cluster.peers", &c.Cluster.NodeID),
+		stringSetting("cluster.bind_addr", "Raft listen address", &c.Cluster.BindAddr),
+		stringSetting("cluster.data_dir", "Raft log and snapshot directory (empty keeps them in memory)", &c.Cluster.DataDir),
+		{
+			path:   "cluster.peers",
+			usage:  "comma-separated id@raft_addr@api_url entries, including this node",
+			isList: true,
+			set: func(value string) error {
+				peers, err := ParsePeers(value)
+				if err == nil {
+					c.Cluster.Peers = peers
+				}
+				return err
+			},
+			get: func() string {
+				entries := make([]string, len(c.Cluster.Peers))
+				for i, p := range c.Cluster.Peers {
+					entries[i] = p.ID + "@" + p.RaftAddr + "@" + p.APIAddr
+				}
+				return strings.Join(entries, ",")
+			},
+		},
+		boolSetting("cluster.consistent_reads", "apply all committed writes before serving reads", &c.Cluster.ConsistentReads),
+		durationSetting("cluster.apply_timeout", "how long a write waits to be committed", &c.Cluster.ApplyTimeout),
+		boolSetting("cluster.insecure_transport", "allow plaintext Raft traffic on a non-loopback cluster.bind_addr", &c.Cluster.InsecureTransport),
+
+		intSetting("events.buffer_size", "recent events kept for reconnecting subscribers", &c.Events.BufferSize),
+
+		boolSetting("gateway.enabled", "serve the reverse proxy gateway", &c.Gateway.Enabled),
+		intSetting("gateway.port", "gateway port", &c.Gateway.Port),
+		stringSetting("gateway.namespace", "namespace whose services the gateway routes to", &c.Gateway.Namespace),
+		durationSetting("gateway.timeout", "default upstream timeout of gateway routes", &c.Gateway.Timeout),
+
+		boolSetting("dns.enabled", "serve DNS for registered services", &c.DNS.Enabled),
+		intSetting("dns.port", "DNS port (UDP and TCP)", &c.DNS.Port),
+		stringSetting("dns.domain", "DNS domain answered for", &c.DNS.Domain),
+		durationSetting("dns.ttl", "TTL of DNS records", &c.DNS.TTL),
+
+		boolSetting("auth.enabled", "require API keys or JWTs", &c.Auth.Enabled),
+		{
+			path:   "auth.api_keys",
+			usage:  "comma-separated key:scopes entries, scopes joined with + (read, write, admin)",
+			isList: true,
+			set: func(value string) error {
+				keys, err := ParseAPIKeys(value)
+				if err == nil {
+					c.Auth.APIKeys = keys
+				}
+				return err
+			},
+			get: func() string {
+				entries := make([]string, len(c.Auth.APIKeys))
+				for i, k := range c.Auth.APIKeys {
+					entries[i] = k.Key + ":" + strings.Join(k.Scopes, "+")
+				}
+				return strings.Join(entries, ",")
+			},
+			redact: func() string {
+				entries := make([]string, len(c.Auth.APIKeys))
+				for i, k := range c.Auth.APIKeys {
+					entries[i] = redacted + ":" + strings.Join(k.Scopes, "+")
+				}
+				return strings.Join(entries, ",")
+			},
+		},
+		secretSetting(stringSetting("auth.jwt_secret", "HMAC secret of accepted JWTs", &c.Auth.JWTSecret)),
+		stringSetting("auth.jwt_issuer", "required iss claim of JWTs", &c.Auth.JWTIssuer),
+		stringSetting("auth.jwt_audience", "required aud claim of JWTs", &c.Auth.JWTAudience),
+
+		boolSetting("tls.enabled", "serve the API over HTTPS", &c.TLS.Enabled),
+		stringSetting("tls.cert_file", "server certificate (PEM)", &c.TLS.CertFile),
+		stringSetting("tls.key_file", "server private key (PEM)", &c.TLS.KeyFile),
+		stringSetting("tls.client_ca_file", "CA bundle verifying client certificates (PEM)", &c.TLS.ClientCAFile),
+		stringSetting("tls.client_auth", "client certificates: none, optional or require", &c.TLS.ClientAuth),
+		durationSetting("tls.reload_interval", "time between checks of the certificate files", &c.TLS.ReloadInterval),
+
+		boolSetting("metrics.enabled", "expose Prometheus metrics on /metrics", &c.Metrics.Enabled),
+
+		boolSetting("tracing.enabled", "export OpenTelemetry traces", &c.Tracing.Enabled),
+		stringSetting("tracing.otlp_endpoint", "OTLP/HTTP endpoint URL, e.g. http://otel-collector:4318", &c.Tracing.Endpoint),
+	}
+}
+
+func stringSetting(path, usage string, dst *string) setting {
+	return setting{
+		path:  path,
+		usage: usage,
+		set: func(value string) error {
+			*dst = value
+			return nil
+		},
+		get: func() string { return *dst },
+	}
+}
+
+func intSetting(path, usage string, dst *int) setting {
+	return setting{
+		path:  path,
+		usage: usage,
+		set: func(value string) error {
+			v, err := strconv.Atoi(value)
+			if err != nil {
+				return errors.New("must be a valid integer")
+			}
+			*dst = v
+			return nil
+		},
+		get: func() string { return strconv.Itoa(*dst) },
+	}
+}
+
+func boolSetting(path, usage string, dst *bool) setting {
+	return setting{
+		path:   path,
+		usage:  usage,
+		isBool: true,
+		set: func(value string) error {
+			v, err := strconv.ParseBool(value)
+			if err != nil {
+				return errors.New("must be a valid boolean")
+			}
+			*dst = v
+			return nil
+		},
+		get: func() string { return strconv.FormatBool(*dst) },
+	}
+}
+
+func durationSetting(path, usage string, dst *time.Duration) setting {
+	return setting{
+		path:  path,
+		usage: usage,
+		set: func(value string) error {
+			v, err := time.ParseDuration(value)
+			if err != nil {
+				return errors.New("must be a valid duration (e.g. 10s, 1m)")
+			}
+			*dst = v
+			return nil
+		},
+		get: func() string { return dst.String() },
+	}
+}
+
+func secretSetting(s setting) setting {
+	s.redact = func() string {
+		if s.get() == "" {
+			return ""
+		}
+		return redacted
+	}
+	return s
+}
@@ -0,0 +1,256 @@
+package dnsserver
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+	"go.uber.org/zap"
+
+	"github.com/carlosealves2/video-ia/service-discover/internal/config"
+	"github.com/carlosealves2/video-ia/service-discover/internal/domain"
+	"github.com/carlosealves2/video-ia/service-discover/internal/repository"
+)
+
+const shutdownTimeout = 5 * time.Second
+
+// Server answers DNS queries for registered services:
+//
+//	<name>.service.<domain>        A/AAAA and SRV for every healthy instance
+//	<tag>.<name>.service.<domain>  the same, restricted to instances with tag
+//	<id>.addr.<domain>             A/AAAA of one instance, used as SRV target
+//
+// These names cover the default namespace; services in another namespace
+// are found by inserting it after "service" or "addr", as in
+// <name>.service.<namespace>.<domain>. Unhealthy instances are never
+// returned.
+type Server struct {
+	repo   repository.ServiceRepository
+	logger *zap.Logger
+	config config.DNSConfig
+	domain string
+	ttl    uint32
+
+	udp *dns.Server
+	tcp *dns.Server
+}
+
+func NewServer(repo repository.ServiceRepository, logger *zap.Logger, cfg config.DNSConfig) *Server {
+	return &Server{
+		repo:   repo,
+		logger: logger,
+		config: cfg,
+		domain: dns.Fqdn(strings.ToLower(strings.Trim(cfg.Domain, "."))),
+		ttl:    uint32(cfg.TTL / time.Second),
+	}
+}
+
+// Start binds the UDP and TCP listeners and serves them in the background.
+func (s *Server) Start() error {
+	addr := fmt.Sprintf(":%d", s.config.Port)
+
+	pc, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return fmt.Errorf("dns: listen udp %s: %w", addr, err)
+	}
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		_ = pc.Close()
+		return fmt.Errorf("dns: listen tcp %s: %w", addr, err)
+	}
+
+	s.udp = &dns.Server{PacketConn: pc, Handler: s}
+	s.tcp = &dns.Server{Listener: l, Handler: s}
+
+	for _, srv := range []*dns.Server{s.udp, s.tcp} {
+		go func(srv *dns.Server) {
+			if err := srv.ActivateAndServe(); err != nil {
+				s.logger.Error("DNS server stopped", zap.Error(err))
+			}
+		}(srv)
+	}
+
+	s.logger.Info("Starting DNS server",
+		zap.Int("port", s.config.Port),
+		zap.String("domain", s.domain),
+	)
+	return nil
+}
+
+func (s *Server) Stop() {
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	for _, srv := range []*dns.Server{s.udp, s.tcp} {
+		if srv != nil {
+			_ = srv.ShutdownContext(ctx)
+		}
+	}
+}
+
+func (s *Server) ServeDNS(w dns.ResponseWriter, r *dns.Msg) {
+	m := new(dns.Msg)
+	m.SetReply(r)
+	m.Authoritative = true
+
+	if len(r.Question) == 0 {
+		m.SetRcode(r, dns.RcodeFormatError)
+		_ = w.WriteMsg(m)
+		return
+	}
+
+	q := r.Question[0]
+	name := strings.ToLower(q.Name)
+
+	if !dns.IsSubDomain(s.domain, name) {
+		m.SetRcode(r, dns.RcodeRefused)
+		_ = w.WriteMsg(m)
+		return
+	}
+
+	labels := dns.SplitDomainName(strings.TrimSuffix(name, s.domain))
+	instances, found := s.lookup(labels)
+	if !found {
+		m.SetRcode(r, dns.RcodeNameError)
+		_ = w.WriteMsg(m)
+		return
+	}
+
+	rand.Shuffle(len(instances), func(i, j int) {
+		instances[i], instances[j] = instances[j], instances[i]
+	})
+
+	for _, svc := range instances {
+		switch q.Qtype {
+		case dns.TypeA, dns.TypeAAAA, dns.TypeANY:
+			if rr := s.address(q.Name, q.Qtype, svc); rr != nil {
+				m.Answer = append(m.Answer, rr)
+			}
+		case dns.TypeSRV:
+			target := s.target(svc)
+			m.Answer = append(m.Answer, &dns.SRV{
+				Hdr:      s.header(q.Name, dns.TypeSRV),
+				Priority: 1,
+				Weight:   1,
+				Port:     uint16(svc.Port),
+				Target:   target,
+			})
+			if rr := s.address(target, dns.TypeANY, svc); rr != nil {
+				m.Extra = append(m.Extra, rr)
+			}
+		}
+	}
+
+	s.logger.Debug("DNS query answered",
+		zap.String("name", q.Name),
+		zap.String("type", dns.TypeToString[q.Qtype]),
+		zap.Int("answers", len(m.Answer)),
+	)
+
+	if opt := r.IsEdns0(); opt != nil {
+		m.SetEdns0(opt.UDPSize(), opt.Do())
+	}
+	if _, ok := w.RemoteAddr().(*net.UDPAddr); ok {
+		m.Truncate(udpSize(r))
+	}
+
+	_ = w.WriteMsg(m)
+}
+
+// udpSize is the largest UDP reply the client of r accepts: the payload size
+// it advertises with EDNS0, or 512 bytes without it. Replies over the limit
+// are truncated and flagged TC, so the client retries over TCP.
+func udpSize(r *dns.Msg) int {
+	if opt := r.IsEdns0(); opt != nil {
+		return int(opt.UDPSize())
+	}
+	return dns.MinMsgSize
+}
+
+// lookup resolves the labels in front of the domain to the healthy instances
+// they name. found is false when the name does not exist or has no healthy
+// instance, so resolvers get NXDOMAIN rather than an empty answer.
+func (s *Server) lookup(labels []string) (instances []*domain.Service, found bool) {
+	namespace := domain.DefaultNamespace
+	if n := len(labels); n >= 3 && (labels[n-2] == "service" || labels[n-2] == "addr") {
+		namespace, labels = labels[n-1], labels[:n-1]
+	}
+
+	switch {
+	case len(labels) == 2 && labels[1] == "addr":
+		svc, err := s.repo.GetByID(domain.Key(namespace, labels[0]))
+		if err != nil || svc.Status != domain.StatusHealthy {
+			return nil, false
+		}
+		return []*domain.Service{svc}, true
+	case len(labels) == 2 && labels[1] == "service":
+		instances = s.healthy(namespace, labels[0], "")
+	case len(labels) == 3 && labels[2] == "service":
+		instances = s.healthy(namespace, labels[1], labels[0])
+	default:
+		return nil, false
+	}
+	return instances, len(instances) > 0
+}
+
+func (s *Server) healthy(namespace, name, tag string) []*domain.Service {
+	var instances []*domain.Service
+	for _, svc := range s.repo.GetAll() {
+		if svc.Status != domain.StatusHealthy || !svc.InNamespace(namespace) || !strings.EqualFold(svc.Name, name) {
+			continue
+		}
+		if tag != "" && !hasTag(svc, tag) {
+			continue
+		}
+		instances = append(instances, svc)
+	}
+	return instances
+}
+
+func hasTag(svc *domain.Service, tag string) bool {
+	for _, t := range svc.Tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// address returns the A or AAAA record for svc's host, or nil when the host
+// is not an IP or does not match qtype.
+func (s *Server) address(name string, qtype uint16, svc *domain.Service) dns.RR {
+	ip := net.ParseIP(svc.Host)
+	if ip == nil {
+		return nil
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		if qtype == dns.TypeAAAA {
+			return nil
+		}
+		return &dns.A{Hdr: s.header(name, dns.TypeA), A: ip4}
+	}
+	if qtype == dns.TypeA {
+		return nil
+	}
+	return &dns.AAAA{Hdr: s.header(name, dns.TypeAAAA), AAAA: ip}
+}
+
+// target is the SRV target for svc: its host when that is already a DNS
+// name, otherwise an addr name this server resolves to the host's IP.
+func (s *Server) target(svc *domain.Service) string {
+	if net.ParseIP(svc.Host) == nil {
+		return dns.Fqdn(svc.Host)
+	}
+	if svc.Namespace != "" && svc.Namespace != domain.DefaultNamespace {
+		return svc.ID + ".addr." + svc.Namespace + "." + s.domain
+	}
+	return svc.ID + ".addr." + s.domain
+}
+
+func (s *Server) header(name string, rrtype uint16) dns.RR_Header {
+	return dns.RR_Header{Name: name, Rrtype: rrtype, Class: dns.ClassINET, Ttl: s.ttl}
+}
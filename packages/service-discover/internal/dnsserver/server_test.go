@@ -0,0 +1,216 @@
+package dnsserver
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/carlosealves2/video-ia/service-discover/internal/config"
+	"github.com/carlosealves2/video-ia/service-discover/internal/domain"
+	"github.com/carlosealves2/video-ia/service-discover/internal/repository"
+)
+
+type recorder struct {
+	dns.ResponseWriter
+	msg *dns.Msg
+	tcp bool
+}
+
+func (r *recorder) RemoteAddr() net.Addr {
+	if r.tcp {
+		return &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 53000}
+	}
+	return &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 53000}
+}
+
+func (r *recorder) WriteMsg(m *dns.Msg) error {
+	r.msg = m
+	return nil
+}
+
+func newTestServer(t *testing.T) *Server {
+	repo := repository.NewMemoryRepository()
+	for _, svc := range []*domain.Service{
+		{ID: "a", Name: "transcoder", Host: "10.0.0.1", Port: 9001, Tags: []string{"gpu"}, Status: domain.StatusHealthy},
+		{ID: "b", Name: "transcoder", Host: "10.0.0.2", Port: 9002, Status: domain.StatusHealthy},
+		{ID: "c", Name: "transcoder", Host: "10.0.0.3", Port: 9003, Tags: []string{"gpu"}, Status: domain.StatusUnhealthy},
+		{ID: "d", Name: "storage", Host: "fd00::1", Port: 9000, Status: domain.StatusHealthy},
+		{ID: "e", Name: "postgres", Host: "db.internal", Port: 5432, Status: domain.StatusHealthy},
+		{ID: "a", Namespace: "dev", Name: "transcoder", Host: "10.1.0.1", Port: 9101, Tags: []string{"gpu"}, Status: domain.StatusHealthy},
+	} {
+		require.NoError(t, repo.Create(svc))
+	}
+
+	return NewServer(repo, zap.NewNop(), config.DNSConfig{Domain: "video-ia.", TTL: 15 * time.Second})
+}
+
+func query(s *Server, name string, qtype uint16) *dns.Msg {
+	req := new(dns.Msg)
+	req.SetQuestion(name, qtype)
+	w := &recorder{}
+	s.ServeDNS(w, req)
+	return w.msg
+}
+
+func addresses(m *dns.Msg) []string {
+	var out []string
+	for _, rr := range m.Answer {
+		switch rr := rr.(type) {
+		case *dns.A:
+			out = append(out, rr.A.String())
+		case *dns.AAAA:
+			out = append(out, rr.AAAA.String())
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+func TestServeARecordsSkipUnhealthy(t *testing.T) {
+	s := newTestServer(t)
+
+	m := query(s, "Transcoder.service.video-ia.", dns.TypeA)
+
+	assert.Equal(t, dns.RcodeSuccess, m.Rcode)
+	assert.True(t, m.Authoritative)
+	assert.Equal(t, []string{"10.0.0.1", "10.0.0.2"}, addresses(m))
+	assert.Equal(t, uint32(15), m.Answer[0].Header().Ttl)
+}
+
+func TestServeTagFilter(t *testing.T) {
+	s := newTestServer(t)
+
+	m := query(s, "gpu.transcoder.service.video-ia.", dns.TypeA)
+
+	assert.Equal(t, []string{"10.0.0.1"}, addresses(m))
+}
+
+func TestServeAAAA(t *testing.T) {
+	s := newTestServer(t)
+
+	assert.Equal(t, []string{"fd00::1"}, addresses(query(s, "storage.service.video-ia.", dns.TypeAAAA)))
+	assert.Empty(t, query(s, "storage.service.video-ia.", dns.TypeA).Answer)
+}
+
+func TestServeSRV(t *testing.T) {
+	s := newTestServer(t)
+
+	m := query(s, "transcoder.service.video-ia.", dns.TypeSRV)
+
+	require.Len(t, m.Answer, 2)
+	ports := map[string]uint16{}
+	for _, rr := range m.Answer {
+		srv := rr.(*dns.SRV)
+		ports[srv.Target] = srv.Port
+	}
+	assert.Equal(t, map[string]uint16{"a.addr.video-ia.": 9001, "b.addr.video-ia.": 9002}, ports)
+	assert.Len(t, m.Extra, 2, "addresses of IP targets are included as glue")
+
+	assert.Equal(t, []string{"10.0.0.1"}, addresses(query(s, "a.addr.video-ia.", dns.TypeA)))
+	assert.Equal(t, dns.RcodeNameError, query(s, "c.addr.video-ia.", dns.TypeA).Rcode)
+}
+
+func TestServeSRVHostname(t *testing.T) {
+	s := newTestServer(t)
+
+	m := query(s, "postgres.service.video-ia.", dns.TypeSRV)
+
+	require.Len(t, m.Answer, 1)
+	assert.Equal(t, "db.internal.", m.Answer[0].(*dns.SRV).Target)
+	assert.Equal(t, uint16(5432), m.Answer[0].(*dns.SRV).Port)
+	assert.Empty(t, m.Extra)
+}
+
+func TestServeNamespace(t *testing.T) {
+	s := newTestServer(t)
+
+	assert.Equal(t, []string{"10.1.0.1"}, addresses(query(s, "transcoder.service.dev.video-ia.", dns.TypeA)))
+	assert.Equal(t, []string{"10.1.0.1"}, addresses(query(s, "gpu.transcoder.service.dev.video-ia.", dns.TypeA)))
+
+	m := query(s, "transcoder.service.dev.video-ia.", dns.TypeSRV)
+	require.Len(t, m.Answer, 1)
+	assert.Equal(t, "a.addr.dev.video-ia.", m.Answer[0].(*dns.SRV).Target)
+	assert.Equal(t, []string{"10.1.0.1"}, addresses(query(s, "a.addr.dev.video-ia.", dns.TypeA)))
+
+	assert.Equal(t, dns.RcodeNameError, query(s, "postgres.service.dev.video-ia.", dns.TypeA).Rcode)
+}
+
+func TestServeUnknownNames(t *testing.T) {
+	s := newTestServer(t)
+
+	assert.Equal(t, dns.RcodeNameError, query(s, "missing.service.video-ia.", dns.TypeA).Rcode)
+	assert.Equal(t, dns.RcodeNameError, query(s, "cpu.transcoder.service.video-ia.", dns.TypeA).Rcode)
+	assert.Equal(t, dns.RcodeNameError, query(s, "transcoder.video-ia.", dns.TypeA).Rcode)
+	assert.Equal(t, dns.RcodeRefused, query(s, "example.com.", dns.TypeA).Rcode)
+}
+
+func TestServeTruncatesLargeUDPReplies(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	for i := 0; i < 40; i++ {
+		require.NoError(t, repo.Create(&domain.Service{
+			ID:     fmt.Sprintf("transcoder-%02d", i),
+			Name:   "transcoder",
+			Host:   fmt.Sprintf("10.0.0.%d", i+1),
+			Port:   9000 + i,
+			Status: domain.StatusHealthy,
+		}))
+	}
+	s := NewServer(repo, zap.NewNop(), config.DNSConfig{Domain: "video-ia", TTL: 15 * time.Second})
+
+	serve := func(w *recorder, edns0 uint16) *dns.Msg {
+		req := new(dns.Msg)
+		req.SetQuestion("transcoder.service.video-ia.", dns.TypeSRV)
+		if edns0 > 0 {
+			req.SetEdns0(edns0, false)
+		}
+		s.ServeDNS(w, req)
+		return w.msg
+	}
+
+	m := serve(&recorder{}, 0)
+	assert.True(t, m.Truncated)
+	assert.LessOrEqual(t, m.Len(), dns.MinMsgSize)
+	assert.Less(t, len(m.Answer), 40)
+
+	m = serve(&recorder{}, 4096)
+	assert.False(t, m.Truncated, "EDNS0 raises the limit")
+	assert.Len(t, m.Answer, 40)
+	assert.NotNil(t, m.IsEdns0(), "EDNS0 queries get an OPT record back")
+
+	m = serve(&recorder{tcp: true}, 0)
+	assert.False(t, m.Truncated, "TCP replies are not limited")
+	assert.Len(t, m.Answer, 40)
+	assert.Len(t, m.Extra, 40)
+}
+
+func TestStartServesUDPAndTCP(t *testing.T) {
+	l, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := l.LocalAddr().(*net.UDPAddr).Port
+	require.NoError(t, l.Close())
+
+	s := newTestServer(t)
+	s.config.Port = port
+	require.NoError(t, s.Start())
+	defer s.Stop()
+
+	addr := fmt.Sprintf("127.0.0.1:%d", port)
+	req := new(dns.Msg)
+	req.SetQuestion("transcoder.service.video-ia.", dns.TypeA)
+
+	for _, network := range []string{"udp", "tcp"} {
+		var m *dns.Msg
+		require.Eventually(t, func() bool {
+			m, _, err = (&dns.Client{Net: network}).Exchange(req, addr)
+			return err == nil
+		}, time.Second, 10*time.Millisecond, network)
+		assert.Len(t, m.Answer, 2, network)
+	}
+}
@@ -1,21 +1,60 @@
 package domain
 
-import "time"
+import (
+	"encoding/json"
+	"errors"
+	"time"
+)
 
 type ServiceStatus string
 
+// DefaultNamespace holds services registered without a namespace, including
+// those stored before namespaces existed.
+const DefaultNamespace = "default"
+
 const (
 	StatusHealthy   ServiceStatus = "healthy"
 	StatusUnhealthy ServiceStatus = "unhealthy"
 )
 
+// Duration is a time.Duration encoded in JSON as a Go duration string
+// ("30s"). Plain numbers are accepted on input and read as seconds.
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+
+	switch v := value.(type) {
+	case float64:
+		*d = Duration(v * float64(time.Second))
+	case string:
+		parsed, err := time.ParseDuration(v)
+		if err != nil {
+			return err
+		}
+		*d = Duration(parsed)
+	default:
+		return errors.New("invalid duration")
+	}
+	return nil
+}
+
 type Route struct {
 	Path    string   `json:"path"`
 	Methods []string `json:"methods"`
+	Timeout Duration `json:"timeout,omitempty"`
 }
 
 type Service struct {
 	ID            string            `json:"id"`
+	Namespace     string            `json:"namespace"`
 	Name          string            `json:"name"`
 	Host          string            `json:"host"`
 	Port          int               `json:"port"`
@@ -26,11 +65,18 @@ type Service struct {
 	Tags          []string          `json:"tags,omitempty"`
 	Metadata      map[string]string `json:"metadata,omitempty"`
 	Status        ServiceStatus     `json:"status"`
+	TTL           Duration          `json:"ttl,omitempty"`
 	LastHeartbeat time.Time         `json:"last_heartbeat"`
 	RegisteredAt  time.Time         `json:"registered_at"`
+
+	// OwnerTokenHash is the SHA-256 of the token handed to the registrant,
+	// which it must present to modify the instance. It is stored and
+	// replicated with the service but never returned by the API; see Public.
+	OwnerTokenHash string `json:"owner_token_hash,omitempty"`
 }
 
 type RegisterServiceRequest struct {
+	ID          string            `json:"id,omitempty"`
 	Name        string            `json:"name" binding:"required"`
 	Host        string            `json:"host" binding:"required"`
 	Port        int               `json:"port" binding:"required,min=1,max=65535"`
@@ -40,6 +86,7 @@ type RegisterServiceRequest struct {
 	HealthCheck string            `json:"health_check,omitempty"`
 	Tags        []string          `json:"tags,omitempty"`
 	Metadata    map[string]string `json:"metadata,omitempty"`
+	TTL         Duration          `json:"ttl,omitempty"`
 }
 
 type UpdateServiceRequest struct {
@@ -52,3 +99,68 @@ type UpdateServiceRequest struct {
 	Tags        []string          `json:"tags,omitempty"`
 	Metadata    map[string]string `json:"metadata,omitempty"`
 }
+
+// Key returns the key a service with id is stored under in namespace. IDs
+// are only unique within a namespace; services in the default namespace
+// keep their bare ID as key.
+func Key(namespace, id string) string {
+	if namespace == "" || namespace == DefaultNamespace {
+		return id
+	}
+	return namespace + "/" + id
+}
+
+// Key returns the key s is stored under.
+func (s *Service) Key() string {
+	return Key(s.Namespace, s.ID)
+}
+
+// InNamespace reports whether s belongs to namespace.
+func (s *Service) InNamespace(namespace string) bool {
+	if s.Namespace == "" {
+		return namespace == DefaultNamespace
+	}
+	return s.Namespace == namespace
+}
+
+// Public returns s without the fields the API must not expose, and with
+// the namespace of services stored before namespaces existed filled in.
+func (s *Service) Public() *Service {
+	if s == nil || s.OwnerTokenHash == "" && s.Namespace != "" {
+		return s
+	}
+	public := *s
+	public.OwnerTokenHash = ""
+	if public.Namespace == "" {
+		public.Namespace = DefaultNamespace
+	}
+	return &public
+}
+
+func (s *Service) Clone() *Service {
+	clone := *s
+
+	if s.Routes != nil {
+		clone.Routes = make([]Route, len(s.Routes))
+		for i, r := range s.Routes {
+			clone.Routes[i] = Route{
+				Path:    r.Path,
+				Methods: append([]string(nil), r.Methods...),
+				Timeout: r.Timeout,
+			}
+		}
+	}
+
+	if s.Tags != nil {
+		clone.Tags = append([]string(nil), s.Tags...)
+	}
+
+	if s.Metadata != nil {
+		clone.Metadata = make(map[string]string, len(s.Metadata))
+		for k, v := range s.Metadata {
+			clone.Metadata[k] = v
+		}
+	}
+
+	return &clone
+}
@@ -0,0 +1,158 @@
+package events
+
+import (
+	"sync"
+	"time"
+
+	"github.com/carlosealves2/video-ia/service-discover/internal/domain"
+)
+
+type Type string
+
+const (
+	TypeRegistered    Type = "registered"
+	TypeUpdated       Type = "updated"
+	TypeStatusChanged Type = "status-changed"
+	TypeUnregistered  Type = "unregistered"
+	// TypeReset carries no service: the catalog was replaced as a whole,
+	// e.g. from a cluster snapshot, and subscribers must re-list it.
+	TypeReset Type = "reset"
+)
+
+const subscriberBuffer = 64
+
+type Event struct {
+	ID             uint64               `json:"id"`
+	Type           Type                 `json:"type"`
+	Service        *domain.Service      `json:"service"`
+	PreviousStatus domain.ServiceStatus `json:"previous_status,omitempty"`
+	Timestamp      time.Time            `json:"timestamp"`
+
+	// previous is the service before the change, so that subscribers whose
+	// filter it matched learn that it left their view.
+	previous *domain.Service
+}
+
+// matches reports whether filter selects the service before or after the
+// change carried by e. Reset events concern every subscriber.
+func (e Event) matches(filter Filter) bool {
+	if filter == nil || e.Type == TypeReset {
+		return true
+	}
+	return filter(e.Service) || (e.previous != nil && filter(e.previous))
+}
+
+type Filter func(*domain.Service) bool
+
+// Subscription delivers events published after it was created. C is closed
+// when the subscriber falls too far behind or the subscription is cancelled.
+type Subscription struct {
+	C <-chan Event
+
+	ch     chan Event
+	filter Filter
+	broker *Broker
+}
+
+func (s *Subscription) Cancel() {
+	s.broker.unsubscribe(s)
+}
+
+// Broker fans registry events out to subscribers and keeps the most recent
+// ones in a bounded buffer so reconnecting subscribers can resume.
+type Broker struct {
+	mu          sync.Mutex
+	lastID      uint64
+	buffer      []Event
+	size        int
+	subscribers map[*Subscription]struct{}
+}
+
+func NewBroker(bufferSize int) *Broker {
+	return &Broker{
+		size:        bufferSize,
+		buffer:      make([]Event, 0, bufferSize),
+		subscribers: make(map[*Subscription]struct{}),
+	}
+}
+
+// Publish sends an event about service to the subscribers whose filter
+// matches it or previous, its state before the change (nil for new
+// services).
+func (b *Broker) Publish(eventType Type, service, previous *domain.Service) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.lastID++
+	event := Event{
+		ID:        b.lastID,
+		Type:      eventType,
+		Service:   service,
+		Timestamp: time.Now(),
+		previous:  previous,
+	}
+	if eventType == TypeStatusChanged && previous != nil {
+		event.PreviousStatus = previous.Status
+	}
+
+	if b.size > 0 {
+		if len(b.buffer) == b.size {
+			copy(b.buffer, b.buffer[1:])
+			b.buffer = b.buffer[:b.size-1]
+		}
+		b.buffer = append(b.buffer, event)
+	}
+
+	for sub := range b.subscribers {
+		if !event.matches(sub.filter) {
+			continue
+		}
+		select {
+		case sub.ch <- event:
+		default:
+			b.drop(sub)
+		}
+	}
+}
+
+// Subscribe registers a subscriber. When lastID is non-zero, buffered events
+// after it are returned as backlog; complete is false if some of those
+// events have already been evicted from the buffer.
+func (b *Broker) Subscribe(lastID uint64, filter Filter) (sub *Subscription, backlog []Event, complete bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	complete = true
+	if lastID > 0 {
+		switch {
+		case lastID > b.lastID:
+			complete = false
+		case lastID < b.lastID && (len(b.buffer) == 0 || b.buffer[0].ID > lastID+1):
+			complete = false
+		}
+		for _, event := range b.buffer {
+			if event.ID > lastID && event.matches(filter) {
+				backlog = append(backlog, event)
+			}
+		}
+	}
+
+	ch := make(chan Event, subscriberBuffer)
+	sub = &Subscription{C: ch, ch: ch, filter: filter, broker: b}
+	b.subscribers[sub] = struct{}{}
+
+	return sub, backlog, complete
+}
+
+func (b *Broker) unsubscribe(sub *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.drop(sub)
+}
+
+func (b *Broker) drop(sub *Subscription) {
+	if _, ok := b.subscribers[sub]; ok {
+		delete(b.subscribers, sub)
+		close(sub.ch)
+	}
+}
@@ -0,0 +1,113 @@
+package events
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/carlosealves2/video-ia/service-discover/internal/domain"
+)
+
+func TestBrokerDeliversToSubscribers(t *testing.T) {
+	broker := NewBroker(10)
+	sub, backlog, complete := broker.Subscribe(0, nil)
+	defer sub.Cancel()
+
+	assert.Empty(t, backlog)
+	assert.True(t, complete)
+
+	broker.Publish(TypeRegistered, &domain.Service{ID: "1"}, nil)
+
+	event := <-sub.C
+	assert.Equal(t, uint64(1), event.ID)
+	assert.Equal(t, TypeRegistered, event.Type)
+	assert.Equal(t, "1", event.Service.ID)
+}
+
+func TestBrokerFiltersEvents(t *testing.T) {
+	broker := NewBroker(10)
+	sub, _, _ := broker.Subscribe(0, func(svc *domain.Service) bool { return svc.Name == "wanted" })
+	defer sub.Cancel()
+
+	broker.Publish(TypeRegistered, &domain.Service{ID: "1", Name: "other"}, nil)
+	broker.Publish(TypeRegistered, &domain.Service{ID: "2", Name: "wanted"}, nil)
+
+	event := <-sub.C
+	assert.Equal(t, "2", event.Service.ID)
+}
+
+func TestBrokerDeliversChangesLeavingFilter(t *testing.T) {
+	broker := NewBroker(10)
+	healthy := func(svc *domain.Service) bool { return svc.Status == domain.StatusHealthy }
+	sub, _, _ := broker.Subscribe(0, healthy)
+	defer sub.Cancel()
+
+	before := &domain.Service{ID: "1", Status: domain.StatusHealthy}
+	after := &domain.Service{ID: "1", Status: domain.StatusUnhealthy}
+	broker.Publish(TypeRegistered, before, nil)
+	broker.Publish(TypeStatusChanged, after, before)
+	broker.Publish(TypeUpdated, &domain.Service{ID: "1", Port: 9090, Status: domain.StatusUnhealthy}, after)
+
+	<-sub.C
+	event := <-sub.C
+	assert.Equal(t, TypeStatusChanged, event.Type)
+	assert.Equal(t, domain.StatusUnhealthy, event.Service.Status)
+	assert.Equal(t, domain.StatusHealthy, event.PreviousStatus)
+	assert.Empty(t, sub.C, "changes outside the filter before and after are not delivered")
+
+	resumed, backlog, _ := broker.Subscribe(1, healthy)
+	defer resumed.Cancel()
+	require.Len(t, backlog, 1)
+	assert.Equal(t, uint64(2), backlog[0].ID)
+}
+
+func TestBrokerResumesFromBuffer(t *testing.T) {
+	broker := NewBroker(10)
+	for i := 0; i < 5; i++ {
+		broker.Publish(TypeUpdated, &domain.Service{ID: "1"}, nil)
+	}
+
+	sub, backlog, complete := broker.Subscribe(3, nil)
+	defer sub.Cancel()
+
+	assert.True(t, complete)
+	require.Len(t, backlog, 2)
+	assert.Equal(t, uint64(4), backlog[0].ID)
+	assert.Equal(t, uint64(5), backlog[1].ID)
+}
+
+func TestBrokerReportsEvictedEvents(t *testing.T) {
+	broker := NewBroker(3)
+	for i := 0; i < 10; i++ {
+		broker.Publish(TypeUpdated, &domain.Service{ID: "1"}, nil)
+	}
+
+	sub, backlog, complete := broker.Subscribe(2, nil)
+	defer sub.Cancel()
+
+	assert.False(t, complete)
+	require.Len(t, backlog, 3)
+	assert.Equal(t, uint64(8), backlog[0].ID)
+
+	sub, _, complete = broker.Subscribe(42, nil)
+	defer sub.Cancel()
+	assert.False(t, complete, "an id from the future means the broker was restarted")
+}
+
+func TestBrokerDropsSlowSubscribers(t *testing.T) {
+	broker := NewBroker(0)
+	sub, _, _ := broker.Subscribe(0, nil)
+
+	for i := 0; i < subscriberBuffer+1; i++ {
+		broker.Publish(TypeUpdated, &domain.Service{ID: "1"}, nil)
+	}
+
+	received := 0
+	for range sub.C {
+		received++
+	}
+	assert.Equal(t, subscriberBuffer, received)
+
+	sub.Cancel()
+}
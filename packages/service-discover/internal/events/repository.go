@@ -0,0 +1,71 @@
+package events
+
+import (
+	"github.com/carlosealves2/video-ia/service-discover/internal/domain"
+	"github.com/carlosealves2/video-ia/service-discover/internal/repository"
+)
+
+// Repository wraps a ServiceRepository and publishes an event for every
+// successful mutation. Updates that only refresh LastHeartbeat are not
+// published, so heartbeats do not flood subscribers.
+type Repository struct {
+	repository.ServiceRepository
+	broker *Broker
+}
+
+func NewRepository(repo repository.ServiceRepository, broker *Broker) *Repository {
+	return &Repository{
+		ServiceRepository: repo,
+		broker:            broker,
+	}
+}
+
+func (r *Repository) Create(service *domain.Service) error {
+	if err := r.ServiceRepository.Create(service); err != nil {
+		return err
+	}
+	r.broker.Publish(TypeRegistered, service.Clone(), nil)
+	return nil
+}
+
+func (r *Repository) Update(service *domain.Service) error {
+	previous, err := r.ServiceRepository.GetByID(service.Key())
+	if err != nil {
+		return err
+	}
+
+	if err := r.ServiceRepository.Update(service); err != nil {
+		return err
+	}
+
+	switch {
+	case previous.Status != service.Status:
+		r.broker.Publish(TypeStatusChanged, service.Clone(), previous)
+	case !repository.OnlyHeartbeatChanged(previous, service):
+		r.broker.Publish(TypeUpdated, service.Clone(), previous)
+	}
+	return nil
+}
+
+func (r *Repository) Delete(id string) error {
+	previous, err := r.ServiceRepository.GetByID(id)
+	if err != nil {
+		return err
+	}
+
+	if err := r.ServiceRepository.Delete(id); err != nil {
+		return err
+	}
+	r.broker.Publish(TypeUnregistered, previous, nil)
+	return nil
+}
+
+// Restore replaces the catalog and publishes a single reset event instead
+// of one event per service, telling subscribers to re-list the catalog.
+func (r *Repository) Restore(services []*domain.Service) error {
+	if err := repository.Restore(r.ServiceRepository, services); err != nil {
+		return err
+	}
+	r.broker.Publish(TypeReset, nil, nil)
+	return nil
+}
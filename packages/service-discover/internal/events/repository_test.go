@@ -0,0 +1,88 @@
+package events
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/carlosealves2/video-ia/service-discover/internal/domain"
+	"github.com/carlosealves2/video-ia/service-discover/internal/repository"
+)
+
+func drain(sub *Subscription) []Event {
+	var received []Event
+	for {
+		select {
+		case event := <-sub.C:
+			received = append(received, event)
+		default:
+			return received
+		}
+	}
+}
+
+func TestRepositoryPublishesMutations(t *testing.T) {
+	broker := NewBroker(10)
+	repo := NewRepository(repository.NewMemoryRepository(), broker)
+	sub, _, _ := broker.Subscribe(0, nil)
+	defer sub.Cancel()
+
+	service := &domain.Service{ID: "1", Name: "video-worker", Port: 8080, Status: domain.StatusHealthy}
+	require.NoError(t, repo.Create(service))
+
+	service.LastHeartbeat = time.Now()
+	require.NoError(t, repo.Update(service))
+
+	service.Port = 9090
+	require.NoError(t, repo.Update(service))
+
+	service.Status = domain.StatusUnhealthy
+	require.NoError(t, repo.Update(service))
+
+	require.NoError(t, repo.Delete("1"))
+
+	received := drain(sub)
+	require.Len(t, received, 4, "a heartbeat-only update must not publish")
+
+	assert.Equal(t, TypeRegistered, received[0].Type)
+	assert.Equal(t, TypeUpdated, received[1].Type)
+	assert.Equal(t, 9090, received[1].Service.Port)
+	assert.Equal(t, TypeStatusChanged, received[2].Type)
+	assert.Equal(t, domain.StatusHealthy, received[2].PreviousStatus)
+	assert.Equal(t, TypeUnregistered, received[3].Type)
+	assert.Equal(t, "video-worker", received[3].Service.Name)
+}
+
+func TestRepositoryRestorePublishesOneReset(t *testing.T) {
+	broker := NewBroker(10)
+	repo := NewRepository(repository.NewMemoryRepository(), broker)
+	require.NoError(t, repo.Create(&domain.Service{ID: "1", Name: "video-worker"}))
+
+	sub, _, _ := broker.Subscribe(0, func(svc *domain.Service) bool { return svc.Name == "uploader" })
+	defer sub.Cancel()
+
+	require.NoError(t, repo.Restore([]*domain.Service{
+		{ID: "2", Name: "video-worker"},
+		{ID: "3", Name: "uploader"},
+	}))
+
+	received := drain(sub)
+	require.Len(t, received, 1, "a restore is not replayed service by service")
+	assert.Equal(t, TypeReset, received[0].Type)
+	assert.Nil(t, received[0].Service)
+	assert.Len(t, repo.GetAll(), 2)
+}
+
+func TestRepositoryFailedMutationsDoNotPublish(t *testing.T) {
+	broker := NewBroker(10)
+	repo := NewRepository(repository.NewMemoryRepository(), broker)
+	sub, _, _ := broker.Subscribe(0, nil)
+	defer sub.Cancel()
+
+	assert.ErrorIs(t, repo.Update(&domain.Service{ID: "missing"}), repository.ErrServiceNotFound)
+	assert.ErrorIs(t, repo.Delete("missing"), repository.ErrServiceNotFound)
+
+	assert.Empty(t, drain(sub))
+}
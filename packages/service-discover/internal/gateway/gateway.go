@@ -0,0 +1,280 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/carlosealves2/video-ia/service-discover/internal/domain"
+	"github.com/carlosealves2/video-ia/service-discover/internal/repository"
+)
+
+// NamespaceHeader is stripped from proxied requests: the gateway routes to
+// the namespace it was configured with, and upstreams must not take a
+// client-supplied value for it.
+const NamespaceHeader = "X-Namespace"
+
+type targetKey struct{}
+
+// entry is one public path prefix (BasePath + Route.Path) in a namespace and
+// the healthy instances serving it.
+type entry struct {
+	namespace string
+	prefix    string
+	methods   map[string]bool
+	timeout   time.Duration
+	instances []*domain.Service
+	next      *atomic.Uint64
+}
+
+func (e *entry) allows(method string) bool {
+	return len(e.methods) == 0 || e.methods[method]
+}
+
+// Gateway reverse-proxies requests to registered services, matching the
+// request path against each service's routes with longest-prefix precedence.
+type Gateway struct {
+	repo           repository.ServiceRepository
+	watcher        repository.Watcher
+	logger         *zap.Logger
+	namespace      string
+	defaultTimeout time.Duration
+	proxy          *httputil.ReverseProxy
+
+	mu      sync.Mutex
+	index   uint64
+	entries []*entry
+	cursors map[string]*atomic.Uint64
+}
+
+// New returns a gateway for the services registered in namespace, or in the
+// default namespace when it is empty.
+func New(repo repository.ServiceRepository, watcher repository.Watcher, logger *zap.Logger, namespace string, defaultTimeout time.Duration) *Gateway {
+	if namespace == "" {
+		namespace = domain.DefaultNamespace
+	}
+	g := &Gateway{
+		repo:           repo,
+		watcher:        watcher,
+		logger:         logger,
+		namespace:      namespace,
+		defaultTimeout: defaultTimeout,
+		cursors:        make(map[string]*atomic.Uint64),
+	}
+
+	g.proxy = &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			pr.SetURL(pr.In.Context().Value(targetKey{}).(*url.URL))
+			pr.SetXForwarded()
+		},
+		ErrorHandler: g.proxyError,
+	}
+
+	return g
+}
+
+func (g *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	r.Header.Del(NamespaceHeader)
+
+	match, pathMatched := g.match(g.namespace, r.Method, r.URL.Path)
+	if match == nil {
+		if pathMatched {
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		writeError(w, http.StatusNotFound, "no service route matches path")
+		return
+	}
+	if len(match.instances) == 0 {
+		writeError(w, http.StatusServiceUnavailable, "no healthy instance available")
+		return
+	}
+
+	instance := match.instances[match.next.Add(1)%uint64(len(match.instances))]
+	scheme, _ := proxyScheme(instance)
+	target := &url.URL{
+		Scheme: scheme,
+		Host:   net.JoinHostPort(instance.Host, strconv.Itoa(instance.Port)),
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), match.timeout)
+	defer cancel()
+	ctx = context.WithValue(ctx, targetKey{}, target)
+
+	g.proxy.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// match returns the longest matching prefix in namespace that accepts
+// method. When a prefix matches the path but not the method, pathMatched
+// reports it so the caller can answer 405 instead of 404.
+func (g *Gateway) match(namespace, method, path string) (match *entry, pathMatched bool) {
+	for _, e := range g.table() {
+		if e.namespace != namespace || !matchesPrefix(path, e.prefix) {
+			continue
+		}
+		if e.allows(method) {
+			return e, true
+		}
+		pathMatched = true
+	}
+	return nil, pathMatched
+}
+
+func matchesPrefix(path, prefix string) bool {
+	if prefix == "/" || path == prefix {
+		return true
+	}
+	if strings.HasSuffix(prefix, "/") {
+		return strings.HasPrefix(path, prefix)
+	}
+	return strings.HasPrefix(path, prefix+"/")
+}
+
+// table returns the route table sorted by descending prefix length,
+// rebuilding it only when the catalog's modify index has moved. When the
+// instances behind a route set different timeouts, the longest one applies,
+// so no instance has its calls cut shorter than it asked for.
+func (g *Gateway) table() []*entry {
+	index := g.watcher.Index()
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.entries != nil && g.index == index {
+		return g.entries
+	}
+
+	type key struct{ namespace, prefix, methods string }
+	grouped := make(map[key]*entry)
+	live := make(map[string]bool)
+
+	for _, svc := range g.repo.GetAll() {
+		if _, ok := proxyScheme(svc); !ok {
+			continue
+		}
+
+		namespace := svc.Namespace
+		if namespace == "" {
+			namespace = domain.DefaultNamespace
+		}
+
+		for _, route := range svc.Routes {
+			prefix := joinPath(svc.BasePath, route.Path)
+			methods := make([]string, len(route.Methods))
+			for i, m := range route.Methods {
+				methods[i] = strings.ToUpper(m)
+			}
+			sort.Strings(methods)
+
+			k := key{namespace, prefix, strings.Join(methods, ",")}
+			e, ok := grouped[k]
+			if !ok {
+				cursorKey := k.namespace + " " + k.prefix + " " + k.methods
+				live[cursorKey] = true
+				e = &entry{
+					namespace: namespace,
+					prefix:    prefix,
+					methods:   make(map[string]bool, len(methods)),
+					next:      g.cursor(cursorKey),
+				}
+				for _, m := range methods {
+					e.methods[m] = true
+				}
+				grouped[k] = e
+			}
+			if timeout := time.Duration(route.Timeout); timeout > e.timeout {
+				e.timeout = timeout
+			}
+			if svc.Status == domain.StatusHealthy {
+				e.instances = append(e.instances, svc)
+			}
+		}
+	}
+
+	entries := make([]*entry, 0, len(grouped))
+	for _, e := range grouped {
+		if e.timeout == 0 {
+			e.timeout = g.defaultTimeout
+		}
+		entries = append(entries, e)
+	}
+	for cursorKey := range g.cursors {
+		if !live[cursorKey] {
+			delete(g.cursors, cursorKey)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return len(entries[i].prefix) > len(entries[j].prefix)
+	})
+
+	g.entries = entries
+	g.index = index
+	return entries
+}
+
+// cursor keeps round-robin positions stable across table rebuilds.
+func (g *Gateway) cursor(key string) *atomic.Uint64 {
+	c, ok := g.cursors[key]
+	if !ok {
+		c = &atomic.Uint64{}
+		g.cursors[key] = c
+	}
+	return c
+}
+
+func (g *Gateway) proxyError(w http.ResponseWriter, r *http.Request, err error) {
+	status := http.StatusBadGateway
+	if errors.Is(err, context.DeadlineExceeded) {
+		status = http.StatusGatewayTimeout
+	}
+
+	g.logger.Warn("Gateway upstream request failed",
+		zap.String("method", r.Method),
+		zap.String("path", r.URL.Path),
+		zap.String("upstream", r.URL.Host),
+		zap.Int("status", status),
+		zap.Error(err),
+	)
+
+	writeError(w, status, http.StatusText(status))
+}
+
+// proxyScheme returns the scheme the gateway reaches svc with. Only http and
+// https services, or those registered without a protocol, can be proxied;
+// the routes of others, e.g. grpc, are left out of the table.
+func proxyScheme(svc *domain.Service) (string, bool) {
+	switch protocol := strings.ToLower(svc.Protocol); protocol {
+	case "":
+		return "http", true
+	case "http", "https":
+		return protocol, true
+	default:
+		return "", false
+	}
+}
+
+func joinPath(basePath, path string) string {
+	joined := "/" + strings.Trim(basePath, "/")
+	if p := strings.Trim(path, "/"); p != "" {
+		joined = strings.TrimSuffix(joined, "/") + "/" + p
+	}
+	return joined
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
@@ -0,0 +1,302 @@
+package gateway
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/carlosealves2/video-ia/service-discover/internal/domain"
+	"github.com/carlosealves2/video-ia/service-discover/internal/repository"
+)
+
+func newTestGateway() (*Gateway, *repository.IndexedRepository) {
+	repo := repository.NewIndexedRepository(repository.NewMemoryRepository())
+	return New(repo, repo, zap.NewNop(), "", time.Second), repo
+}
+
+func backend(t *testing.T, name string, handler http.HandlerFunc) (host string, port int) {
+	if handler == nil {
+		handler = func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Backend", name)
+			w.Header().Set("X-Seen-Path", r.URL.Path)
+			w.Header().Set("X-Seen-Forwarded-Host", r.Header.Get("X-Forwarded-Host"))
+			w.Header().Set("X-Seen-Forwarded-For", r.Header.Get("X-Forwarded-For"))
+			w.Header().Set("X-Seen-Namespace", r.Header.Get(NamespaceHeader))
+		}
+	}
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	h, p, err := net.SplitHostPort(server.Listener.Addr().String())
+	require.NoError(t, err)
+	port, err = strconv.Atoi(p)
+	require.NoError(t, err)
+	return h, port
+}
+
+func register(t *testing.T, repo repository.ServiceRepository, id, name, basePath string, status domain.ServiceStatus, host string, port int, routes ...domain.Route) {
+	require.NoError(t, repo.Create(&domain.Service{
+		ID:       id,
+		Name:     name,
+		Host:     host,
+		Port:     port,
+		Protocol: "http",
+		BasePath: basePath,
+		Routes:   routes,
+		Status:   status,
+	}))
+}
+
+func get(g *Gateway, method, path string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(method, path, nil)
+	req.Host = "edge.video-ia.local"
+	g.ServeHTTP(w, req)
+	return w
+}
+
+func TestGatewayLongestPrefixWins(t *testing.T) {
+	g, repo := newTestGateway()
+
+	host, port := backend(t, "videos", nil)
+	register(t, repo, "1", "videos", "/api", domain.StatusHealthy, host, port, domain.Route{Path: "/videos"})
+	host, port = backend(t, "transcoder", nil)
+	register(t, repo, "2", "transcoder", "/api", domain.StatusHealthy, host, port, domain.Route{Path: "/videos/transcode"})
+
+	w := get(g, http.MethodGet, "/api/videos/transcode/42")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "transcoder", w.Header().Get("X-Backend"))
+	assert.Equal(t, "/api/videos/transcode/42", w.Header().Get("X-Seen-Path"))
+	assert.Equal(t, "edge.video-ia.local", w.Header().Get("X-Seen-Forwarded-Host"))
+	assert.NotEmpty(t, w.Header().Get("X-Seen-Forwarded-For"))
+
+	w = get(g, http.MethodGet, "/api/videos/42")
+	assert.Equal(t, "videos", w.Header().Get("X-Backend"))
+
+	w = get(g, http.MethodGet, "/api/videosx")
+	assert.Equal(t, http.StatusNotFound, w.Code, "prefixes match on path segments")
+}
+
+func TestGatewayMethodMatching(t *testing.T) {
+	g, repo := newTestGateway()
+
+	host, port := backend(t, "readers", nil)
+	register(t, repo, "1", "readers", "", domain.StatusHealthy, host, port, domain.Route{Path: "/videos", Methods: []string{"get"}})
+	host, port = backend(t, "writers", nil)
+	register(t, repo, "2", "writers", "", domain.StatusHealthy, host, port, domain.Route{Path: "/videos", Methods: []string{"POST"}})
+
+	assert.Equal(t, "readers", get(g, http.MethodGet, "/videos").Header().Get("X-Backend"))
+	assert.Equal(t, "writers", get(g, http.MethodPost, "/videos").Header().Get("X-Backend"))
+	assert.Equal(t, http.StatusMethodNotAllowed, get(g, http.MethodDelete, "/videos").Code)
+}
+
+func TestGatewayRoundRobinHealthyInstances(t *testing.T) {
+	g, repo := newTestGateway()
+
+	for _, name := range []string{"a", "b"} {
+		host, port := backend(t, name, nil)
+		register(t, repo, name, "worker", "", domain.StatusHealthy, host, port, domain.Route{Path: "/jobs"})
+	}
+	host, port := backend(t, "sick", nil)
+	register(t, repo, "sick", "worker", "", domain.StatusUnhealthy, host, port, domain.Route{Path: "/jobs"})
+
+	seen := map[string]int{}
+	for i := 0; i < 10; i++ {
+		seen[get(g, http.MethodGet, "/jobs").Header().Get("X-Backend")]++
+	}
+
+	assert.Equal(t, 5, seen["a"])
+	assert.Equal(t, 5, seen["b"])
+	assert.Zero(t, seen["sick"])
+}
+
+func TestGatewayNoHealthyInstance(t *testing.T) {
+	g, repo := newTestGateway()
+
+	host, port := backend(t, "sick", nil)
+	register(t, repo, "1", "worker", "", domain.StatusUnhealthy, host, port, domain.Route{Path: "/jobs"})
+
+	assert.Equal(t, http.StatusServiceUnavailable, get(g, http.MethodGet, "/jobs").Code)
+}
+
+func TestGatewayRouteTimeout(t *testing.T) {
+	g, repo := newTestGateway()
+
+	host, port := backend(t, "slow", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(2 * time.Second):
+		case <-r.Context().Done():
+		}
+	})
+	register(t, repo, "1", "slow", "", domain.StatusHealthy, host, port,
+		domain.Route{Path: "/slow", Timeout: domain.Duration(50 * time.Millisecond)})
+
+	start := time.Now()
+	w := get(g, http.MethodGet, "/slow")
+
+	assert.Equal(t, http.StatusGatewayTimeout, w.Code)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestGatewayConflictingRouteTimeouts(t *testing.T) {
+	g, repo := newTestGateway()
+
+	for i, timeout := range []time.Duration{3 * time.Second, 5 * time.Second, 0, 4 * time.Second} {
+		register(t, repo, strconv.Itoa(i), "encoder", "", domain.StatusHealthy, "127.0.0.1", 9000+i,
+			domain.Route{Path: "/encode", Timeout: domain.Duration(timeout)})
+	}
+	register(t, repo, "default", "thumbs", "", domain.StatusHealthy, "127.0.0.1", 9100, domain.Route{Path: "/thumbs"})
+
+	for i := 0; i < 10; i++ {
+		g.entries = nil
+		e, _ := g.match(domain.DefaultNamespace, http.MethodGet, "/encode")
+		require.NotNil(t, e)
+		assert.Equal(t, 5*time.Second, e.timeout, "the longest timeout wins")
+	}
+
+	e, _ := g.match(domain.DefaultNamespace, http.MethodGet, "/thumbs")
+	require.NotNil(t, e)
+	assert.Equal(t, time.Second, e.timeout, "routes without a timeout use the default")
+}
+
+func TestGatewayForgetsRemovedRoutes(t *testing.T) {
+	g, repo := newTestGateway()
+
+	register(t, repo, "1", "encoder", "", domain.StatusHealthy, "127.0.0.1", 9000, domain.Route{Path: "/encode"})
+	register(t, repo, "2", "thumbs", "", domain.StatusHealthy, "127.0.0.1", 9001, domain.Route{Path: "/thumbs"})
+	g.table()
+	assert.Len(t, g.cursors, 2)
+
+	require.NoError(t, repo.Delete("1"))
+	g.table()
+	assert.Len(t, g.cursors, 1)
+}
+
+func TestGatewayIPv6Upstream(t *testing.T) {
+	l, err := net.Listen("tcp", "[::1]:0")
+	if err != nil {
+		t.Skip("IPv6 loopback unavailable")
+	}
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Backend", "ipv6")
+	}))
+	server.Listener = l
+	server.Start()
+	defer server.Close()
+
+	g, repo := newTestGateway()
+	register(t, repo, "1", "ipv6", "", domain.StatusHealthy, "::1", l.Addr().(*net.TCPAddr).Port, domain.Route{Path: "/v6"})
+
+	w := get(g, http.MethodGet, "/v6")
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "ipv6", w.Header().Get("X-Backend"))
+}
+
+func TestGatewaySkipsNonHTTPInstances(t *testing.T) {
+	g, repo := newTestGateway()
+
+	host, port := backend(t, "plain", nil)
+	for id, protocol := range map[string]string{"grpc": "grpc", "plain": ""} {
+		require.NoError(t, repo.Create(&domain.Service{
+			ID:       id,
+			Name:     "encoder",
+			Host:     host,
+			Port:     port,
+			Protocol: protocol,
+			Routes:   []domain.Route{{Path: "/encode"}},
+			Status:   domain.StatusHealthy,
+		}))
+	}
+	require.NoError(t, repo.Create(&domain.Service{
+		ID:       "grpc-only",
+		Name:     "thumbs",
+		Host:     host,
+		Port:     port,
+		Protocol: "grpc",
+		Routes:   []domain.Route{{Path: "/thumbs"}},
+		Status:   domain.StatusHealthy,
+	}))
+
+	for i := 0; i < 4; i++ {
+		w := get(g, http.MethodGet, "/encode")
+		assert.Equal(t, http.StatusOK, w.Code, "an empty protocol is proxied over http")
+		assert.Equal(t, "plain", w.Header().Get("X-Backend"))
+	}
+	assert.Equal(t, http.StatusNotFound, get(g, http.MethodGet, "/thumbs").Code)
+}
+
+func TestGatewayUnreachableUpstream(t *testing.T) {
+	g, repo := newTestGateway()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := l.Addr().(*net.TCPAddr).Port
+	require.NoError(t, l.Close())
+
+	register(t, repo, "1", "gone", "", domain.StatusHealthy, "127.0.0.1", port, domain.Route{Path: "/gone"})
+
+	w := get(g, http.MethodGet, "/gone")
+	body, _ := io.ReadAll(w.Body)
+
+	assert.Equal(t, http.StatusBadGateway, w.Code)
+	assert.Contains(t, string(body), "Bad Gateway")
+}
+
+func TestGatewayPicksUpCatalogChanges(t *testing.T) {
+	g, repo := newTestGateway()
+
+	assert.Equal(t, http.StatusNotFound, get(g, http.MethodGet, "/late").Code)
+
+	host, port := backend(t, "late", nil)
+	register(t, repo, "1", "late", "", domain.StatusHealthy, host, port, domain.Route{Path: "/late"})
+
+	assert.Equal(t, http.StatusOK, get(g, http.MethodGet, "/late").Code)
+}
+
+func TestGatewayNamespace(t *testing.T) {
+	repo := repository.NewIndexedRepository(repository.NewMemoryRepository())
+	dev := New(repo, repo, zap.NewNop(), "dev", time.Second)
+	g := New(repo, repo, zap.NewNop(), "", time.Second)
+
+	host, port := backend(t, "videos", nil)
+	register(t, repo, "1", "videos", "", domain.StatusHealthy, host, port, domain.Route{Path: "/videos"})
+	devHost, devPort := backend(t, "videos-dev", nil)
+	require.NoError(t, repo.Create(&domain.Service{
+		ID:        "1",
+		Namespace: "dev",
+		Name:      "videos",
+		Host:      devHost,
+		Port:      devPort,
+		Protocol:  "http",
+		Routes:    []domain.Route{{Path: "/videos"}},
+		Status:    domain.StatusHealthy,
+	}))
+
+	assert.Equal(t, "videos", get(g, http.MethodGet, "/videos").Header().Get("X-Backend"))
+	assert.Equal(t, "videos-dev", get(dev, http.MethodGet, "/videos").Header().Get("X-Backend"))
+
+	// The client cannot pick another namespace, nor make upstreams believe
+	// it did.
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/videos", nil)
+	req.Header.Set(NamespaceHeader, "dev")
+	g.ServeHTTP(w, req)
+	assert.Equal(t, "videos", w.Header().Get("X-Backend"))
+	assert.Empty(t, w.Header().Get("X-Seen-Namespace"))
+}
+
+func TestJoinPath(t *testing.T) {
+	assert.Equal(t, "/api/v1/users", joinPath("/api/v1", "/users"))
+	assert.Equal(t, "/api/v1/users", joinPath("/api/v1/", "users/"))
+	assert.Equal(t, "/users", joinPath("", "/users"))
+	assert.Equal(t, "/", joinPath("", "/"))
+}
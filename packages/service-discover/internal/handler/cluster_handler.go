@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/carlosealves2/video-ia/service-discover/internal/cluster"
+	"github.com/carlosealves2/video-ia/service-discover/internal/repository"
+)
+
+type ClusterHandler struct {
+	node   *cluster.Node
+	logger *zap.Logger
+}
+
+func NewClusterHandler(node *cluster.Node, logger *zap.Logger) *ClusterHandler {
+	return &ClusterHandler{
+		node:   node,
+		logger: logger,
+	}
+}
+
+func (h *ClusterHandler) Apply(c *gin.Context) {
+	var cmd cluster.Command
+	if err := c.ShouldBindJSON(&cmd); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := h.node.ApplyLocal(cmd)
+	switch {
+	case err == nil:
+		c.JSON(http.StatusOK, gin.H{"message": "applied"})
+	case errors.Is(err, repository.ErrServiceNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, repository.ErrServiceAlreadyExists):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, cluster.ErrNotLeader):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+	default:
+		h.logger.Error("Failed to apply forwarded command",
+			zap.String("type", string(cmd.Type)),
+			zap.Error(err),
+		)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+func (h *ClusterHandler) Status(c *gin.Context) {
+	response := gin.H{
+		"node_id":       h.node.ID(),
+		"state":         h.node.State(),
+		"applied_index": h.node.AppliedIndex(),
+	}
+	if leader, ok := h.node.Leader(); ok {
+		response["leader"] = leader.ID
+	}
+
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,134 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/carlosealves2/video-ia/service-discover/internal/events"
+)
+
+const keepAliveInterval = 15 * time.Second
+
+type EventHandler struct {
+	broker *events.Broker
+	logger *zap.Logger
+
+	closing context.Context
+	close   context.CancelFunc
+}
+
+func NewEventHandler(broker *events.Broker, logger *zap.Logger) *EventHandler {
+	closing, cancel := context.WithCancel(context.Background())
+	return &EventHandler{
+		broker:  broker,
+		logger:  logger,
+		closing: closing,
+		close:   cancel,
+	}
+}
+
+// Close ends the open streams so that they do not hold up a graceful
+// shutdown. Subscribers are expected to reconnect with Last-Event-ID.
+func (h *EventHandler) Close() {
+	h.close()
+}
+
+// Stream serves registry events of the request's namespace as Server-Sent
+// Events. It accepts the same filters as Search and resumes
+// after the Last-Event-ID header (or ?last_event_id) from the broker's
+// buffer. A "reset" event is sent first when the requested events are no
+// longer buffered, telling the subscriber to re-list the catalog.
+func (h *EventHandler) Stream(c *gin.Context) {
+	filter, err := parseServiceFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	lastEventID := c.GetHeader("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = c.Query("last_event_id")
+	}
+
+	var lastID uint64
+	if lastEventID != "" {
+		parsed, err := strconv.ParseUint(lastEventID, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "last event id must be a non-negative integer"})
+			return
+		}
+		lastID = parsed
+	}
+
+	sub, backlog, complete := h.broker.Subscribe(lastID, filter.Match)
+	defer sub.Cancel()
+
+	h.logger.Info("Event subscriber connected",
+		append(filter.fields(), zap.Uint64("last_event_id", lastID))...,
+	)
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	if !complete {
+		c.Render(-1, sse.Event{
+			Event: "reset",
+			Data:  gin.H{"message": "events since last event id are no longer available"},
+		})
+	}
+	for _, event := range backlog {
+		h.render(c, event)
+	}
+	c.Writer.Flush()
+
+	ticker := time.NewTicker(keepAliveInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			h.logger.Info("Event subscriber disconnected")
+			return
+		case <-h.closing.Done():
+			h.logger.Info("Event stream closed for shutdown")
+			return
+		case event, ok := <-sub.C:
+			if !ok {
+				h.logger.Warn("Event subscriber dropped for falling behind")
+				return
+			}
+			h.render(c, event)
+			c.Writer.Flush()
+		case <-ticker.C:
+			_, _ = c.Writer.WriteString(": keepalive\n\n")
+			c.Writer.Flush()
+		}
+	}
+}
+
+func (h *EventHandler) render(c *gin.Context, event events.Event) {
+	if event.Type == events.TypeReset {
+		c.Render(-1, sse.Event{
+			Id:    strconv.FormatUint(event.ID, 10),
+			Event: string(event.Type),
+			Data:  gin.H{"message": "the catalog was replaced"},
+		})
+		return
+	}
+
+	event.Service = event.Service.Public()
+	c.Render(-1, sse.Event{
+		Id:    strconv.FormatUint(event.ID, 10),
+		Event: string(event.Type),
+		Data:  event,
+	})
+}
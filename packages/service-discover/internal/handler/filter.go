@@ -0,0 +1,133 @@
+package handler
+
+import (
+	"errors"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/carlosealves2/video-ia/service-discover/internal/domain"
+	"github.com/carlosealves2/video-ia/service-discover/internal/selector"
+)
+
+type serviceFilter struct {
+	namespace string
+	route     string
+	name      string
+	protocol  string
+	status    domain.ServiceStatus
+	selector  selector.Selector
+
+	// tags must all be present, or one of them with matchAnyTag.
+	tags        []string
+	matchAnyTag bool
+}
+
+// parseServiceFilter reads the search query: route (prefix), name
+// (case-insensitive substring), protocol, status, tags with tag_mode all
+// (default) or any, and a label selector over tags and metadata. The
+// single-valued tag is still accepted alongside tags.
+func parseServiceFilter(c *gin.Context) (serviceFilter, error) {
+	f := serviceFilter{
+		namespace: namespaceOf(c),
+		route:     c.Query("route"),
+		name:      c.Query("name"),
+		protocol:  c.Query("protocol"),
+	}
+
+	for _, tag := range strings.Split(c.Query("tags"), ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			f.tags = append(f.tags, tag)
+		}
+	}
+	if tag := c.Query("tag"); tag != "" {
+		f.tags = append(f.tags, tag)
+	}
+
+	switch c.Query("tag_mode") {
+	case "", "all":
+	case "any":
+		f.matchAnyTag = true
+	default:
+		return f, errors.New("tag_mode must be all or any")
+	}
+
+	switch status := domain.ServiceStatus(c.Query("status")); status {
+	case "", domain.StatusHealthy, domain.StatusUnhealthy:
+		f.status = status
+	default:
+		return f, errors.New("status must be healthy or unhealthy")
+	}
+
+	sel, err := selector.Parse(c.Query("selector"))
+	if err != nil {
+		return f, err
+	}
+	f.selector = sel
+
+	return f, nil
+}
+
+func (f serviceFilter) Match(svc *domain.Service) bool {
+	if !svc.InNamespace(f.namespace) {
+		return false
+	}
+
+	if f.route != "" {
+		found := false
+		for _, r := range svc.Routes {
+			if strings.HasPrefix(r.Path, f.route) || r.Path == f.route {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if f.name != "" && !strings.Contains(strings.ToLower(svc.Name), strings.ToLower(f.name)) {
+		return false
+	}
+
+	if f.protocol != "" && !strings.EqualFold(svc.Protocol, f.protocol) {
+		return false
+	}
+
+	if f.status != "" && svc.Status != f.status {
+		return false
+	}
+
+	if len(f.tags) > 0 {
+		matched := 0
+		for _, tag := range f.tags {
+			if slices.Contains(svc.Tags, tag) {
+				matched++
+			}
+		}
+		if matched == 0 || (!f.matchAnyTag && matched < len(f.tags)) {
+			return false
+		}
+	}
+
+	return f.selector.Matches(svc.Tags, svc.Metadata)
+}
+
+func (f serviceFilter) fields() []zap.Field {
+	tagMode := "all"
+	if f.matchAnyTag {
+		tagMode = "any"
+	}
+	return []zap.Field{
+		zap.String("namespace", f.namespace),
+		zap.String("route", f.route),
+		zap.String("name", f.name),
+		zap.String("protocol", f.protocol),
+		zap.String("status", string(f.status)),
+		zap.Strings("tags", f.tags),
+		zap.String("tag_mode", tagMode),
+		zap.Stringer("selector", f.selector),
+	}
+}
@@ -0,0 +1,46 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/carlosealves2/video-ia/service-discover/internal/domain"
+)
+
+// NamespaceHeader selects the namespace of requests whose path does not
+// carry one.
+const NamespaceHeader = "X-Namespace"
+
+const namespaceKey = "namespace"
+
+// Namespace scopes the request to the namespace in its :namespace path
+// segment, its X-Namespace header or, without either, the default
+// namespace. Names follow the same rules as instance IDs.
+func Namespace() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		namespace := c.Param("namespace")
+		if namespace == "" {
+			namespace = c.GetHeader(NamespaceHeader)
+		}
+		if namespace == "" {
+			namespace = domain.DefaultNamespace
+		}
+
+		if !instanceIDPattern.MatchString(namespace) {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "namespace must be lowercase letters, digits and hyphens (at most 63 characters)"})
+			return
+		}
+
+		c.Set(namespaceKey, namespace)
+		c.Next()
+	}
+}
+
+// namespaceOf returns the namespace the request was scoped to by Namespace.
+func namespaceOf(c *gin.Context) string {
+	if namespace := c.GetString(namespaceKey); namespace != "" {
+		return namespace
+	}
+	return domain.DefaultNamespace
+}
@@ -0,0 +1,105 @@
+package handler
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/carlosealves2/video-ia/service-discover/internal/auth"
+	"github.com/carlosealves2/video-ia/service-discover/internal/certs"
+	"github.com/carlosealves2/video-ia/service-discover/internal/domain"
+)
+
+// OwnerTokenHeader carries the instance's owner token: returned by Register
+// and expected on Register refreshes, Update, Heartbeat and Unregister.
+const OwnerTokenHeader = "X-Owner-Token"
+
+var errNotOwner = errors.New("instance is owned by another registrant")
+
+func newOwnerToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashOwnerToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// holdsToken reports whether the request carries svc's owner token.
+// Instances registered before ownership existed have no token to match.
+func holdsToken(c *gin.Context, svc *domain.Service) bool {
+	if svc.OwnerTokenHash == "" {
+		return true
+	}
+	token := c.GetHeader(OwnerTokenHeader)
+	return token != "" && subtle.ConstantTimeCompare([]byte(hashOwnerToken(token)), []byte(svc.OwnerTokenHash)) == 1
+}
+
+// mayModify reports whether the caller may change svc: always when
+// ownership is not enforced, otherwise for the holder of its owner token and
+// for admins.
+func (h *ServiceHandler) mayModify(c *gin.Context, svc *domain.Service) bool {
+	if !h.enforceOwnership || holdsToken(c, svc) {
+		return true
+	}
+	principal, ok := auth.FromContext(c.Request.Context())
+	return ok && principal.Has(auth.ScopeAdmin)
+}
+
+// authorizeOwner aborts with 403 unless the caller may modify svc and its
+// client certificate, if any, covers svc's name.
+func (h *ServiceHandler) authorizeOwner(c *gin.Context, svc *domain.Service) bool {
+	if !certificateAllows(c, svc.Name) {
+		h.logger.Warn("Client certificate does not cover service name",
+			zap.String("service_id", svc.ID),
+			zap.String("service_name", svc.Name),
+			zap.String("method", c.Request.Method),
+		)
+		c.JSON(http.StatusForbidden, gin.H{"error": "client certificate does not allow modifying " + svc.Name})
+		return false
+	}
+	if h.mayModify(c, svc) {
+		return true
+	}
+
+	h.logger.Warn("Rejected change to instance owned by another registrant",
+		zap.String("service_id", svc.ID),
+		zap.String("service_name", svc.Name),
+		zap.String("method", c.Request.Method),
+	)
+	c.JSON(http.StatusForbidden, gin.H{"error": errNotOwner.Error()})
+	return false
+}
+
+// certificateAllows reports whether the caller may register or modify a
+// service called name. Callers that presented a verified client certificate
+// may only touch the names it maps to, unless they are admins; other callers
+// are not restricted here.
+func certificateAllows(c *gin.Context, name string) bool {
+	cert, ok := certs.VerifiedClient(c.Request.TLS)
+	if !ok || certs.Allows(cert, name) {
+		return true
+	}
+	principal, ok := auth.FromContext(c.Request.Context())
+	return ok && principal.Has(auth.ScopeAdmin)
+}
+
+// public strips secrets from services before they are returned.
+func public(services []*domain.Service) []*domain.Service {
+	out := make([]*domain.Service, len(services))
+	for i, svc := range services {
+		out[i] = svc.Public()
+	}
+	return out
+}
@@ -0,0 +1,83 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/carlosealves2/video-ia/service-discover/internal/balancer"
+	"github.com/carlosealves2/video-ia/service-discover/internal/domain"
+	"github.com/carlosealves2/video-ia/service-discover/internal/repository"
+)
+
+type ResolveHandler struct {
+	repo     repository.ServiceRepository
+	balancer *balancer.Balancer
+	logger   *zap.Logger
+}
+
+func NewResolveHandler(repo repository.ServiceRepository, logger *zap.Logger) *ResolveHandler {
+	return &ResolveHandler{
+		repo:     repo,
+		balancer: balancer.New(),
+		logger:   logger,
+	}
+}
+
+// Resolve returns one healthy instance of the named service in the request's
+// namespace, chosen by ?strategy (round-robin by default). consistent-hash
+// requires ?key.
+func (h *ResolveHandler) Resolve(c *gin.Context) {
+	name := c.Param("name")
+
+	strategy, err := balancer.ParseStrategy(c.Query("strategy"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	namespace := namespaceOf(c)
+	var instances []*domain.Service
+	live := make(map[string]bool)
+	for _, svc := range h.repo.GetAll() {
+		live[domain.Key(svc.Namespace, svc.Name)] = true
+		if svc.InNamespace(namespace) && svc.Name == name {
+			instances = append(instances, svc)
+		}
+	}
+	h.balancer.Retain(live)
+
+	instance, err := h.balancer.Pick(domain.Key(namespace, name), strategy, c.Query("key"), instances)
+	switch {
+	case err == nil:
+	case errors.Is(err, balancer.ErrNoHealthyInstance):
+		h.logger.Warn("No healthy instance to resolve",
+			zap.String("service_name", name),
+			zap.String("namespace", namespace),
+			zap.String("strategy", string(strategy)),
+			zap.Int("instances", len(instances)),
+		)
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":     err.Error(),
+			"service":   name,
+			"strategy":  strategy,
+			"instances": len(instances),
+		})
+		return
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	annotate(c, instance)
+
+	h.logger.Debug("Resolved service instance",
+		zap.String("service_name", name),
+		zap.String("strategy", string(strategy)),
+		zap.String("service_id", instance.ID),
+	)
+
+	c.JSON(http.StatusOK, instance.Public())
+}
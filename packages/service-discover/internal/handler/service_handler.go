@@ -1,8 +1,11 @@
 package handler
 
 import (
+	"context"
+	"errors"
 	"net/http"
-	"strings"
+	"regexp"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -13,18 +16,106 @@ import (
 	"github.com/carlosealves2/video-ia/service-discover/internal/repository"
 )
 
+const (
+	IndexHeader = "X-Registry-Index"
+
+	// RegistrationHeader tells a registrant whether its registration created
+	// a new record or refreshed an existing one.
+	RegistrationHeader    = "X-Registration"
+	RegistrationCreated   = "created"
+	RegistrationRefreshed = "refreshed"
+
+	defaultBlockingWait = 30 * time.Second
+	maxBlockingWait     = 5 * time.Minute
+)
+
+var (
+	instanceIDPattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
+	instanceNamespace = uuid.MustParse("5b1c6f0e-3c7a-4d8e-9a51-2f4b7d0c9e13")
+)
+
 type ServiceHandler struct {
-	repo   repository.ServiceRepository
-	logger *zap.Logger
+	repo    repository.ServiceRepository
+	watcher repository.Watcher
+	logger  *zap.Logger
+
+	enforceOwnership bool
+	healthChecked    bool
+
+	closing context.Context
+	close   context.CancelFunc
 }
 
-func NewServiceHandler(repo repository.ServiceRepository, logger *zap.Logger) *ServiceHandler {
+func NewServiceHandler(repo repository.ServiceRepository, watcher repository.Watcher, logger *zap.Logger) *ServiceHandler {
+	closing, cancel := context.WithCancel(context.Background())
 	return &ServiceHandler{
-		repo:   repo,
-		logger: logger,
+		repo:    repo,
+		watcher: watcher,
+		logger:  logger,
+		closing: closing,
+		close:   cancel,
 	}
 }
 
+// Close releases blocking queries still waiting for a change, which then
+// answer with the current state, so they do not hold up a graceful
+// shutdown. Blocking queries received afterwards do not wait.
+func (h *ServiceHandler) Close() {
+	h.close()
+}
+
+// RequireOwnership restricts changes to an instance to the holder of its
+// owner token and to admins. It is meant for authenticated deployments;
+// without authentication every caller is trusted.
+func (h *ServiceHandler) RequireOwnership() {
+	h.enforceOwnership = true
+}
+
+// DeferToHealthChecks leaves the status of instances to the health checker:
+// heartbeats then only refresh LastHeartbeat and cannot mark healthy an
+// instance whose probe fails.
+func (h *ServiceHandler) DeferToHealthChecks() {
+	h.healthChecked = true
+}
+
+// waitForIndex implements blocking queries: with ?index=N it holds the
+// request until the catalog's modify index passes N or ?wait elapses. The
+// index is read before the caller loads its data, so a client that repeats
+// the query with the returned index cannot miss a change.
+func (h *ServiceHandler) waitForIndex(c *gin.Context) bool {
+	var index uint64
+	if raw := c.Query("index"); raw != "" {
+		parsed, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "index must be a non-negative integer"})
+			return false
+		}
+		index = parsed
+	}
+
+	wait := defaultBlockingWait
+	if raw := c.Query("wait"); raw != "" {
+		parsed, err := time.ParseDuration(raw)
+		if err != nil || parsed <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "wait must be a positive duration (e.g. 30s)"})
+			return false
+		}
+		wait = min(parsed, maxBlockingWait)
+	}
+
+	current := h.watcher.Index()
+	if index > 0 && current <= index {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), wait)
+		defer cancel()
+		stop := context.AfterFunc(h.closing, cancel)
+		defer stop()
+		current = h.watcher.Wait(ctx, index)
+	}
+
+	c.Header(IndexHeader, strconv.FormatUint(current, 10))
+	return true
+}
+
 func (h *ServiceHandler) Register(c *gin.Context) {
 	var req domain.RegisterServiceRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -35,6 +126,32 @@ func (h *ServiceHandler) Register(c *gin.Context) {
 		return
 	}
 
+	if req.TTL < 0 {
+		h.logger.Warn("Invalid TTL in register request",
+			zap.String("service_name", req.Name),
+			zap.Duration("ttl", time.Duration(req.TTL)),
+		)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ttl must not be negative"})
+		return
+	}
+
+	if req.ID != "" && !instanceIDPattern.MatchString(req.ID) {
+		h.logger.Warn("Invalid instance ID in register request",
+			zap.String("service_name", req.Name),
+			zap.String("service_id", req.ID),
+		)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "id must be lowercase letters, digits and hyphens (at most 63 characters)"})
+		return
+	}
+
+	if !certificateAllows(c, req.Name) {
+		h.logger.Warn("Client certificate does not cover service name",
+			zap.String("service_name", req.Name),
+		)
+		c.JSON(http.StatusForbidden, gin.H{"error": "client certificate does not allow registering " + req.Name})
+		return
+	}
+
 	protocol := req.Protocol
 	if protocol == "" {
 		protocol = "http"
@@ -46,7 +163,8 @@ func (h *ServiceHandler) Register(c *gin.Context) {
 	}
 
 	service := &domain.Service{
-		ID:            uuid.New().String(),
+		ID:            req.ID,
+		Namespace:     namespaceOf(c),
 		Name:          req.Name,
 		Host:          req.Host,
 		Port:          req.Port,
@@ -57,11 +175,21 @@ func (h *ServiceHandler) Register(c *gin.Context) {
 		Tags:          req.Tags,
 		Metadata:      req.Metadata,
 		Status:        domain.StatusHealthy,
+		TTL:           req.TTL,
 		LastHeartbeat: time.Now(),
 		RegisteredAt:  time.Now(),
 	}
 
-	if err := h.repo.Create(service); err != nil {
+	created, token, err := h.upsert(c, service)
+	if errors.Is(err, errNotOwner) {
+		h.logger.Warn("Rejected registration of instance owned by another registrant",
+			zap.String("service_id", service.ID),
+			zap.String("service_name", service.Name),
+		)
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
 		h.logger.Error("Failed to register service",
 			zap.String("service_name", req.Name),
 			zap.Error(err),
@@ -70,36 +198,143 @@ func (h *ServiceHandler) Register(c *gin.Context) {
 		return
 	}
 
+	annotate(c, service)
+
+	result, status := RegistrationCreated, http.StatusCreated
+	if !created {
+		result, status = RegistrationRefreshed, http.StatusOK
+	}
+
 	h.logger.Info("Service registered successfully",
 		zap.String("service_id", service.ID),
 		zap.String("service_name", service.Name),
+		zap.String("namespace", service.Namespace),
+		zap.String("registration", result),
 		zap.String("host", service.Host),
 		zap.Int("port", service.Port),
 		zap.String("protocol", service.Protocol),
 		zap.String("base_path", service.BasePath),
 		zap.Int("routes_count", len(service.Routes)),
+		zap.Duration("ttl", time.Duration(service.TTL)),
 	)
 
-	c.JSON(http.StatusCreated, service)
+	c.Header(RegistrationHeader, result)
+	c.Header(OwnerTokenHeader, token)
+	c.JSON(status, service.Public())
+}
+
+// upsert stores service, refreshing the existing record of the same instance
+// when there is one. An instance is identified within its namespace by its
+// client-supplied ID or, without one, by name, host and port. New instances
+// without an ID get one derived from that key, so concurrent retries of the
+// same registration collide on Create instead of leaving duplicates.
+//
+// It returns the instance's owner token: the one presented by the caller
+// when it still matches, or a new one.
+func (h *ServiceHandler) upsert(c *gin.Context, service *domain.Service) (created bool, token string, err error) {
+	existing := h.findInstance(service)
+	if existing == nil {
+		if service.ID == "" {
+			service.ID = instanceID(service)
+		}
+		if token, err = issueOwnerToken(service); err != nil {
+			return false, "", err
+		}
+		err = h.repo.Create(service)
+		if !errors.Is(err, repository.ErrServiceAlreadyExists) {
+			return err == nil, token, err
+		}
+		if existing, err = h.repo.GetByID(service.Key()); err != nil {
+			return false, "", err
+		}
+	}
+
+	// Refreshing needs the owner token whatever the instance's status; a
+	// registrant that lost it can register again once the reaper has
+	// removed the instance.
+	if !h.mayModify(c, existing) || !certificateAllows(c, existing.Name) {
+		return false, "", errNotOwner
+	}
+
+	service.ID = existing.ID
+	service.RegisteredAt = existing.RegisteredAt
+	if held := c.GetHeader(OwnerTokenHeader); held != "" && existing.OwnerTokenHash != "" && holdsToken(c, existing) {
+		token = held
+		service.OwnerTokenHash = existing.OwnerTokenHash
+	} else if token, err = issueOwnerToken(service); err != nil {
+		return false, "", err
+	}
+	return false, token, h.repo.Update(service)
+}
+
+// issueOwnerToken gives service a new owner token and returns it.
+func issueOwnerToken(service *domain.Service) (string, error) {
+	token, err := newOwnerToken()
+	if err != nil {
+		return "", err
+	}
+	service.OwnerTokenHash = hashOwnerToken(token)
+	return token, nil
+}
+
+func (h *ServiceHandler) findInstance(service *domain.Service) *domain.Service {
+	if service.ID != "" {
+		existing, err := h.repo.GetByID(service.Key())
+		if err != nil {
+			return nil
+		}
+		return existing
+	}
+
+	for _, svc := range h.repo.GetAll() {
+		if svc.InNamespace(service.Namespace) && svc.Name == service.Name && svc.Host == service.Host && svc.Port == service.Port {
+			return svc
+		}
+	}
+	return nil
+}
+
+func instanceID(service *domain.Service) string {
+	key := service.Name + "\x00" + service.Host + "\x00" + strconv.Itoa(service.Port)
+	if service.Namespace != domain.DefaultNamespace {
+		key = service.Namespace + "\x00" + key
+	}
+	return uuid.NewSHA1(instanceNamespace, []byte(key)).String()
 }
 
 func (h *ServiceHandler) List(c *gin.Context) {
-	services := h.repo.GetAll()
+	if !h.waitForIndex(c) {
+		return
+	}
+
+	namespace := namespaceOf(c)
+	var services []*domain.Service
+	for _, svc := range h.repo.GetAll() {
+		if svc.InNamespace(namespace) {
+			services = append(services, svc)
+		}
+	}
 
 	h.logger.Info("Listed all services",
+		zap.String("namespace", namespace),
 		zap.Int("count", len(services)),
 	)
 
 	c.JSON(http.StatusOK, gin.H{
-		"services": services,
+		"services": public(services),
 		"count":    len(services),
 	})
 }
 
 func (h *ServiceHandler) Get(c *gin.Context) {
 	id := c.Param("id")
+	key := domain.Key(namespaceOf(c), id)
+
+	if !h.waitForIndex(c) {
+		return
+	}
 
-	service, err := h.repo.GetByID(id)
+	service, err := h.repo.GetByID(key)
 	if err != nil {
 		h.logger.Warn("Service not found",
 			zap.String("service_id", id),
@@ -108,18 +343,21 @@ func (h *ServiceHandler) Get(c *gin.Context) {
 		return
 	}
 
+	annotate(c, service)
+
 	h.logger.Info("Retrieved service",
 		zap.String("service_id", id),
 		zap.String("service_name", service.Name),
 	)
 
-	c.JSON(http.StatusOK, service)
+	c.JSON(http.StatusOK, service.Public())
 }
 
 func (h *ServiceHandler) Update(c *gin.Context) {
 	id := c.Param("id")
+	key := domain.Key(namespaceOf(c), id)
 
-	service, err := h.repo.GetByID(id)
+	service, err := h.repo.GetByID(key)
 	if err != nil {
 		h.logger.Warn("Service not found for update",
 			zap.String("service_id", id),
@@ -128,6 +366,12 @@ func (h *ServiceHandler) Update(c *gin.Context) {
 		return
 	}
 
+	annotate(c, service)
+
+	if !h.authorizeOwner(c, service) {
+		return
+	}
+
 	var req domain.UpdateServiceRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		h.logger.Warn("Failed to bind update request",
@@ -177,13 +421,14 @@ func (h *ServiceHandler) Update(c *gin.Context) {
 		zap.String("service_name", service.Name),
 	)
 
-	c.JSON(http.StatusOK, service)
+	c.JSON(http.StatusOK, service.Public())
 }
 
 func (h *ServiceHandler) Unregister(c *gin.Context) {
 	id := c.Param("id")
+	key := domain.Key(namespaceOf(c), id)
 
-	service, err := h.repo.GetByID(id)
+	service, err := h.repo.GetByID(key)
 	if err != nil {
 		h.logger.Warn("Service not found for unregister",
 			zap.String("service_id", id),
@@ -192,9 +437,15 @@ func (h *ServiceHandler) Unregister(c *gin.Context) {
 		return
 	}
 
+	annotate(c, service)
+
+	if !h.authorizeOwner(c, service) {
+		return
+	}
+
 	serviceName := service.Name
 
-	if err := h.repo.Delete(id); err != nil {
+	if err := h.repo.Delete(key); err != nil {
 		h.logger.Error("Failed to unregister service",
 			zap.String("service_id", id),
 			zap.Error(err),
@@ -213,8 +464,9 @@ func (h *ServiceHandler) Unregister(c *gin.Context) {
 
 func (h *ServiceHandler) Heartbeat(c *gin.Context) {
 	id := c.Param("id")
+	key := domain.Key(namespaceOf(c), id)
 
-	service, err := h.repo.GetByID(id)
+	service, err := h.repo.GetByID(key)
 	if err != nil {
 		h.logger.Warn("Service not found for heartbeat",
 			zap.String("service_id", id),
@@ -223,8 +475,16 @@ func (h *ServiceHandler) Heartbeat(c *gin.Context) {
 		return
 	}
 
+	annotate(c, service)
+
+	if !h.authorizeOwner(c, service) {
+		return
+	}
+
 	service.LastHeartbeat = time.Now()
-	service.Status = domain.StatusHealthy
+	if !h.healthChecked {
+		service.Status = domain.StatusHealthy
+	}
 
 	if err := h.repo.Update(service); err != nil {
 		h.logger.Error("Failed to update heartbeat",
@@ -248,56 +508,31 @@ func (h *ServiceHandler) Heartbeat(c *gin.Context) {
 }
 
 func (h *ServiceHandler) Search(c *gin.Context) {
-	route := c.Query("route")
-	name := c.Query("name")
-	tag := c.Query("tag")
+	filter, err := parseServiceFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !h.waitForIndex(c) {
+		return
+	}
 
 	services := h.repo.GetAll()
 	var results []*domain.Service
 
 	for _, svc := range services {
-		if route != "" {
-			found := false
-			for _, r := range svc.Routes {
-				if strings.HasPrefix(r.Path, route) || r.Path == route {
-					found = true
-					break
-				}
-			}
-			if !found {
-				continue
-			}
-		}
-
-		if name != "" && !strings.Contains(strings.ToLower(svc.Name), strings.ToLower(name)) {
-			continue
+		if filter.Match(svc) {
+			results = append(results, svc)
 		}
-
-		if tag != "" {
-			found := false
-			for _, t := range svc.Tags {
-				if t == tag {
-					found = true
-					break
-				}
-			}
-			if !found {
-				continue
-			}
-		}
-
-		results = append(results, svc)
 	}
 
 	h.logger.Info("Search completed",
-		zap.String("route", route),
-		zap.String("name", name),
-		zap.String("tag", tag),
-		zap.Int("results", len(results)),
+		append(filter.fields(), zap.Int("results", len(results)))...,
 	)
 
 	c.JSON(http.StatusOK, gin.H{
-		"services": results,
+		"services": public(results),
 		"count":    len(results),
 	})
 }
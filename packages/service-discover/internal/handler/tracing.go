@@ -0,0 +1,18 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/carlosealves2/video-ia/service-discover/internal/domain"
+)
+
+// annotate records the instance a request acted on in its trace span.
+func annotate(c *gin.Context, svc *domain.Service) {
+	trace.SpanFromContext(c.Request.Context()).SetAttributes(
+		attribute.String("service_id", svc.ID),
+		attribute.String("service_name", svc.Name),
+		attribute.String("namespace", namespaceOf(c)),
+	)
+}
@@ -0,0 +1,223 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+
+	"github.com/carlosealves2/video-ia/service-discover/internal/config"
+	"github.com/carlosealves2/video-ia/service-discover/internal/domain"
+	"github.com/carlosealves2/video-ia/service-discover/internal/repository"
+)
+
+type probeState struct {
+	successes int
+	failures  int
+}
+
+type Checker struct {
+	repo   repository.ServiceRepository
+	logger *zap.Logger
+	config config.HealthCheckConfig
+	client *http.Client
+
+	states map[string]*probeState
+	mu     sync.Mutex
+
+	expired func(*domain.Service) bool
+
+	worker
+}
+
+func NewChecker(repo repository.ServiceRepository, logger *zap.Logger, cfg config.HealthCheckConfig) *Checker {
+	return &Checker{
+		repo:   repo,
+		logger: logger,
+		config: cfg,
+		client: &http.Client{
+			Timeout: cfg.Timeout,
+		},
+		states: make(map[string]*probeState),
+	}
+}
+
+// KeepExpired stops the checker from marking healthy the instances for
+// which expired reports true, e.g. those the reaper expired for missing
+// heartbeats, so the two do not flip them back and forth.
+func (c *Checker) KeepExpired(expired func(*domain.Service) bool) {
+	c.expired = expired
+}
+
+func (c *Checker) Start(ctx context.Context) {
+	c.run(ctx, c.config.Interval, c.checkAll)
+}
+
+func (c *Checker) checkAll(ctx context.Context) {
+	services := c.repo.GetAll()
+
+	var wg sync.WaitGroup
+	for _, svc := range services {
+		wg.Add(1)
+		go func(svc *domain.Service) {
+			defer wg.Done()
+			c.record(svc, c.probe(ctx, svc))
+		}(svc)
+	}
+	wg.Wait()
+
+	c.prune(services)
+}
+
+// probe sends an HTTP GET to the health check of http and https services.
+// Services speaking any other protocol, e.g. grpc, only have their port
+// dialed, since their health endpoint cannot be reached over HTTP.
+func (c *Checker) probe(ctx context.Context, svc *domain.Service) error {
+	if !probesHTTP(svc) {
+		return c.dial(ctx, svc)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ProbeURL(svc), nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (c *Checker) dial(ctx context.Context, svc *domain.Service) error {
+	dialer := net.Dialer{Timeout: c.config.Timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", probeAddr(svc))
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// record updates the consecutive success/failure counters for svc and, once
+// a streak has reached its threshold, asserts the matching status on every
+// round, so a status set elsewhere (e.g. by a re-registration) cannot hide
+// a failing probe.
+func (c *Checker) record(svc *domain.Service, probeErr error) {
+	c.mu.Lock()
+	state, ok := c.states[svc.Key()]
+	if !ok {
+		state = &probeState{}
+		c.states[svc.Key()] = state
+	}
+
+	var target domain.ServiceStatus
+	if probeErr == nil {
+		state.failures = 0
+		state.successes++
+		if state.successes >= c.config.SuccessThreshold {
+			target = domain.StatusHealthy
+		}
+	} else {
+		state.successes = 0
+		state.failures++
+		if state.failures >= c.config.FailureThreshold {
+			target = domain.StatusUnhealthy
+		}
+	}
+	c.mu.Unlock()
+
+	if probeErr != nil {
+		c.logger.Debug("Health check failed",
+			zap.String("service_id", svc.ID),
+			zap.String("service_name", svc.Name),
+			zap.String("target", probeTarget(svc)),
+			zap.Error(probeErr),
+		)
+	}
+
+	if target != "" {
+		c.setStatus(svc.Key(), target, probeErr)
+	}
+}
+
+func (c *Checker) setStatus(key string, status domain.ServiceStatus, probeErr error) {
+	service, err := c.repo.GetByID(key)
+	if err != nil || service.Status == status {
+		return
+	}
+	if status == domain.StatusHealthy && c.expired != nil && c.expired(service) {
+		return
+	}
+
+	service.Status = status
+	if err := c.repo.Update(service); err != nil {
+		c.logger.Error("Failed to update service status",
+			zap.String("service_id", service.ID),
+			zap.Error(err),
+		)
+		return
+	}
+
+	fields := []zap.Field{
+		zap.String("service_id", service.ID),
+		zap.String("service_name", service.Name),
+		zap.String("status", string(status)),
+	}
+	if probeErr != nil {
+		fields = append(fields, zap.Error(probeErr))
+	}
+	c.logger.Info("Service health status changed", fields...)
+}
+
+func (c *Checker) prune(services []*domain.Service) {
+	alive := make(map[string]struct{}, len(services))
+	for _, svc := range services {
+		alive[svc.Key()] = struct{}{}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for id := range c.states {
+		if _, ok := alive[id]; !ok {
+			delete(c.states, id)
+		}
+	}
+}
+
+func probesHTTP(svc *domain.Service) bool {
+	return strings.EqualFold(svc.Protocol, "http") || strings.EqualFold(svc.Protocol, "https")
+}
+
+func probeAddr(svc *domain.Service) string {
+	return net.JoinHostPort(svc.Host, strconv.Itoa(svc.Port))
+}
+
+func probeTarget(svc *domain.Service) string {
+	if probesHTTP(svc) {
+		return ProbeURL(svc)
+	}
+	return probeAddr(svc)
+}
+
+// ProbeURL returns the URL the health check of an http or https service is
+// probed at.
+func ProbeURL(svc *domain.Service) string {
+	probe := url.URL{
+		Scheme: strings.ToLower(svc.Protocol),
+		Host:   probeAddr(svc),
+		Path:   path.Join("/", svc.BasePath, svc.HealthCheck),
+	}
+	return probe.String()
+}
@@ -0,0 +1,238 @@
+package health
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/carlosealves2/video-ia/service-discover/internal/config"
+	"github.com/carlosealves2/video-ia/service-discover/internal/domain"
+	"github.com/carlosealves2/video-ia/service-discover/internal/repository"
+)
+
+func newTestChecker(repo repository.ServiceRepository) *Checker {
+	return NewChecker(repo, zap.NewNop(), config.HealthCheckConfig{
+		Enabled:          true,
+		Interval:         time.Second,
+		Timeout:          500 * time.Millisecond,
+		SuccessThreshold: 2,
+		FailureThreshold: 2,
+	})
+}
+
+func registerBackend(t *testing.T, repo repository.ServiceRepository, server *httptest.Server) *domain.Service {
+	host, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	require.NoError(t, err)
+	p, err := strconv.Atoi(port)
+	require.NoError(t, err)
+
+	service := &domain.Service{
+		ID:          "1",
+		Name:        "video-worker",
+		Host:        host,
+		Port:        p,
+		Protocol:    "http",
+		BasePath:    "/api",
+		HealthCheck: "/health",
+		Status:      domain.StatusHealthy,
+	}
+	require.NoError(t, repo.Create(service))
+	return service
+}
+
+func TestProbeURL(t *testing.T) {
+	svc := &domain.Service{
+		Protocol:    "https",
+		Host:        "10.0.0.1",
+		Port:        8443,
+		BasePath:    "/api/v1",
+		HealthCheck: "/health",
+	}
+
+	assert.Equal(t, "https://10.0.0.1:8443/api/v1/health", ProbeURL(svc))
+
+	svc.BasePath = "/api/v1/"
+	assert.Equal(t, "https://10.0.0.1:8443/api/v1/health", ProbeURL(svc))
+
+	svc.BasePath = ""
+	svc.HealthCheck = "health"
+	assert.Equal(t, "https://10.0.0.1:8443/health", ProbeURL(svc))
+}
+
+func TestCheckerThresholds(t *testing.T) {
+	var status atomic.Int32
+	status.Store(http.StatusOK)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/health", r.URL.Path)
+		w.WriteHeader(int(status.Load()))
+	}))
+	defer server.Close()
+
+	repo := repository.NewMemoryRepository()
+	registerBackend(t, repo, server)
+	checker := newTestChecker(repo)
+	ctx := context.Background()
+
+	status.Store(http.StatusServiceUnavailable)
+
+	checker.checkAll(ctx)
+	svc, _ := repo.GetByID("1")
+	assert.Equal(t, domain.StatusHealthy, svc.Status, "one failure is below the threshold")
+
+	checker.checkAll(ctx)
+	svc, _ = repo.GetByID("1")
+	assert.Equal(t, domain.StatusUnhealthy, svc.Status)
+
+	status.Store(http.StatusOK)
+
+	checker.checkAll(ctx)
+	svc, _ = repo.GetByID("1")
+	assert.Equal(t, domain.StatusUnhealthy, svc.Status, "one success is below the threshold")
+
+	checker.checkAll(ctx)
+	svc, _ = repo.GetByID("1")
+	assert.Equal(t, domain.StatusHealthy, svc.Status)
+}
+
+func TestCheckerReassertsFailingProbe(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	repo := repository.NewMemoryRepository()
+	registerBackend(t, repo, server)
+	checker := newTestChecker(repo)
+	ctx := context.Background()
+
+	checker.checkAll(ctx)
+	checker.checkAll(ctx)
+	svc, _ := repo.GetByID("1")
+	require.Equal(t, domain.StatusUnhealthy, svc.Status)
+
+	// Something else, e.g. a re-registration, marks it healthy again.
+	svc.Status = domain.StatusHealthy
+	require.NoError(t, repo.Update(svc))
+
+	checker.checkAll(ctx)
+	svc, _ = repo.GetByID("1")
+	assert.Equal(t, domain.StatusUnhealthy, svc.Status)
+}
+
+func TestCheckerKeepsExpiredUnhealthy(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	repo := repository.NewMemoryRepository()
+	svc := registerBackend(t, repo, server)
+	svc.Status = domain.StatusUnhealthy
+	require.NoError(t, repo.Update(svc))
+
+	var expired atomic.Bool
+	expired.Store(true)
+	checker := newTestChecker(repo)
+	checker.KeepExpired(func(*domain.Service) bool { return expired.Load() })
+	ctx := context.Background()
+
+	checker.checkAll(ctx)
+	checker.checkAll(ctx)
+	svc, _ = repo.GetByID("1")
+	assert.Equal(t, domain.StatusUnhealthy, svc.Status)
+
+	expired.Store(false)
+	checker.checkAll(ctx)
+	svc, _ = repo.GetByID("1")
+	assert.Equal(t, domain.StatusHealthy, svc.Status)
+}
+
+func TestCheckerUnreachableService(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	repo := repository.NewMemoryRepository()
+	registerBackend(t, repo, server)
+	server.Close()
+
+	checker := newTestChecker(repo)
+	checker.checkAll(context.Background())
+	checker.checkAll(context.Background())
+
+	svc, _ := repo.GetByID("1")
+	assert.Equal(t, domain.StatusUnhealthy, svc.Status)
+}
+
+func TestCheckerDialsGRPCServices(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() { _ = listener.Close() }()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			_ = conn.Close()
+		}
+	}()
+
+	host, port, err := net.SplitHostPort(listener.Addr().String())
+	require.NoError(t, err)
+	p, err := strconv.Atoi(port)
+	require.NoError(t, err)
+
+	repo := repository.NewMemoryRepository()
+	require.NoError(t, repo.Create(&domain.Service{
+		ID:          "1",
+		Name:        "video-encoder",
+		Host:        host,
+		Port:        p,
+		Protocol:    "grpc",
+		HealthCheck: "/health",
+		Status:      domain.StatusHealthy,
+	}))
+
+	checker := newTestChecker(repo)
+	ctx := context.Background()
+
+	checker.checkAll(ctx)
+	checker.checkAll(ctx)
+	svc, _ := repo.GetByID("1")
+	assert.Equal(t, domain.StatusHealthy, svc.Status, "a reachable grpc port is healthy")
+
+	require.NoError(t, listener.Close())
+
+	checker.checkAll(ctx)
+	checker.checkAll(ctx)
+	svc, _ = repo.GetByID("1")
+	assert.Equal(t, domain.StatusUnhealthy, svc.Status)
+}
+
+func TestCheckerPrunesRemovedServices(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	repo := repository.NewMemoryRepository()
+	registerBackend(t, repo, server)
+
+	checker := newTestChecker(repo)
+	checker.checkAll(context.Background())
+	assert.Len(t, checker.states, 1)
+
+	require.NoError(t, repo.Delete("1"))
+	checker.checkAll(context.Background())
+	assert.Empty(t, checker.states)
+}
+
+func TestCheckerStartStop(t *testing.T) {
+	checker := newTestChecker(repository.NewMemoryRepository())
+	checker.Start(context.Background())
+	checker.Stop()
+}
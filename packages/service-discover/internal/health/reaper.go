@@ -0,0 +1,118 @@
+package health
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/carlosealves2/video-ia/service-discover/internal/config"
+	"github.com/carlosealves2/video-ia/service-discover/internal/domain"
+	"github.com/carlosealves2/video-ia/service-discover/internal/repository"
+)
+
+type Reaper struct {
+	repo   repository.ServiceRepository
+	logger *zap.Logger
+	config config.HeartbeatConfig
+	now    func() time.Time
+
+	worker
+}
+
+func NewReaper(repo repository.ServiceRepository, logger *zap.Logger, cfg config.HeartbeatConfig) *Reaper {
+	return &Reaper{
+		repo:   repo,
+		logger: logger,
+		config: cfg,
+		now:    time.Now,
+	}
+}
+
+func (r *Reaper) Start(ctx context.Context) {
+	r.run(ctx, r.config.ReapInterval, r.reap)
+}
+
+func (r *Reaper) ttl(svc *domain.Service) time.Duration {
+	if svc.TTL > 0 {
+		return time.Duration(svc.TTL)
+	}
+	return r.config.TTL
+}
+
+// Expired reports whether svc has missed its heartbeat TTL.
+func (r *Reaper) Expired(svc *domain.Service) bool {
+	ttl := r.ttl(svc)
+	return ttl > 0 && r.now().Sub(svc.LastHeartbeat) > ttl
+}
+
+func (r *Reaper) reap(_ context.Context) {
+	for _, svc := range r.repo.GetAll() {
+		ttl := r.ttl(svc)
+		if ttl <= 0 {
+			continue
+		}
+
+		elapsed := r.now().Sub(svc.LastHeartbeat)
+		switch {
+		case elapsed > ttl+r.config.DeregisterAfter:
+			r.deregister(svc, elapsed)
+		case elapsed > ttl && svc.Status == domain.StatusHealthy:
+			r.expire(svc, ttl, elapsed)
+		}
+	}
+}
+
+// current re-reads svc, which GetAll may have returned before a heartbeat
+// or removal, and reports whether it is still the instance that was found
+// expired.
+func (r *Reaper) current(svc *domain.Service) (*domain.Service, bool) {
+	current, err := r.repo.GetByID(svc.Key())
+	if err != nil || !current.LastHeartbeat.Equal(svc.LastHeartbeat) {
+		return nil, false
+	}
+	return current, true
+}
+
+func (r *Reaper) expire(svc *domain.Service, ttl, elapsed time.Duration) {
+	svc, ok := r.current(svc)
+	if !ok || svc.Status != domain.StatusHealthy {
+		return
+	}
+
+	svc.Status = domain.StatusUnhealthy
+	if err := r.repo.Update(svc); err != nil {
+		r.logger.Error("Failed to mark expired service unhealthy",
+			zap.String("service_id", svc.ID),
+			zap.Error(err),
+		)
+		return
+	}
+
+	r.logger.Warn("Service heartbeat expired",
+		zap.String("service_id", svc.ID),
+		zap.String("service_name", svc.Name),
+		zap.Duration("ttl", ttl),
+		zap.Duration("since_last_heartbeat", elapsed),
+	)
+}
+
+func (r *Reaper) deregister(svc *domain.Service, elapsed time.Duration) {
+	if _, ok := r.current(svc); !ok {
+		return
+	}
+
+	if err := r.repo.Delete(svc.Key()); err != nil {
+		r.logger.Error("Failed to deregister expired service",
+			zap.String("service_id", svc.ID),
+			zap.Error(err),
+		)
+		return
+	}
+
+	r.logger.Info("Service deregistered after missing heartbeats",
+		zap.String("service_id", svc.ID),
+		zap.String("service_name", svc.Name),
+		zap.Duration("since_last_heartbeat", elapsed),
+	)
+}
@@ -0,0 +1,143 @@
+package health
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/carlosealves2/video-ia/service-discover/internal/config"
+	"github.com/carlosealves2/video-ia/service-discover/internal/domain"
+	"github.com/carlosealves2/video-ia/service-discover/internal/repository"
+)
+
+func newTestReaper(repo repository.ServiceRepository, now *time.Time) *Reaper {
+	reaper := NewReaper(repo, zap.NewNop(), config.HeartbeatConfig{
+		TTL:             30 * time.Second,
+		DeregisterAfter: time.Minute,
+		ReapInterval:    time.Second,
+	})
+	reaper.now = func() time.Time { return *now }
+	return reaper
+}
+
+func TestReaperExpiresAndDeregisters(t *testing.T) {
+	start := time.Now()
+	now := start
+
+	repo := repository.NewMemoryRepository()
+	require.NoError(t, repo.Create(&domain.Service{
+		ID:            "1",
+		Name:          "video-worker",
+		Status:        domain.StatusHealthy,
+		LastHeartbeat: start,
+	}))
+	reaper := newTestReaper(repo, &now)
+
+	now = start.Add(20 * time.Second)
+	reaper.reap(context.Background())
+	svc, err := repo.GetByID("1")
+	require.NoError(t, err)
+	assert.Equal(t, domain.StatusHealthy, svc.Status)
+
+	now = start.Add(31 * time.Second)
+	reaper.reap(context.Background())
+	svc, err = repo.GetByID("1")
+	require.NoError(t, err)
+	assert.Equal(t, domain.StatusUnhealthy, svc.Status)
+
+	now = start.Add(91 * time.Second)
+	reaper.reap(context.Background())
+	assert.False(t, repo.Exists("1"))
+}
+
+func TestReaperPerServiceTTL(t *testing.T) {
+	start := time.Now()
+	now := start.Add(10 * time.Second)
+
+	repo := repository.NewMemoryRepository()
+	require.NoError(t, repo.Create(&domain.Service{
+		ID:            "1",
+		Name:          "video-worker",
+		Status:        domain.StatusHealthy,
+		TTL:           domain.Duration(5 * time.Second),
+		LastHeartbeat: start,
+	}))
+	reaper := newTestReaper(repo, &now)
+
+	reaper.reap(context.Background())
+
+	svc, err := repo.GetByID("1")
+	require.NoError(t, err)
+	assert.Equal(t, domain.StatusUnhealthy, svc.Status)
+}
+
+func TestReaperWithoutTTL(t *testing.T) {
+	start := time.Now()
+	now := start.Add(24 * time.Hour)
+
+	repo := repository.NewMemoryRepository()
+	require.NoError(t, repo.Create(&domain.Service{
+		ID:            "1",
+		Name:          "legacy-service",
+		Status:        domain.StatusHealthy,
+		LastHeartbeat: start,
+	}))
+	reaper := newTestReaper(repo, &now)
+	reaper.config.TTL = 0
+
+	reaper.reap(context.Background())
+
+	svc, err := repo.GetByID("1")
+	require.NoError(t, err)
+	assert.Equal(t, domain.StatusHealthy, svc.Status)
+}
+
+// racingRepository runs meanwhile after listing services, as if a request
+// changed them before the reaper acted on its copies.
+type racingRepository struct {
+	repository.ServiceRepository
+	meanwhile func()
+}
+
+func (r *racingRepository) GetAll() []*domain.Service {
+	services := r.ServiceRepository.GetAll()
+	r.meanwhile()
+	return services
+}
+
+func TestReaperSkipsInstancesHeartbeatingMeanwhile(t *testing.T) {
+	start := time.Now()
+	now := start.Add(40 * time.Second)
+
+	memory := repository.NewMemoryRepository()
+	require.NoError(t, memory.Create(&domain.Service{
+		ID:            "expiring",
+		Name:          "video-worker",
+		Status:        domain.StatusHealthy,
+		LastHeartbeat: start,
+	}))
+	require.NoError(t, memory.Create(&domain.Service{
+		ID:            "deregistering",
+		Name:          "video-worker",
+		Status:        domain.StatusUnhealthy,
+		LastHeartbeat: start.Add(-time.Minute),
+	}))
+	repo := &racingRepository{ServiceRepository: memory, meanwhile: func() {
+		for _, svc := range memory.GetAll() {
+			svc.LastHeartbeat = now
+			require.NoError(t, memory.Update(svc))
+		}
+	}}
+
+	newTestReaper(repo, &now).reap(context.Background())
+
+	svc, err := memory.GetByID("expiring")
+	require.NoError(t, err)
+	assert.Equal(t, domain.StatusHealthy, svc.Status)
+	assert.Equal(t, now, svc.LastHeartbeat)
+	assert.True(t, memory.Exists("deregistering"))
+}
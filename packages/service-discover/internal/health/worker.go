@@ -0,0 +1,49 @@
+package health
+
+import (
+	"context"
+	"time"
+)
+
+type worker struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+	active func() bool
+}
+
+// OnlyWhen restricts each round to moments when cond reports true, e.g. to
+// the cluster leader so replicas do not probe and write concurrently.
+func (w *worker) OnlyWhen(cond func() bool) {
+	w.active = cond
+}
+
+func (w *worker) run(ctx context.Context, interval time.Duration, fn func(context.Context)) {
+	ctx, w.cancel = context.WithCancel(ctx)
+	w.done = make(chan struct{})
+
+	go func() {
+		defer close(w.done)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if w.active == nil || w.active() {
+					fn(ctx)
+				}
+			}
+		}
+	}()
+}
+
+func (w *worker) Stop() {
+	if w.cancel == nil {
+		return
+	}
+	w.cancel()
+	<-w.done
+}
@@ -0,0 +1,84 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/carlosealves2/video-ia/service-discover/internal/domain"
+	"github.com/carlosealves2/video-ia/service-discover/internal/repository"
+)
+
+func newService(id string) *domain.Service {
+	return &domain.Service{
+		ID:            id,
+		Name:          "transcoder",
+		Status:        domain.StatusHealthy,
+		LastHeartbeat: time.Now().Add(-time.Minute),
+	}
+}
+
+func TestRepositoryCountsCatalogActivity(t *testing.T) {
+	m := New()
+	repo := NewRepository(repository.NewMemoryRepository(), m)
+
+	svc := newService("a")
+	require.NoError(t, repo.Create(svc))
+	require.NoError(t, repo.Create(newService("b")))
+	assert.Equal(t, 2.0, testutil.ToFloat64(m.registrations.WithLabelValues("default", "transcoder")))
+
+	svc.LastHeartbeat = time.Now()
+	require.NoError(t, repo.Update(svc))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.heartbeats.WithLabelValues("default", "transcoder")))
+
+	svc.Status = domain.StatusUnhealthy
+	require.NoError(t, repo.Update(svc))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.heartbeats.WithLabelValues("default", "transcoder")), "status changes are not heartbeats")
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.transitions.WithLabelValues("default", "transcoder", "healthy", "unhealthy")))
+
+	require.NoError(t, repo.Delete("b"))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.deregistrations.WithLabelValues("default", "transcoder")))
+}
+
+func TestRepositoryRestoreIsNotCounted(t *testing.T) {
+	m := New()
+	repo := NewRepository(repository.NewMemoryRepository(), m)
+	require.NoError(t, repo.Create(newService("a")))
+
+	require.NoError(t, repo.Restore([]*domain.Service{newService("b"), newService("c")}))
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.registrations.WithLabelValues("default", "transcoder")))
+	assert.Equal(t, 0.0, testutil.ToFloat64(m.deregistrations.WithLabelValues("default", "transcoder")))
+	assert.Len(t, repo.GetAll(), 2)
+}
+
+func TestRepositoryReportsZeroHealthyInstances(t *testing.T) {
+	m := New()
+	repo := NewRepository(repository.NewMemoryRepository(), m)
+
+	svc := newService("a")
+	svc.Status = domain.StatusUnhealthy
+	require.NoError(t, repo.Create(svc))
+
+	expected := `
+# HELP service_discover_instances Registered instances, by service and health status.
+# TYPE service_discover_instances gauge
+service_discover_instances{namespace="default",service="transcoder",status="healthy"} 0
+service_discover_instances{namespace="default",service="transcoder",status="unhealthy"} 1
+`
+	assert.NoError(t, testutil.CollectAndCompare(repo, strings.NewReader(expected)))
+}
+
+func TestObserveRequest(t *testing.T) {
+	m := New()
+
+	m.ObserveRequest("GET", "/api/v1/services/:id", 200, 10*time.Millisecond)
+	m.ObserveRequest("GET", "/api/v1/services/:id", 200, 20*time.Millisecond)
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(m.requests.WithLabelValues("GET", "/api/v1/services/:id", "200")))
+	assert.Equal(t, 1, testutil.CollectAndCount(m.requestDuration))
+}
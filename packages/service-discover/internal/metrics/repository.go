@@ -0,0 +1,132 @@
+package metrics
+
+import (
+	"reflect"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/carlosealves2/video-ia/service-discover/internal/domain"
+	"github.com/carlosealves2/video-ia/service-discover/internal/repository"
+)
+
+var instancesDesc = prometheus.NewDesc(
+	prometheus.BuildFQName(namespace, "", "instances"),
+	"Registered instances, by service and health status.",
+	[]string{"namespace", "service", "status"},
+	nil,
+)
+
+// Repository wraps a ServiceRepository and counts registrations,
+// deregistrations, heartbeats and health transitions as they are applied.
+// It also exports the catalog's instance counts, read at scrape time.
+//
+// In a cluster it wraps each node's local replica, so every node reports
+// the same catalog activity.
+type Repository struct {
+	repository.ServiceRepository
+	metrics *Metrics
+}
+
+func NewRepository(repo repository.ServiceRepository, m *Metrics) *Repository {
+	r := &Repository{
+		ServiceRepository: repo,
+		metrics:           m,
+	}
+	m.registry.MustRegister(r)
+	return r
+}
+
+func (r *Repository) Create(service *domain.Service) error {
+	if err := r.ServiceRepository.Create(service); err != nil {
+		return err
+	}
+	r.metrics.registrations.WithLabelValues(labels(service)...).Inc()
+	return nil
+}
+
+func (r *Repository) Update(service *domain.Service) error {
+	previous, err := r.ServiceRepository.GetByID(service.Key())
+	if err != nil {
+		return err
+	}
+
+	if err := r.ServiceRepository.Update(service); err != nil {
+		return err
+	}
+
+	if isHeartbeat(previous, service) {
+		r.metrics.heartbeats.WithLabelValues(labels(service)...).Inc()
+	}
+	if previous.Status != service.Status {
+		r.metrics.transitions.WithLabelValues(append(labels(service), string(previous.Status), string(service.Status))...).Inc()
+	}
+	return nil
+}
+
+func (r *Repository) Delete(id string) error {
+	previous, err := r.ServiceRepository.GetByID(id)
+	if err != nil {
+		return err
+	}
+
+	if err := r.ServiceRepository.Delete(id); err != nil {
+		return err
+	}
+	r.metrics.deregistrations.WithLabelValues(labels(previous)...).Inc()
+	return nil
+}
+
+// Restore replaces the catalog without counting registrations or
+// deregistrations: a restored snapshot brings state the cluster already
+// counted, and the instance gauges are read from the catalog at scrape time.
+func (r *Repository) Restore(services []*domain.Service) error {
+	return repository.Restore(r.ServiceRepository, services)
+}
+
+func (r *Repository) Describe(ch chan<- *prometheus.Desc) {
+	ch <- instancesDesc
+}
+
+// Collect reports both statuses of every registered service, so that a
+// service whose instances all turn unhealthy reports zero healthy instances
+// rather than no series.
+func (r *Repository) Collect(ch chan<- prometheus.Metric) {
+	type key struct{ namespace, service string }
+	counts := make(map[key]map[domain.ServiceStatus]int)
+
+	for _, svc := range r.ServiceRepository.GetAll() {
+		l := labels(svc)
+		k := key{l[0], l[1]}
+		if counts[k] == nil {
+			counts[k] = make(map[domain.ServiceStatus]int)
+		}
+		counts[k][svc.Status]++
+	}
+
+	for k, byStatus := range counts {
+		for _, status := range []domain.ServiceStatus{domain.StatusHealthy, domain.StatusUnhealthy} {
+			ch <- prometheus.MustNewConstMetric(instancesDesc, prometheus.GaugeValue,
+				float64(byStatus[status]), k.namespace, k.service, string(status))
+		}
+	}
+}
+
+func labels(svc *domain.Service) []string {
+	namespace := svc.Namespace
+	if namespace == "" {
+		namespace = domain.DefaultNamespace
+	}
+	return []string{namespace, svc.Name}
+}
+
+// isHeartbeat reports whether current only moves previous's LastHeartbeat
+// forward, possibly reviving it.
+func isHeartbeat(previous, current *domain.Service) bool {
+	if !current.LastHeartbeat.After(previous.LastHeartbeat) {
+		return false
+	}
+	normalized := current.Clone()
+	normalized.LastHeartbeat = previous.LastHeartbeat
+	normalized.Status = previous.Status
+	return reflect.DeepEqual(previous, normalized)
+}
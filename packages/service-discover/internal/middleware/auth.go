@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/carlosealves2/video-ia/service-discover/internal/auth"
+)
+
+// Auth rejects requests without valid credentials (401) or without the
+// scope their method needs (403): read for GET and HEAD, write otherwise.
+// Handlers find the caller with auth.FromContext.
+func Auth(authenticator *auth.Authenticator, logger *zap.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, err := authenticator.Authenticate(c.Request)
+		if err != nil {
+			logger.Warn("Rejected unauthenticated request",
+				zap.String("method", c.Request.Method),
+				zap.String("path", c.Request.URL.Path),
+				zap.String("client_ip", c.ClientIP()),
+				zap.Error(err),
+			)
+			c.Header("WWW-Authenticate", `Bearer realm="service-discover"`)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+
+		scope := auth.ScopeFor(c.Request.Method)
+		if !principal.Has(scope) {
+			logger.Warn("Rejected request lacking scope",
+				zap.String("method", c.Request.Method),
+				zap.String("path", c.Request.URL.Path),
+				zap.String("subject", principal.Subject),
+				zap.String("scope", string(scope)),
+			)
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error": auth.ErrInsufficientScope.Error() + ": " + string(scope) + " scope required",
+			})
+			return
+		}
+
+		c.Request = c.Request.WithContext(auth.NewContext(c.Request.Context(), principal))
+		c.Next()
+	}
+}
+
+// RequireScope rejects requests whose caller, identified by Auth, lacks
+// scope (403). It must run after Auth.
+func RequireScope(scope auth.Scope, logger *zap.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, _ := auth.FromContext(c.Request.Context())
+		if !principal.Has(scope) {
+			logger.Warn("Rejected request lacking scope",
+				zap.String("method", c.Request.Method),
+				zap.String("path", c.Request.URL.Path),
+				zap.String("subject", principal.Subject),
+				zap.String("scope", string(scope)),
+			)
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error": auth.ErrInsufficientScope.Error() + ": " + string(scope) + " scope required",
+			})
+			return
+		}
+		c.Next()
+	}
+}
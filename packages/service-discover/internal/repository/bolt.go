@@ -0,0 +1,160 @@
+package repository
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+	"go.uber.org/zap"
+
+	"github.com/carlosealves2/video-ia/service-discover/internal/domain"
+)
+
+var servicesBucket = []byte("services")
+
+type BoltRepository struct {
+	db     *bolt.DB
+	logger *zap.Logger
+}
+
+// NewBoltRepository opens the database at path, creating it if needed.
+// Records that can no longer be decoded are reported to logger.
+func NewBoltRepository(path string, logger *zap.Logger) (*BoltRepository, error) {
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, err
+		}
+	}
+
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(servicesBucket)
+		return err
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+
+	return &BoltRepository{db: db, logger: logger}, nil
+}
+
+func (r *BoltRepository) Create(service *domain.Service) error {
+	data, err := json.Marshal(service)
+	if err != nil {
+		return err
+	}
+
+	return r.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(servicesBucket)
+		if bucket.Get([]byte(service.Key())) != nil {
+			return ErrServiceAlreadyExists
+		}
+		return bucket.Put([]byte(service.Key()), data)
+	})
+}
+
+func (r *BoltRepository) GetByID(id string) (*domain.Service, error) {
+	var service domain.Service
+
+	err := r.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(servicesBucket).Get([]byte(id))
+		if data == nil {
+			return ErrServiceNotFound
+		}
+		return json.Unmarshal(data, &service)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &service, nil
+}
+
+func (r *BoltRepository) GetAll() []*domain.Service {
+	services := make([]*domain.Service, 0)
+
+	_ = r.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(servicesBucket).ForEach(func(key, data []byte) error {
+			var service domain.Service
+			if err := json.Unmarshal(data, &service); err != nil {
+				r.logger.Error("Skipping undecodable service record",
+					zap.ByteString("key", key),
+					zap.Error(err),
+				)
+				return nil
+			}
+			services = append(services, &service)
+			return nil
+		})
+	})
+
+	return services
+}
+
+func (r *BoltRepository) Update(service *domain.Service) error {
+	data, err := json.Marshal(service)
+	if err != nil {
+		return err
+	}
+
+	return r.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(servicesBucket)
+		if bucket.Get([]byte(service.Key())) == nil {
+			return ErrServiceNotFound
+		}
+		return bucket.Put([]byte(service.Key()), data)
+	})
+}
+
+func (r *BoltRepository) Delete(id string) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(servicesBucket)
+		if bucket.Get([]byte(id)) == nil {
+			return ErrServiceNotFound
+		}
+		return bucket.Delete([]byte(id))
+	})
+}
+
+func (r *BoltRepository) Exists(id string) bool {
+	exists := false
+	_ = r.db.View(func(tx *bolt.Tx) error {
+		exists = tx.Bucket(servicesBucket).Get([]byte(id)) != nil
+		return nil
+	})
+	return exists
+}
+
+func (r *BoltRepository) Restore(services []*domain.Service) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		if err := tx.DeleteBucket(servicesBucket); err != nil {
+			return err
+		}
+		bucket, err := tx.CreateBucket(servicesBucket)
+		if err != nil {
+			return err
+		}
+
+		for _, service := range services {
+			data, err := json.Marshal(service)
+			if err != nil {
+				return err
+			}
+			if err := bucket.Put([]byte(service.Key()), data); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *BoltRepository) Close() error {
+	return r.db.Close()
+}
@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/carlosealves2/video-ia/service-discover/internal/domain"
+)
+
+func newTestBoltRepository(t *testing.T, path string) *BoltRepository {
+	repo, err := NewBoltRepository(path, zap.NewNop())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = repo.Close() })
+	return repo
+}
+
+func TestBoltRepositoryContract(t *testing.T) {
+	runRepositoryContract(t, func(t *testing.T) ServiceRepository {
+		return newTestBoltRepository(t, filepath.Join(t.TempDir(), "services.db"))
+	})
+}
+
+func TestNewBoltRepositoryCreatesDirectory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "dir", "services.db")
+
+	repo := newTestBoltRepository(t, path)
+
+	assert.NotNil(t, repo)
+	assert.FileExists(t, path)
+}
+
+func TestBoltRepositoryPersistsAcrossReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "services.db")
+
+	repo, err := NewBoltRepository(path, zap.NewNop())
+	require.NoError(t, err)
+
+	service := createTestService("1", "video-worker")
+	service.TTL = domain.Duration(30 * time.Second)
+	service.Metadata = map[string]string{"gpu": "true"}
+	require.NoError(t, repo.Create(service))
+	require.NoError(t, repo.Close())
+
+	reopened := newTestBoltRepository(t, path)
+
+	result, err := reopened.GetByID("1")
+	require.NoError(t, err)
+	assert.Equal(t, "video-worker", result.Name)
+	assert.Equal(t, domain.Duration(30*time.Second), result.TTL)
+	assert.Equal(t, "true", result.Metadata["gpu"])
+	assert.Len(t, reopened.GetAll(), 1)
+}
+
+func TestBoltRepositoryGetAllReportsUndecodableRecords(t *testing.T) {
+	core, logs := observer.New(zap.ErrorLevel)
+	repo, err := NewBoltRepository(filepath.Join(t.TempDir(), "services.db"), zap.New(core))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = repo.Close() })
+
+	require.NoError(t, repo.Create(createTestService("1", "video-worker")))
+	require.NoError(t, repo.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(servicesBucket).Put([]byte("2"), []byte("{not json"))
+	}))
+
+	services := repo.GetAll()
+
+	require.Len(t, services, 1)
+	assert.Equal(t, "1", services[0].ID)
+	entries := logs.FilterMessage("Skipping undecodable service record").All()
+	require.Len(t, entries, 1)
+	assert.Equal(t, "2", entries[0].ContextMap()["key"])
+}
@@ -0,0 +1,307 @@
+package repository
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/carlosealves2/video-ia/service-discover/internal/domain"
+)
+
+// runRepositoryContract runs the behaviour every ServiceRepository
+// implementation must provide against a fresh repository per case.
+func runRepositoryContract(t *testing.T, newRepo func(t *testing.T) ServiceRepository) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, repo ServiceRepository)
+	}{
+		{"Create", testCreate},
+		{"CreateDuplicate", testCreateDuplicate},
+		{"GetByID", testGetByID},
+		{"GetByIDNotFound", testGetByIDNotFound},
+		{"GetAll", testGetAll},
+		{"GetAllEmpty", testGetAllEmpty},
+		{"Update", testUpdate},
+		{"UpdateNotFound", testUpdateNotFound},
+		{"Delete", testDelete},
+		{"DeleteNotFound", testDeleteNotFound},
+		{"Exists", testExists},
+		{"ConcurrentAccess", testConcurrentAccess},
+		{"ConcurrentReadWrite", testConcurrentReadWrite},
+		{"ServiceWithRoutes", testServiceWithRoutes},
+		{"DeleteAndRecreate", testDeleteAndRecreate},
+		{"ReturnsCopies", testReturnsCopies},
+		{"SameIDInTwoNamespaces", testSameIDInTwoNamespaces},
+		{"Restore", testRestore},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, newRepo(t))
+		})
+	}
+}
+
+func createTestService(id, name string) *domain.Service {
+	return &domain.Service{
+		ID:            id,
+		Name:          name,
+		Host:          "localhost",
+		Port:          8080,
+		Protocol:      "http",
+		Status:        domain.StatusHealthy,
+		LastHeartbeat: time.Now(),
+		RegisteredAt:  time.Now(),
+	}
+}
+
+func testCreate(t *testing.T, repo ServiceRepository) {
+	service := createTestService("1", "test-service")
+
+	err := repo.Create(service)
+
+	require.NoError(t, err)
+	assert.True(t, repo.Exists("1"))
+}
+
+func testCreateDuplicate(t *testing.T, repo ServiceRepository) {
+	service := createTestService("1", "test-service")
+
+	err := repo.Create(service)
+	require.NoError(t, err)
+
+	err = repo.Create(service)
+	assert.ErrorIs(t, err, ErrServiceAlreadyExists)
+}
+
+func testGetByID(t *testing.T, repo ServiceRepository) {
+	service := createTestService("1", "test-service")
+	_ = repo.Create(service)
+
+	result, err := repo.GetByID("1")
+
+	require.NoError(t, err)
+	assert.Equal(t, "1", result.ID)
+	assert.Equal(t, "test-service", result.Name)
+}
+
+func testGetByIDNotFound(t *testing.T, repo ServiceRepository) {
+
+	_, err := repo.GetByID("not-found")
+
+	assert.ErrorIs(t, err, ErrServiceNotFound)
+}
+
+func testGetAll(t *testing.T, repo ServiceRepository) {
+	_ = repo.Create(createTestService("1", "service-1"))
+	_ = repo.Create(createTestService("2", "service-2"))
+	_ = repo.Create(createTestService("3", "service-3"))
+
+	services := repo.GetAll()
+
+	assert.Len(t, services, 3)
+}
+
+func testGetAllEmpty(t *testing.T, repo ServiceRepository) {
+
+	services := repo.GetAll()
+
+	assert.Empty(t, services)
+}
+
+func testUpdate(t *testing.T, repo ServiceRepository) {
+	service := createTestService("1", "test-service")
+	_ = repo.Create(service)
+
+	service.Name = "updated-service"
+	service.Port = 9090
+	err := repo.Update(service)
+
+	require.NoError(t, err)
+
+	result, _ := repo.GetByID("1")
+	assert.Equal(t, "updated-service", result.Name)
+	assert.Equal(t, 9090, result.Port)
+}
+
+func testUpdateNotFound(t *testing.T, repo ServiceRepository) {
+	service := createTestService("not-found", "test-service")
+
+	err := repo.Update(service)
+
+	assert.ErrorIs(t, err, ErrServiceNotFound)
+}
+
+func testDelete(t *testing.T, repo ServiceRepository) {
+	service := createTestService("1", "test-service")
+	_ = repo.Create(service)
+
+	err := repo.Delete("1")
+
+	require.NoError(t, err)
+	assert.False(t, repo.Exists("1"))
+}
+
+func testDeleteNotFound(t *testing.T, repo ServiceRepository) {
+
+	err := repo.Delete("not-found")
+
+	assert.ErrorIs(t, err, ErrServiceNotFound)
+}
+
+func testExists(t *testing.T, repo ServiceRepository) {
+	service := createTestService("1", "test-service")
+	_ = repo.Create(service)
+
+	assert.True(t, repo.Exists("1"))
+	assert.False(t, repo.Exists("2"))
+}
+
+func testConcurrentAccess(t *testing.T, repo ServiceRepository) {
+	var wg sync.WaitGroup
+
+	// Concurrent creates
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			service := createTestService(string(rune('A'+id)), "service")
+			_ = repo.Create(service)
+		}(i)
+	}
+
+	wg.Wait()
+
+	// Concurrent reads
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = repo.GetAll()
+		}()
+	}
+
+	wg.Wait()
+}
+
+func testConcurrentReadWrite(t *testing.T, repo ServiceRepository) {
+	service := createTestService("1", "test-service")
+	_ = repo.Create(service)
+
+	var wg sync.WaitGroup
+
+	// Concurrent updates and reads
+	for i := 0; i < 50; i++ {
+		wg.Add(2)
+
+		go func() {
+			defer wg.Done()
+			s := createTestService("1", "updated")
+			_ = repo.Update(s)
+		}()
+
+		go func() {
+			defer wg.Done()
+			_, _ = repo.GetByID("1")
+		}()
+	}
+
+	wg.Wait()
+}
+
+func testServiceWithRoutes(t *testing.T, repo ServiceRepository) {
+	service := &domain.Service{
+		ID:       "1",
+		Name:     "api-service",
+		Host:     "localhost",
+		Port:     8080,
+		Protocol: "http",
+		BasePath: "/api/v1",
+		Routes: []domain.Route{
+			{Path: "/users", Methods: []string{"GET", "POST"}},
+			{Path: "/orders", Methods: []string{"GET"}},
+		},
+		HealthCheck:   "/health",
+		Tags:          []string{"api", "v1"},
+		Metadata:      map[string]string{"version": "1.0.0"},
+		Status:        domain.StatusHealthy,
+		LastHeartbeat: time.Now(),
+		RegisteredAt:  time.Now(),
+	}
+
+	err := repo.Create(service)
+	require.NoError(t, err)
+
+	result, err := repo.GetByID("1")
+	require.NoError(t, err)
+
+	assert.Equal(t, "/api/v1", result.BasePath)
+	assert.Len(t, result.Routes, 2)
+	assert.Equal(t, "/users", result.Routes[0].Path)
+	assert.Equal(t, []string{"GET", "POST"}, result.Routes[0].Methods)
+	assert.Equal(t, []string{"api", "v1"}, result.Tags)
+	assert.Equal(t, "1.0.0", result.Metadata["version"])
+}
+
+func testDeleteAndRecreate(t *testing.T, repo ServiceRepository) {
+	service := createTestService("1", "test-service")
+
+	_ = repo.Create(service)
+	_ = repo.Delete("1")
+
+	err := repo.Create(service)
+	require.NoError(t, err)
+	assert.True(t, repo.Exists("1"))
+}
+
+func testReturnsCopies(t *testing.T, repo ServiceRepository) {
+	service := createTestService("1", "test-service")
+	service.Tags = []string{"api"}
+	require.NoError(t, repo.Create(service))
+
+	service.Name = "changed-before-read"
+	result, err := repo.GetByID("1")
+	require.NoError(t, err)
+	assert.Equal(t, "test-service", result.Name)
+
+	result.Tags[0] = "changed-after-read"
+	result, err = repo.GetByID("1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"api"}, result.Tags)
+}
+
+func testSameIDInTwoNamespaces(t *testing.T, repo ServiceRepository) {
+	dev := createTestService("1", "test-service")
+	dev.Namespace = "dev"
+	require.NoError(t, repo.Create(createTestService("1", "test-service")))
+	require.NoError(t, repo.Create(dev))
+
+	assert.Len(t, repo.GetAll(), 2)
+
+	result, err := repo.GetByID(domain.Key("dev", "1"))
+	require.NoError(t, err)
+	assert.Equal(t, "dev", result.Namespace)
+
+	require.NoError(t, repo.Delete(dev.Key()))
+	assert.True(t, repo.Exists("1"))
+	assert.False(t, repo.Exists(dev.Key()))
+}
+
+func testRestore(t *testing.T, repo ServiceRepository) {
+	require.NoError(t, repo.Create(createTestService("stale", "test-service")))
+	require.NoError(t, repo.Create(createTestService("1", "before-restore")))
+
+	dev := createTestService("1", "test-service")
+	dev.Namespace = "dev"
+	require.NoError(t, Restore(repo, []*domain.Service{createTestService("1", "test-service"), dev}))
+
+	assert.Len(t, repo.GetAll(), 2)
+	assert.False(t, repo.Exists("stale"))
+	result, err := repo.GetByID("1")
+	require.NoError(t, err)
+	assert.Equal(t, "test-service", result.Name)
+	assert.True(t, repo.Exists(dev.Key()))
+}
@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"context"
+	"reflect"
+	"sync"
+
+	"github.com/carlosealves2/video-ia/service-discover/internal/domain"
+)
+
+// Watcher exposes the catalog's modify index, which increases on every
+// successful mutation, and lets callers block until it moves.
+type Watcher interface {
+	Index() uint64
+	Wait(ctx context.Context, index uint64) uint64
+}
+
+// IndexedRepository wraps a ServiceRepository and bumps a modify index on
+// every successful Create, Update or Delete. Updates that only refresh
+// LastHeartbeat leave it alone, so blocking queries do not wake on every
+// heartbeat.
+type IndexedRepository struct {
+	ServiceRepository
+
+	mu      sync.Mutex
+	index   uint64
+	changed chan struct{}
+}
+
+func NewIndexedRepository(repo ServiceRepository) *IndexedRepository {
+	return &IndexedRepository{
+		ServiceRepository: repo,
+		index:             1,
+		changed:           make(chan struct{}),
+	}
+}
+
+func (r *IndexedRepository) Create(service *domain.Service) error {
+	if err := r.ServiceRepository.Create(service); err != nil {
+		return err
+	}
+	r.bump()
+	return nil
+}
+
+func (r *IndexedRepository) Update(service *domain.Service) error {
+	previous, err := r.ServiceRepository.GetByID(service.Key())
+	if err != nil {
+		return err
+	}
+
+	if err := r.ServiceRepository.Update(service); err != nil {
+		return err
+	}
+	if !OnlyHeartbeatChanged(previous, service) {
+		r.bump()
+	}
+	return nil
+}
+
+func (r *IndexedRepository) Delete(id string) error {
+	if err := r.ServiceRepository.Delete(id); err != nil {
+		return err
+	}
+	r.bump()
+	return nil
+}
+
+// Restore replaces the catalog and bumps the index once for the whole
+// replacement.
+func (r *IndexedRepository) Restore(services []*domain.Service) error {
+	if err := Restore(r.ServiceRepository, services); err != nil {
+		return err
+	}
+	r.bump()
+	return nil
+}
+
+func (r *IndexedRepository) Index() uint64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.index
+}
+
+// Wait blocks until the index is greater than index or ctx is done, and
+// returns the index at that point.
+func (r *IndexedRepository) Wait(ctx context.Context, index uint64) uint64 {
+	for {
+		r.mu.Lock()
+		current, changed := r.index, r.changed
+		r.mu.Unlock()
+
+		if current > index {
+			return current
+		}
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return current
+		}
+	}
+}
+
+// OnlyHeartbeatChanged reports whether current differs from previous in
+// nothing but LastHeartbeat.
+func OnlyHeartbeatChanged(previous, current *domain.Service) bool {
+	normalized := current.Clone()
+	normalized.LastHeartbeat = previous.LastHeartbeat
+	return reflect.DeepEqual(previous, normalized)
+}
+
+func (r *IndexedRepository) bump() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.index++
+	close(r.changed)
+	r.changed = make(chan struct{})
+}
@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/carlosealves2/video-ia/service-discover/internal/domain"
+)
+
+func TestIndexedRepositoryContract(t *testing.T) {
+	runRepositoryContract(t, func(t *testing.T) ServiceRepository {
+		return NewIndexedRepository(NewMemoryRepository())
+	})
+}
+
+func TestIndexedRepositoryBumpsOnMutation(t *testing.T) {
+	repo := NewIndexedRepository(NewMemoryRepository())
+	assert.Equal(t, uint64(1), repo.Index())
+
+	require.NoError(t, repo.Create(createTestService("1", "test-service")))
+	assert.Equal(t, uint64(2), repo.Index())
+
+	require.NoError(t, repo.Update(createTestService("1", "updated")))
+	assert.Equal(t, uint64(3), repo.Index())
+
+	require.NoError(t, repo.Delete("1"))
+	assert.Equal(t, uint64(4), repo.Index())
+
+	_, _ = repo.GetByID("1")
+	_ = repo.GetAll()
+	assert.Equal(t, uint64(4), repo.Index(), "reads must not bump the index")
+}
+
+func TestIndexedRepositoryRestoreBumpsOnce(t *testing.T) {
+	repo := NewIndexedRepository(NewMemoryRepository())
+	require.NoError(t, repo.Create(createTestService("1", "test-service")))
+
+	require.NoError(t, repo.Restore([]*domain.Service{
+		createTestService("2", "test-service"),
+		createTestService("3", "test-service"),
+	}))
+
+	assert.Equal(t, uint64(3), repo.Index())
+	assert.Len(t, repo.GetAll(), 2)
+}
+
+func TestIndexedRepositoryIgnoresHeartbeats(t *testing.T) {
+	repo := NewIndexedRepository(NewMemoryRepository())
+	require.NoError(t, repo.Create(createTestService("1", "test-service")))
+
+	svc, err := repo.GetByID("1")
+	require.NoError(t, err)
+	svc.LastHeartbeat = svc.LastHeartbeat.Add(time.Minute)
+	require.NoError(t, repo.Update(svc))
+	assert.Equal(t, uint64(2), repo.Index())
+
+	svc.Port = 9090
+	require.NoError(t, repo.Update(svc))
+	assert.Equal(t, uint64(3), repo.Index())
+}
+
+func TestIndexedRepositoryFailedMutationKeepsIndex(t *testing.T) {
+	repo := NewIndexedRepository(NewMemoryRepository())
+
+	assert.ErrorIs(t, repo.Delete("missing"), ErrServiceNotFound)
+	assert.ErrorIs(t, repo.Update(createTestService("missing", "x")), ErrServiceNotFound)
+
+	assert.Equal(t, uint64(1), repo.Index())
+}
+
+func TestIndexedRepositoryWaitReturnsImmediatelyWhenBehind(t *testing.T) {
+	repo := NewIndexedRepository(NewMemoryRepository())
+	require.NoError(t, repo.Create(createTestService("1", "test-service")))
+
+	assert.Equal(t, uint64(2), repo.Wait(context.Background(), 1))
+}
+
+func TestIndexedRepositoryWaitUnblocksOnChange(t *testing.T) {
+	repo := NewIndexedRepository(NewMemoryRepository())
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		_ = repo.Create(createTestService("1", "test-service"))
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	assert.Equal(t, uint64(2), repo.Wait(ctx, 1))
+}
+
+func TestIndexedRepositoryWaitTimesOut(t *testing.T) {
+	repo := NewIndexedRepository(NewMemoryRepository())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	assert.Equal(t, uint64(1), repo.Wait(ctx, 1))
+}
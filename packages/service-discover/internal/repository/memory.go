@@ -12,6 +12,9 @@ var (
 	ErrServiceAlreadyExists = errors.New("service already exists")
 )
 
+// ServiceRepository stores services under their domain.Key, so GetByID,
+// Delete and Exists take a key rather than a bare ID for services outside
+// the default namespace.
 type ServiceRepository interface {
 	Create(service *domain.Service) error
 	GetByID(id string) (*domain.Service, error)
@@ -21,6 +24,33 @@ type ServiceRepository interface {
 	Exists(id string) bool
 }
 
+// Restorer is implemented by repositories that can replace their whole
+// content at once. Wrappers forward it to the repository they wrap and
+// report the replacement as one change rather than one per service.
+type Restorer interface {
+	Restore(services []*domain.Service) error
+}
+
+// Restore replaces the content of repo with services, in one step when repo
+// is a Restorer and otherwise by deleting and creating each service.
+func Restore(repo ServiceRepository, services []*domain.Service) error {
+	if r, ok := repo.(Restorer); ok {
+		return r.Restore(services)
+	}
+
+	for _, svc := range repo.GetAll() {
+		if err := repo.Delete(svc.Key()); err != nil {
+			return err
+		}
+	}
+	for _, svc := range services {
+		if err := repo.Create(svc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 type MemoryRepository struct {
 	services map[string]*domain.Service
 	mu       sync.RWMutex
@@ -36,11 +66,11 @@ func (r *MemoryRepository) Create(service *domain.Service) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	if _, exists := r.services[service.ID]; exists {
+	if _, exists := r.services[service.Key()]; exists {
 		return ErrServiceAlreadyExists
 	}
 
-	r.services[service.ID] = service
+	r.services[service.Key()] = service.Clone()
 	return nil
 }
 
@@ -53,7 +83,7 @@ func (r *MemoryRepository) GetByID(id string) (*domain.Service, error) {
 		return nil, ErrServiceNotFound
 	}
 
-	return service, nil
+	return service.Clone(), nil
 }
 
 func (r *MemoryRepository) GetAll() []*domain.Service {
@@ -62,7 +92,7 @@ func (r *MemoryRepository) GetAll() []*domain.Service {
 
 	services := make([]*domain.Service, 0, len(r.services))
 	for _, service := range r.services {
-		services = append(services, service)
+		services = append(services, service.Clone())
 	}
 
 	return services
@@ -72,11 +102,11 @@ func (r *MemoryRepository) Update(service *domain.Service) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	if _, exists := r.services[service.ID]; !exists {
+	if _, exists := r.services[service.Key()]; !exists {
 		return ErrServiceNotFound
 	}
 
-	r.services[service.ID] = service
+	r.services[service.Key()] = service.Clone()
 	return nil
 }
 
@@ -99,3 +129,16 @@ func (r *MemoryRepository) Exists(id string) bool {
 	_, exists := r.services[id]
 	return exists
 }
+
+func (r *MemoryRepository) Restore(services []*domain.Service) error {
+	restored := make(map[string]*domain.Service, len(services))
+	for _, service := range services {
+		restored[service.Key()] = service.Clone()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.services = restored
+	return nil
+}
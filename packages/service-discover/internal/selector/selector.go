@@ -0,0 +1,180 @@
+// Package selector evaluates label selectors against the tags and metadata
+// of a service, in the style of Kubernetes label selectors. A selector is a
+// comma-separated list of requirements, all of which must hold:
+//
+//	env=prod              metadata env is prod (env==prod is the same)
+//	env!=prod             metadata env is not prod, or is not set
+//	region in (us,eu)     metadata region is us or eu
+//	region notin (us,eu)  metadata region is neither, or is not set
+//	gpu                   gpu is a tag or a metadata key
+//	!deprecated           deprecated is neither a tag nor a metadata key
+package selector
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+type Operator string
+
+const (
+	Exists       Operator = "exists"
+	DoesNotExist Operator = "!"
+	Equals       Operator = "="
+	NotEquals    Operator = "!="
+	In           Operator = "in"
+	NotIn        Operator = "notin"
+)
+
+var (
+	keyPattern = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9._/-]*[A-Za-z0-9])?$`)
+	setPattern = regexp.MustCompile(`^(\S+)\s+(in|notin)\s*\((.*)\)$`)
+)
+
+type Requirement struct {
+	Key      string
+	Operator Operator
+	Values   []string
+}
+
+// Selector matches when all of its requirements do. The empty selector
+// matches everything.
+type Selector []Requirement
+
+// Parse parses a selector such as "env=prod,region in (us,eu),!deprecated".
+func Parse(s string) (Selector, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+
+	parts, err := split(s)
+	if err != nil {
+		return nil, err
+	}
+
+	sel := make(Selector, 0, len(parts))
+	for _, part := range parts {
+		r, err := parseRequirement(strings.TrimSpace(part))
+		if err != nil {
+			return nil, err
+		}
+		sel = append(sel, r)
+	}
+	return sel, nil
+}
+
+// split cuts s at the commas that are not inside a value set.
+func split(s string) ([]string, error) {
+	var parts []string
+	depth, start := 0, 0
+	for i, r := range s {
+		switch r {
+		case '(':
+			if depth > 0 {
+				return nil, fmt.Errorf("selector: nested parenthesis in %q", s)
+			}
+			depth++
+		case ')':
+			if depth == 0 {
+				return nil, fmt.Errorf("selector: unbalanced parenthesis in %q", s)
+			}
+			depth--
+		case ',':
+			if depth == 0 {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	if depth > 0 {
+		return nil, fmt.Errorf("selector: unbalanced parenthesis in %q", s)
+	}
+	return append(parts, s[start:]), nil
+}
+
+func parseRequirement(s string) (Requirement, error) {
+	if s == "" {
+		return Requirement{}, errors.New("selector: empty requirement")
+	}
+
+	var r Requirement
+	if m := setPattern.FindStringSubmatch(s); m != nil {
+		r = Requirement{Key: m[1], Operator: Operator(m[2])}
+		for _, v := range strings.Split(m[3], ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				r.Values = append(r.Values, v)
+			}
+		}
+		if len(r.Values) == 0 {
+			return Requirement{}, fmt.Errorf("selector: %q needs at least one value", s)
+		}
+	} else if key, value, ok := strings.Cut(s, "!="); ok {
+		r = Requirement{Key: strings.TrimSpace(key), Operator: NotEquals, Values: []string{strings.TrimSpace(value)}}
+	} else if key, value, ok := strings.Cut(s, "=="); ok {
+		r = Requirement{Key: strings.TrimSpace(key), Operator: Equals, Values: []string{strings.TrimSpace(value)}}
+	} else if key, value, ok := strings.Cut(s, "="); ok {
+		r = Requirement{Key: strings.TrimSpace(key), Operator: Equals, Values: []string{strings.TrimSpace(value)}}
+	} else if key, ok := strings.CutPrefix(s, "!"); ok {
+		r = Requirement{Key: strings.TrimSpace(key), Operator: DoesNotExist}
+	} else {
+		r = Requirement{Key: s, Operator: Exists}
+	}
+
+	if !keyPattern.MatchString(r.Key) {
+		return Requirement{}, fmt.Errorf("selector: invalid key %q in %q", r.Key, s)
+	}
+	for _, v := range r.Values {
+		if strings.ContainsAny(v, "=!()") {
+			return Requirement{}, fmt.Errorf("selector: invalid value %q in %q", v, s)
+		}
+	}
+	return r, nil
+}
+
+func (s Selector) Matches(tags []string, metadata map[string]string) bool {
+	for _, r := range s {
+		if !r.Matches(tags, metadata) {
+			return false
+		}
+	}
+	return true
+}
+
+func (r Requirement) Matches(tags []string, metadata map[string]string) bool {
+	value, set := metadata[r.Key]
+	switch r.Operator {
+	case Exists:
+		return set || slices.Contains(tags, r.Key)
+	case DoesNotExist:
+		return !set && !slices.Contains(tags, r.Key)
+	case Equals, In:
+		return set && slices.Contains(r.Values, value)
+	case NotEquals, NotIn:
+		return !set || !slices.Contains(r.Values, value)
+	}
+	return false
+}
+
+func (s Selector) String() string {
+	parts := make([]string, len(s))
+	for i, r := range s {
+		parts[i] = r.String()
+	}
+	return strings.Join(parts, ",")
+}
+
+func (r Requirement) String() string {
+	switch r.Operator {
+	case Exists:
+		return r.Key
+	case DoesNotExist:
+		return "!" + r.Key
+	case In, NotIn:
+		return r.Key + " " + string(r.Operator) + " (" + strings.Join(r.Values, ",") + ")"
+	default:
+		return r.Key + string(r.Operator) + strings.Join(r.Values, ",")
+	}
+}
@@ -0,0 +1,81 @@
+package selector
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	sel, err := Parse("env=prod, tier==gold,zone!=a, region in (us, eu),arch notin (arm),gpu,!deprecated")
+
+	require.NoError(t, err)
+	assert.Equal(t, Selector{
+		{Key: "env", Operator: Equals, Values: []string{"prod"}},
+		{Key: "tier", Operator: Equals, Values: []string{"gold"}},
+		{Key: "zone", Operator: NotEquals, Values: []string{"a"}},
+		{Key: "region", Operator: In, Values: []string{"us", "eu"}},
+		{Key: "arch", Operator: NotIn, Values: []string{"arm"}},
+		{Key: "gpu", Operator: Exists},
+		{Key: "deprecated", Operator: DoesNotExist},
+	}, sel)
+	assert.Equal(t, "env=prod,tier=gold,zone!=a,region in (us,eu),arch notin (arm),gpu,!deprecated", sel.String())
+}
+
+func TestParseEmpty(t *testing.T) {
+	sel, err := Parse("  ")
+
+	require.NoError(t, err)
+	assert.True(t, sel.Matches(nil, nil))
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, s := range []string{
+		"env=prod,",
+		"region in (us,eu",
+		"region in ()",
+		"region in (us,(eu))",
+		"=prod",
+		"!",
+		"env=pr(od",
+		"bad key=1",
+	} {
+		_, err := Parse(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestMatches(t *testing.T) {
+	tags := []string{"gpu", "h264"}
+	metadata := map[string]string{"env": "prod", "region": "us"}
+
+	tests := []struct {
+		selector string
+		want     bool
+	}{
+		{"env=prod", true},
+		{"env=dev", false},
+		{"env!=dev", true},
+		{"zone!=a", true},
+		{"region in (us,eu)", true},
+		{"region in (eu)", false},
+		{"zone in (a)", false},
+		{"region notin (eu)", true},
+		{"zone notin (a)", true},
+		{"gpu", true},
+		{"env", true},
+		{"cpu", false},
+		{"!deprecated", true},
+		{"!h264", false},
+		{"!env", false},
+		{"env=prod,region in (us,eu),!deprecated,gpu", true},
+		{"env=prod,deprecated", false},
+	}
+
+	for _, tt := range tests {
+		sel, err := Parse(tt.selector)
+		require.NoError(t, err, tt.selector)
+		assert.Equal(t, tt.want, sel.Matches(tags, metadata), tt.selector)
+	}
+}
@@ -0,0 +1,224 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/carlosealves2/video-ia/service-discover/internal/bootstrap"
+	"github.com/carlosealves2/video-ia/service-discover/internal/config"
+)
+
+func setupAuthTestApp() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	cfg := &config.Config{
+		Port:     8080,
+		LogLevel: "error",
+		GinMode:  "test",
+		Auth: config.AuthConfig{
+			Enabled: true,
+			APIKeys: []config.APIKey{
+				{Key: "reader", Scopes: []string{"read"}},
+				{Key: "writer", Scopes: []string{"read", "write"}},
+				{Key: "other-writer", Scopes: []string{"read", "write"}},
+				{Key: "ops", Scopes: []string{"admin"}},
+			},
+		},
+	}
+
+	app := bootstrap.New(cfg).
+		InitLogger().
+		InitRepository().
+		InitAuth().
+		InitHandlers().
+		InitRouter()
+
+	return app.GetRouter()
+}
+
+func authRequest(router *gin.Engine, method, path, key string, body []byte) *httptest.ResponseRecorder {
+	return ownerRequest(router, method, path, key, "", body)
+}
+
+func ownerRequest(router *gin.Engine, method, path, key, token string, body []byte) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(method, path, bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if key != "" {
+		req.Header.Set("X-API-Key", key)
+	}
+	if token != "" {
+		req.Header.Set("X-Owner-Token", token)
+	}
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestAuthHealthStaysOpen(t *testing.T) {
+	router := setupAuthTestApp()
+
+	assert.Equal(t, http.StatusOK, authRequest(router, http.MethodGet, "/health", "", nil).Code)
+}
+
+func TestAuthRequiresCredentials(t *testing.T) {
+	router := setupAuthTestApp()
+
+	w := authRequest(router, http.MethodGet, "/api/v1/services/list", "", nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.NotEmpty(t, w.Header().Get("WWW-Authenticate"))
+
+	assert.Equal(t, http.StatusUnauthorized, authRequest(router, http.MethodGet, "/api/v1/services/list", "wrong", nil).Code)
+}
+
+func TestAuthSeparatesReadAndWriteScopes(t *testing.T) {
+	router := setupAuthTestApp()
+	body := []byte(`{"name":"uploader","host":"10.0.0.1","port":8080}`)
+
+	assert.Equal(t, http.StatusOK, authRequest(router, http.MethodGet, "/api/v1/services/list", "reader", nil).Code)
+	assert.Equal(t, http.StatusForbidden, authRequest(router, http.MethodPost, "/api/v1/services/register", "reader", body).Code)
+	assert.Equal(t, http.StatusForbidden, authRequest(router, http.MethodDelete, "/api/v1/services/any/unregister", "reader", nil).Code)
+
+	assert.Equal(t, http.StatusCreated, authRequest(router, http.MethodPost, "/api/v1/services/register", "writer", body).Code)
+}
+
+func TestOwnershipRequiresOwnerToken(t *testing.T) {
+	router := setupAuthTestApp()
+	body := []byte(`{"id":"uploader-1","name":"uploader","host":"10.0.0.1","port":8080}`)
+
+	w := authRequest(router, http.MethodPost, "/api/v1/services/register", "writer", body)
+	require.Equal(t, http.StatusCreated, w.Code)
+	token := w.Header().Get("X-Owner-Token")
+	require.NotEmpty(t, token)
+	assert.NotContains(t, w.Body.String(), "owner_token_hash")
+
+	heartbeat := "/api/v1/services/uploader-1/heartbeat"
+	assert.Equal(t, http.StatusForbidden, authRequest(router, http.MethodPut, heartbeat, "other-writer", nil).Code)
+	assert.Equal(t, http.StatusForbidden, ownerRequest(router, http.MethodPut, heartbeat, "other-writer", "forged", nil).Code)
+	assert.Equal(t, http.StatusForbidden, authRequest(router, http.MethodPost, "/api/v1/services/register", "other-writer", body).Code)
+	assert.Equal(t, http.StatusOK, ownerRequest(router, http.MethodPut, heartbeat, "other-writer", token, nil).Code)
+
+	// Refreshing with the token keeps it.
+	w = ownerRequest(router, http.MethodPost, "/api/v1/services/register", "writer", token, body)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, token, w.Header().Get("X-Owner-Token"))
+
+	assert.Equal(t, http.StatusForbidden, authRequest(router, http.MethodDelete, "/api/v1/services/uploader-1/unregister", "writer", nil).Code)
+	assert.Equal(t, http.StatusOK, ownerRequest(router, http.MethodDelete, "/api/v1/services/uploader-1/unregister", "writer", token, nil).Code)
+}
+
+func TestOwnershipAdminOverride(t *testing.T) {
+	router := setupAuthTestApp()
+	body := []byte(`{"id":"uploader-1","name":"uploader","host":"10.0.0.1","port":8080}`)
+	require.Equal(t, http.StatusCreated, authRequest(router, http.MethodPost, "/api/v1/services/register", "writer", body).Code)
+
+	assert.Equal(t, http.StatusOK, authRequest(router, http.MethodPut, "/api/v1/services/uploader-1/update", "ops", []byte(`{"port":9090}`)).Code)
+	assert.Equal(t, http.StatusOK, authRequest(router, http.MethodDelete, "/api/v1/services/uploader-1/unregister", "ops", nil).Code)
+}
+
+func TestOwnerTokenHiddenFromReads(t *testing.T) {
+	router := setupAuthTestApp()
+	body := []byte(`{"name":"uploader","host":"10.0.0.1","port":8080}`)
+	require.Equal(t, http.StatusCreated, authRequest(router, http.MethodPost, "/api/v1/services/register", "writer", body).Code)
+
+	w := authRequest(router, http.MethodGet, "/api/v1/services/list", "reader", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), "owner_token_hash")
+}
+
+func TestOwnershipKeptWhileUnhealthy(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := l.Addr().(*net.TCPAddr).Port
+	require.NoError(t, l.Close())
+
+	cfg := &config.Config{
+		Port:     port,
+		LogLevel: "error",
+		GinMode:  "test",
+		Server:   config.ServerConfig{ShutdownTimeout: time.Second},
+		Heartbeat: config.HeartbeatConfig{
+			DeregisterAfter: time.Hour,
+			ReapInterval:    10 * time.Millisecond,
+		},
+		Auth: config.AuthConfig{
+			Enabled: true,
+			APIKeys: []config.APIKey{
+				{Key: "writer", Scopes: []string{"read", "write"}},
+				{Key: "other-writer", Scopes: []string{"read", "write"}},
+			},
+		},
+	}
+	app := bootstrap.New(cfg).
+		InitLogger().
+		InitRepository().
+		InitAuth().
+		InitHandlers().
+		InitHeartbeatReaper().
+		InitRouter()
+	router := app.GetRouter()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	run(t, ctx, app, fmt.Sprintf("http://127.0.0.1:%d", port))
+
+	body := []byte(`{"id":"uploader-1","name":"uploader","host":"10.0.0.1","port":8080,"ttl":"50ms"}`)
+	require.Equal(t, http.StatusCreated, authRequest(router, http.MethodPost, "/api/v1/services/register", "writer", body).Code)
+	require.Eventually(t, func() bool {
+		w := authRequest(router, http.MethodGet, "/api/v1/services/uploader-1", "writer", nil)
+		return strings.Contains(w.Body.String(), `"status":"unhealthy"`)
+	}, 5*time.Second, 10*time.Millisecond)
+
+	hijack := []byte(`{"id":"uploader-1","name":"uploader","host":"10.6.6.6","port":6666}`)
+	assert.Equal(t, http.StatusForbidden, authRequest(router, http.MethodPost, "/api/v1/services/register", "other-writer", hijack).Code)
+}
+
+func TestClusterRoutesRequireAdmin(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	raftAddr := l.Addr().String()
+	require.NoError(t, l.Close())
+
+	cfg := &config.Config{
+		Port:     8080,
+		LogLevel: "error",
+		GinMode:  "test",
+		Cluster: config.ClusterConfig{
+			Enabled:      true,
+			NodeID:       "node-1",
+			BindAddr:     raftAddr,
+			Peers:        []config.Peer{{ID: "node-1", RaftAddr: raftAddr, APIAddr: "http://127.0.0.1:8080"}},
+			ApplyTimeout: time.Second,
+		},
+		Auth: config.AuthConfig{
+			Enabled: true,
+			APIKeys: []config.APIKey{
+				{Key: "writer", Scopes: []string{"read", "write"}},
+				{Key: "ops", Scopes: []string{"admin"}},
+			},
+		},
+	}
+	router := bootstrap.New(cfg).
+		InitLogger().
+		InitRepository().
+		InitAuth().
+		InitCluster().
+		InitHandlers().
+		InitRouter().
+		GetRouter()
+
+	command := []byte(`{"type":"delete","id":"default/uploader-1"}`)
+	assert.Equal(t, http.StatusForbidden, authRequest(router, http.MethodPost, "/internal/cluster/apply", "writer", command).Code)
+	assert.Equal(t, http.StatusForbidden, authRequest(router, http.MethodGet, "/internal/cluster/status", "writer", nil).Code)
+	assert.Equal(t, http.StatusOK, authRequest(router, http.MethodGet, "/internal/cluster/status", "ops", nil).Code)
+}
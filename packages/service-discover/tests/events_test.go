@@ -0,0 +1,129 @@
+package tests
+
+import (
+	"bufio"
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/carlosealves2/video-ia/service-discover/internal/bootstrap"
+	"github.com/carlosealves2/video-ia/service-discover/internal/config"
+)
+
+func setupEventsServer(t *testing.T) *httptest.Server {
+	gin.SetMode(gin.TestMode)
+
+	cfg := &config.Config{
+		Port:     8080,
+		LogLevel: "error",
+		GinMode:  "test",
+		Events:   config.EventsConfig{BufferSize: 16},
+	}
+
+	app := bootstrap.New(cfg).
+		InitLogger().
+		InitRepository().
+		InitHandlers().
+		InitRouter()
+
+	server := httptest.NewServer(app.GetRouter())
+	t.Cleanup(server.Close)
+	return server
+}
+
+type sseEvent struct {
+	id    string
+	event string
+	data  string
+}
+
+func readEvents(t *testing.T, resp *http.Response, n int) []sseEvent {
+	events := make(chan sseEvent)
+	go func() {
+		scanner := bufio.NewScanner(resp.Body)
+		var current sseEvent
+		for scanner.Scan() {
+			line := scanner.Text()
+			switch {
+			case strings.HasPrefix(line, "id:"):
+				current.id = strings.TrimSpace(strings.TrimPrefix(line, "id:"))
+			case strings.HasPrefix(line, "event:"):
+				current.event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+			case strings.HasPrefix(line, "data:"):
+				current.data = strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+			case line == "" && current.event != "":
+				events <- current
+				current = sseEvent{}
+			}
+		}
+	}()
+
+	var received []sseEvent
+	timeout := time.After(5 * time.Second)
+	for len(received) < n {
+		select {
+		case event := <-events:
+			received = append(received, event)
+		case <-timeout:
+			t.Fatalf("timed out waiting for events, got %d of %d", len(received), n)
+		}
+	}
+	return received
+}
+
+func postJSON(t *testing.T, url, body string) {
+	resp, err := http.Post(url, "application/json", bytes.NewBufferString(body))
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+}
+
+func TestEventStream(t *testing.T) {
+	server := setupEventsServer(t)
+
+	resp, err := http.Get(server.URL + "/api/v1/services/events?name=video")
+	require.NoError(t, err)
+	defer func() { _ = resp.Body.Close() }()
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	postJSON(t, server.URL+"/api/v1/services/register", `{"name": "billing", "host": "localhost", "port": 3000}`)
+	postJSON(t, server.URL+"/api/v1/services/register", `{"name": "video-worker", "host": "localhost", "port": 3001}`)
+
+	received := readEvents(t, resp, 1)
+	assert.Equal(t, "registered", received[0].event)
+	assert.Contains(t, received[0].data, "video-worker")
+	assert.NotContains(t, received[0].data, "billing")
+}
+
+func TestEventStreamResumesFromLastEventID(t *testing.T) {
+	server := setupEventsServer(t)
+
+	postJSON(t, server.URL+"/api/v1/services/register", `{"name": "first", "host": "localhost", "port": 3000}`)
+	postJSON(t, server.URL+"/api/v1/services/register", `{"name": "second", "host": "localhost", "port": 3001}`)
+
+	req, _ := http.NewRequest("GET", server.URL+"/api/v1/services/events", nil)
+	req.Header.Set("Last-Event-ID", "1")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer func() { _ = resp.Body.Close() }()
+
+	received := readEvents(t, resp, 1)
+	assert.Equal(t, "2", received[0].id)
+	assert.Contains(t, received[0].data, "second")
+}
+
+func TestEventStreamInvalidLastEventID(t *testing.T) {
+	server := setupEventsServer(t)
+
+	resp, err := http.Get(server.URL + "/api/v1/services/events?last_event_id=abc")
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
@@ -5,7 +5,9 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
@@ -84,6 +86,38 @@ func TestRegisterServiceInvalidRequest(t *testing.T) {
 	assert.Equal(t, http.StatusBadRequest, w.Code)
 }
 
+func TestRegisterServiceWithTTL(t *testing.T) {
+	router := setupTestApp()
+
+	body := []byte(`{"name": "ttl-service", "host": "localhost", "port": 3000, "ttl": "15s"}`)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/v1/services/register", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	var response domain.Service
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	require.NoError(t, err)
+	assert.Equal(t, domain.Duration(15*time.Second), response.TTL)
+	assert.Contains(t, w.Body.String(), `"ttl":"15s"`)
+}
+
+func TestRegisterServiceInvalidTTL(t *testing.T) {
+	router := setupTestApp()
+
+	for _, ttl := range []string{`"-5s"`, `"soon"`} {
+		body := []byte(`{"name": "ttl-service", "host": "localhost", "port": 3000, "ttl": ` + ttl + `}`)
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/api/v1/services/register", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, ttl)
+	}
+}
+
 func TestListServices(t *testing.T) {
 	router := setupTestApp()
 
@@ -100,6 +134,96 @@ func TestListServices(t *testing.T) {
 	assert.Contains(t, response, "count")
 }
 
+func registerTestService(t *testing.T, router *gin.Engine, name string) domain.Service {
+	body, _ := json.Marshal(domain.RegisterServiceRequest{Name: name, Host: "localhost", Port: 3000})
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/v1/services/register", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	var service domain.Service
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &service))
+	return service
+}
+
+func TestListReturnsRegistryIndex(t *testing.T) {
+	router := setupTestApp()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/services/list", nil)
+	router.ServeHTTP(w, req)
+	before, err := strconv.ParseUint(w.Header().Get("X-Registry-Index"), 10, 64)
+	require.NoError(t, err)
+
+	registerTestService(t, router, "indexed-service")
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/v1/services/list", nil)
+	router.ServeHTTP(w, req)
+	after, err := strconv.ParseUint(w.Header().Get("X-Registry-Index"), 10, 64)
+	require.NoError(t, err)
+
+	assert.Greater(t, after, before)
+}
+
+func TestBlockingListWaitsForChange(t *testing.T) {
+	router := setupTestApp()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/services/list", nil)
+	router.ServeHTTP(w, req)
+	index := w.Header().Get("X-Registry-Index")
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		body := []byte(`{"name": "late-service", "host": "localhost", "port": 3000}`)
+		req, _ := http.NewRequest("POST", "/api/v1/services/register", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}()
+
+	start := time.Now()
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/v1/services/list?index="+index+"&wait=5s", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Less(t, time.Since(start), 5*time.Second)
+	assert.NotEqual(t, index, w.Header().Get("X-Registry-Index"))
+	assert.Contains(t, w.Body.String(), "late-service")
+}
+
+func TestBlockingSearchTimesOut(t *testing.T) {
+	router := setupTestApp()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/services/search", nil)
+	router.ServeHTTP(w, req)
+	index := w.Header().Get("X-Registry-Index")
+
+	start := time.Now()
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/v1/services/search?index="+index+"&wait=100ms", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+	assert.Equal(t, index, w.Header().Get("X-Registry-Index"))
+}
+
+func TestBlockingQueryInvalidParams(t *testing.T) {
+	router := setupTestApp()
+
+	for _, query := range []string{"index=abc", "index=1&wait=soon", "index=1&wait=-1s"} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/api/v1/services/list?"+query, nil)
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+}
+
 func TestGetServiceNotFound(t *testing.T) {
 	router := setupTestApp()
 
@@ -190,3 +314,67 @@ func TestConfigBuilder(t *testing.T) {
 	assert.Equal(t, "info", cfg.LogLevel)
 	assert.Equal(t, "release", cfg.GinMode)
 }
+
+func postRegister(router *gin.Engine, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/v1/services/register", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func listServices(t *testing.T, router *gin.Engine) []domain.Service {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/services/list", nil)
+	router.ServeHTTP(w, req)
+
+	var response struct {
+		Services []domain.Service `json:"services"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	return response.Services
+}
+
+func TestRegisterIsIdempotentOnNameHostPort(t *testing.T) {
+	router := setupTestApp()
+
+	first := postRegister(router, `{"name": "video-processor", "host": "10.0.0.1", "port": 3000, "tags": ["v1"]}`)
+	require.Equal(t, http.StatusCreated, first.Code)
+	assert.Equal(t, "created", first.Header().Get("X-Registration"))
+
+	var created domain.Service
+	require.NoError(t, json.Unmarshal(first.Body.Bytes(), &created))
+
+	second := postRegister(router, `{"name": "video-processor", "host": "10.0.0.1", "port": 3000, "tags": ["v2"]}`)
+	require.Equal(t, http.StatusOK, second.Code)
+	assert.Equal(t, "refreshed", second.Header().Get("X-Registration"))
+
+	var refreshed domain.Service
+	require.NoError(t, json.Unmarshal(second.Body.Bytes(), &refreshed))
+	assert.Equal(t, created.ID, refreshed.ID)
+	assert.Equal(t, []string{"v2"}, refreshed.Tags)
+	assert.True(t, refreshed.RegisteredAt.Equal(created.RegisteredAt))
+
+	other := postRegister(router, `{"name": "video-processor", "host": "10.0.0.1", "port": 3001}`)
+	require.Equal(t, http.StatusCreated, other.Code)
+
+	assert.Len(t, listServices(t, router), 2)
+}
+
+func TestRegisterWithInstanceID(t *testing.T) {
+	router := setupTestApp()
+
+	first := postRegister(router, `{"id": "transcoder-0", "name": "transcoder", "host": "10.0.0.1", "port": 3000}`)
+	require.Equal(t, http.StatusCreated, first.Code)
+
+	second := postRegister(router, `{"id": "transcoder-0", "name": "transcoder", "host": "10.0.0.9", "port": 3000}`)
+	require.Equal(t, http.StatusOK, second.Code)
+
+	services := listServices(t, router)
+	require.Len(t, services, 1)
+	assert.Equal(t, "transcoder-0", services[0].ID)
+	assert.Equal(t, "10.0.0.9", services[0].Host)
+
+	invalid := postRegister(router, `{"id": "Not Valid", "name": "transcoder", "host": "10.0.0.1", "port": 3000}`)
+	assert.Equal(t, http.StatusBadRequest, invalid.Code)
+}
@@ -0,0 +1,55 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/carlosealves2/video-ia/service-discover/internal/bootstrap"
+	"github.com/carlosealves2/video-ia/service-discover/internal/config"
+)
+
+func setupMetricsTestApp() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	cfg := &config.Config{
+		Port:     8080,
+		LogLevel: "error",
+		GinMode:  "test",
+		Metrics:  config.MetricsConfig{Enabled: true},
+	}
+
+	app := bootstrap.New(cfg).
+		InitLogger().
+		InitMetrics().
+		InitRepository().
+		InitHandlers().
+		InitRouter()
+
+	return app.GetRouter()
+}
+
+func TestMetricsEndpoint(t *testing.T) {
+	router := setupMetricsTestApp()
+
+	require.Equal(t, http.StatusCreated, postRegister(router, `{"id": "uploader-0", "name": "uploader", "host": "10.0.0.1", "port": 3000}`).Code)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPut, "/api/v1/services/uploader-0/heartbeat", nil)
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodGet, "/metrics", nil)
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	body := w.Body.String()
+	assert.Contains(t, body, `service_discover_http_requests_total{method="PUT",route="/api/v1/services/:id/heartbeat",status="200"} 1`)
+	assert.Contains(t, body, `service_discover_instances{namespace="default",service="uploader",status="healthy"} 1`)
+	assert.Contains(t, body, `service_discover_registrations_total{namespace="default",service="uploader"} 1`)
+	assert.Contains(t, body, `service_discover_heartbeats_total{namespace="default",service="uploader"} 1`)
+}
@@ -0,0 +1,72 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/carlosealves2/video-ia/service-discover/internal/domain"
+)
+
+func namespaceRequest(router *gin.Engine, method, path, namespace, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	if namespace != "" {
+		req.Header.Set("X-Namespace", namespace)
+	}
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func decodeServices(t *testing.T, w *httptest.ResponseRecorder) []domain.Service {
+	require.Equal(t, http.StatusOK, w.Code)
+	var response struct {
+		Services []domain.Service `json:"services"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	return response.Services
+}
+
+func TestNamespacesIsolateRegistrations(t *testing.T) {
+	router := setupTestApp()
+	body := `{"id": "uploader-0", "name": "uploader", "host": "10.0.0.1", "port": 3000}`
+
+	w := postRegister(router, body)
+	require.Equal(t, http.StatusCreated, w.Code)
+	var registered domain.Service
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &registered))
+	assert.Equal(t, "default", registered.Namespace)
+
+	// The same instance ID is a different instance in another namespace,
+	// whether the namespace comes from the header or the path.
+	require.Equal(t, http.StatusCreated, namespaceRequest(router, http.MethodPost, "/api/v1/services/register", "dev", body).Code)
+	require.Equal(t, http.StatusCreated, namespaceRequest(router, http.MethodPost, "/api/v1/namespaces/staging/services/register", "", body).Code)
+
+	for _, namespace := range []string{"default", "dev", "staging"} {
+		services := decodeServices(t, namespaceRequest(router, http.MethodGet, "/api/v1/namespaces/"+namespace+"/services/search?name=uploader", "", ""))
+		require.Len(t, services, 1)
+		assert.Equal(t, namespace, services[0].Namespace)
+	}
+	assert.Len(t, listServices(t, router), 1)
+	assert.Empty(t, decodeServices(t, namespaceRequest(router, http.MethodGet, "/api/v1/services/list", "prod", "")))
+
+	require.Equal(t, http.StatusOK, namespaceRequest(router, http.MethodDelete, "/api/v1/services/uploader-0/unregister", "dev", "").Code)
+	assert.Equal(t, http.StatusNotFound, namespaceRequest(router, http.MethodGet, "/api/v1/namespaces/dev/services/uploader-0", "", "").Code)
+	assert.Equal(t, http.StatusOK, namespaceRequest(router, http.MethodGet, "/api/v1/services/uploader-0", "", "").Code)
+	assert.Equal(t, http.StatusOK, namespaceRequest(router, http.MethodGet, "/api/v1/resolve/uploader", "staging", "").Code)
+	assert.Equal(t, http.StatusServiceUnavailable, namespaceRequest(router, http.MethodGet, "/api/v1/resolve/uploader", "dev", "").Code)
+}
+
+func TestInvalidNamespace(t *testing.T) {
+	router := setupTestApp()
+
+	assert.Equal(t, http.StatusBadRequest, namespaceRequest(router, http.MethodGet, "/api/v1/services/list", "Not_Valid", "").Code)
+	assert.Equal(t, http.StatusBadRequest, namespaceRequest(router, http.MethodGet, "/api/v1/namespaces/Not_Valid/services/list", "", "").Code)
+}
@@ -0,0 +1,95 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/carlosealves2/video-ia/service-discover/internal/domain"
+)
+
+func registerInstance(t *testing.T, router *gin.Engine, req domain.RegisterServiceRequest) domain.Service {
+	body, _ := json.Marshal(req)
+	w := httptest.NewRecorder()
+	httpReq, _ := http.NewRequest("POST", "/api/v1/services/register", bytes.NewBuffer(body))
+	httpReq.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, httpReq)
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	var service domain.Service
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &service))
+	return service
+}
+
+func resolve(router *gin.Engine, query string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/resolve/"+query, nil)
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestResolveRoundRobin(t *testing.T) {
+	router := setupTestApp()
+
+	registerInstance(t, router, domain.RegisterServiceRequest{Name: "transcoder", Host: "10.0.0.1", Port: 8080})
+	registerInstance(t, router, domain.RegisterServiceRequest{Name: "transcoder", Host: "10.0.0.2", Port: 8080})
+	registerInstance(t, router, domain.RegisterServiceRequest{Name: "uploader", Host: "10.0.0.3", Port: 8080})
+
+	hosts := map[string]int{}
+	for i := 0; i < 4; i++ {
+		w := resolve(router, "transcoder")
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var service domain.Service
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &service))
+		assert.Equal(t, "transcoder", service.Name)
+		hosts[service.Host]++
+	}
+
+	assert.Equal(t, map[string]int{"10.0.0.1": 2, "10.0.0.2": 2}, hosts)
+}
+
+func TestResolveConsistentHash(t *testing.T) {
+	router := setupTestApp()
+
+	registerInstance(t, router, domain.RegisterServiceRequest{Name: "transcoder", Host: "10.0.0.1", Port: 8080})
+	registerInstance(t, router, domain.RegisterServiceRequest{Name: "transcoder", Host: "10.0.0.2", Port: 8080})
+
+	first := resolve(router, "transcoder?strategy=consistent-hash&key=video-42")
+	require.Equal(t, http.StatusOK, first.Code)
+	for i := 0; i < 5; i++ {
+		assert.Equal(t, first.Body.String(), resolve(router, "transcoder?strategy=consistent-hash&key=video-42").Body.String())
+	}
+
+	assert.Equal(t, http.StatusBadRequest, resolve(router, "transcoder?strategy=consistent-hash").Code)
+}
+
+func TestResolveInvalidStrategy(t *testing.T) {
+	router := setupTestApp()
+
+	w := resolve(router, "transcoder?strategy=fastest")
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "unknown load-balancing strategy")
+}
+
+func TestResolveNoHealthyInstance(t *testing.T) {
+	router := setupTestApp()
+
+	w := resolve(router, "missing?strategy=random")
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+	var response map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "no healthy instance available", response["error"])
+	assert.Equal(t, "missing", response["service"])
+	assert.Equal(t, "random", response["strategy"])
+	assert.EqualValues(t, 0, response["instances"])
+}
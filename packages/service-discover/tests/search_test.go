@@ -0,0 +1,84 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/carlosealves2/video-ia/service-discover/internal/domain"
+)
+
+func setupSearchTestApp(t *testing.T) *gin.Engine {
+	t.Helper()
+	router := setupTestApp()
+	for _, req := range []domain.RegisterServiceRequest{
+		{Name: "encoder-us", Host: "10.0.0.1", Port: 9000, Protocol: "grpc", Tags: []string{"gpu", "h264"}, Metadata: map[string]string{"env": "prod", "region": "us"}},
+		{Name: "encoder-eu", Host: "10.0.0.2", Port: 9000, Protocol: "grpc", Tags: []string{"gpu", "vp9"}, Metadata: map[string]string{"env": "prod", "region": "eu"}},
+		{Name: "encoder-old", Host: "10.0.0.3", Port: 9000, Protocol: "grpc", Tags: []string{"h264", "deprecated"}, Metadata: map[string]string{"env": "prod", "region": "us"}},
+		{Name: "uploader", Host: "10.0.0.4", Port: 8080, Protocol: "http", Metadata: map[string]string{"env": "staging"}},
+	} {
+		registerInstance(t, router, req)
+	}
+	return router
+}
+
+func searchNames(t *testing.T, router *gin.Engine, query string) []string {
+	t.Helper()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/services/search?"+query, nil)
+	router.ServeHTTP(w, req)
+
+	var names []string
+	for _, svc := range decodeServices(t, w) {
+		names = append(names, svc.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestSearchSelector(t *testing.T) {
+	router := setupSearchTestApp(t)
+
+	assert.Equal(t, []string{"encoder-eu", "encoder-us"},
+		searchNames(t, router, "selector=env%3Dprod,region+in+(us,eu),!deprecated"))
+	assert.Equal(t, []string{"encoder-old", "encoder-us"},
+		searchNames(t, router, "selector=region%3Dus"))
+	assert.Equal(t, []string{"encoder-eu", "encoder-old", "encoder-us"},
+		searchNames(t, router, "selector=env!%3Dstaging"))
+}
+
+func TestSearchTags(t *testing.T) {
+	router := setupSearchTestApp(t)
+
+	assert.Equal(t, []string{"encoder-us"}, searchNames(t, router, "tags=gpu,h264"))
+	assert.Equal(t, []string{"encoder-us"}, searchNames(t, router, "tags=gpu,h264&tag_mode=all"))
+	assert.Equal(t, []string{"encoder-eu", "encoder-old", "encoder-us"}, searchNames(t, router, "tags=gpu,h264&tag_mode=any"))
+	assert.Equal(t, []string{"encoder-old", "encoder-us"}, searchNames(t, router, "tag=h264"))
+}
+
+func TestSearchStatusAndProtocol(t *testing.T) {
+	router := setupSearchTestApp(t)
+
+	assert.Equal(t, []string{"uploader"}, searchNames(t, router, "protocol=HTTP"))
+	assert.Len(t, searchNames(t, router, "status=healthy&protocol=grpc"), 3)
+	assert.Empty(t, searchNames(t, router, "status=unhealthy"))
+}
+
+func TestSearchInvalidQuery(t *testing.T) {
+	router := setupSearchTestApp(t)
+
+	for _, query := range []string{
+		"selector=region+in+(us",
+		"tag_mode=some",
+		"status=down",
+	} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/api/v1/services/search?"+query, nil)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+}
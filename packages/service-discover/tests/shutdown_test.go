@@ -0,0 +1,154 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/carlosealves2/video-ia/service-discover/internal/bootstrap"
+	"github.com/carlosealves2/video-ia/service-discover/internal/config"
+)
+
+func setupShutdownTestApp(t *testing.T) (*bootstrap.App, string) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := l.Addr().(*net.TCPAddr).Port
+	require.NoError(t, l.Close())
+
+	cfg := &config.Config{
+		Port:     port,
+		LogLevel: "error",
+		GinMode:  "test",
+		Server: config.ServerConfig{
+			ShutdownTimeout: 10 * time.Second,
+		},
+	}
+
+	app := bootstrap.New(cfg).
+		InitLogger().
+		InitRepository().
+		InitHandlers().
+		InitRouter()
+
+	return app, fmt.Sprintf("http://127.0.0.1:%d", port)
+}
+
+// run starts app and waits until it answers; the returned channel yields
+// Run's result.
+func run(t *testing.T, ctx context.Context, app *bootstrap.App, baseURL string) <-chan error {
+	t.Helper()
+	done := make(chan error, 1)
+	go func() { done <- app.Run(ctx) }()
+
+	require.Eventually(t, func() bool {
+		resp, err := http.Get(baseURL + "/health")
+		if err != nil {
+			return false
+		}
+		_ = resp.Body.Close()
+		return resp.StatusCode == http.StatusOK
+	}, 5*time.Second, 10*time.Millisecond)
+	return done
+}
+
+func TestRunDrainsInFlightRequests(t *testing.T) {
+	app, baseURL := setupShutdownTestApp(t)
+
+	started := make(chan struct{})
+	app.GetRouter().GET("/slow", func(c *gin.Context) {
+		close(started)
+		time.Sleep(300 * time.Millisecond)
+		c.String(http.StatusOK, "done")
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := run(t, ctx, app, baseURL)
+
+	type result struct {
+		status int
+		body   string
+		err    error
+	}
+	results := make(chan result, 1)
+	go func() {
+		resp, err := http.Get(baseURL + "/slow")
+		if err != nil {
+			results <- result{err: err}
+			return
+		}
+		defer func() { _ = resp.Body.Close() }()
+		body, err := io.ReadAll(resp.Body)
+		results <- result{status: resp.StatusCode, body: string(body), err: err}
+	}()
+
+	<-started
+	cancel()
+
+	res := <-results
+	require.NoError(t, res.err)
+	assert.Equal(t, http.StatusOK, res.status)
+	assert.Equal(t, "done", res.body)
+
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return after shutdown")
+	}
+
+	_, err := http.Get(baseURL + "/health")
+	assert.Error(t, err, "no new connections after shutdown")
+}
+
+func TestRunReleasesLongLivedRequests(t *testing.T) {
+	app, baseURL := setupShutdownTestApp(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := run(t, ctx, app, baseURL)
+
+	stream, err := http.Get(baseURL + "/api/v1/services/events")
+	require.NoError(t, err)
+	defer func() { _ = stream.Body.Close() }()
+	require.Equal(t, http.StatusOK, stream.StatusCode)
+
+	blocking := make(chan int, 1)
+	go func() {
+		resp, err := http.Get(baseURL + "/api/v1/services/list?index=1&wait=5m")
+		if err != nil {
+			blocking <- 0
+			return
+		}
+		_ = resp.Body.Close()
+		blocking <- resp.StatusCode
+	}()
+
+	// Give the blocking query time to start waiting.
+	time.Sleep(100 * time.Millisecond)
+	start := time.Now()
+	cancel()
+
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run waited for the drain deadline")
+	}
+	assert.Less(t, time.Since(start), 2*time.Second)
+	assert.Equal(t, http.StatusOK, <-blocking)
+
+	_, err = io.ReadAll(stream.Body)
+	assert.NoError(t, err, "the stream ends cleanly")
+}
@@ -0,0 +1,72 @@
+package tests
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// registerWithCertificate registers name as a caller whose TLS client
+// certificate, with the given common name, was verified.
+func registerWithCertificate(router *gin.Engine, commonName, name string) *httptest.ResponseRecorder {
+	body := []byte(`{"id":"` + name + `-1","name":"` + name + `","host":"10.0.0.1","port":8080}`)
+	return certificateRequest(router, http.MethodPost, "/api/v1/services/register", commonName, body)
+}
+
+// certificateRequest sends a request as a caller whose TLS client
+// certificate, with the given common name, was verified.
+func certificateRequest(router *gin.Engine, method, path, commonName string, body []byte) *httptest.ResponseRecorder {
+	cert := &x509.Certificate{Subject: pkix.Name{CommonName: commonName}}
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(method, path, bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.TLS = &tls.ConnectionState{
+		PeerCertificates: []*x509.Certificate{cert},
+		VerifiedChains:   [][]*x509.Certificate{{cert}},
+	}
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestClientCertificateLimitsRegisteredName(t *testing.T) {
+	router := setupTestApp()
+
+	assert.Equal(t, http.StatusCreated, registerWithCertificate(router, "uploader", "uploader").Code)
+
+	w := registerWithCertificate(router, "uploader", "transcoder")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "client certificate does not allow registering transcoder")
+}
+
+func TestClientCertificateLimitsModifiedName(t *testing.T) {
+	router := setupTestApp()
+	require.Equal(t, http.StatusCreated, registerWithCertificate(router, "uploader", "uploader").Code)
+
+	for _, tt := range []struct {
+		method, path string
+		body         []byte
+	}{
+		{http.MethodPut, "/api/v1/services/uploader-1/update", []byte(`{"port":9090}`)},
+		{http.MethodPut, "/api/v1/services/uploader-1/heartbeat", nil},
+		{http.MethodDelete, "/api/v1/services/uploader-1/unregister", nil},
+	} {
+		w := certificateRequest(router, tt.method, tt.path, "transcoder", tt.body)
+		assert.Equal(t, http.StatusForbidden, w.Code, tt.path)
+		assert.Contains(t, w.Body.String(), "client certificate does not allow modifying uploader", tt.path)
+	}
+
+	hijack := []byte(`{"id":"uploader-1","name":"transcoder","host":"10.6.6.6","port":6666}`)
+	assert.Equal(t, http.StatusForbidden, certificateRequest(router, http.MethodPost, "/api/v1/services/register", "transcoder", hijack).Code)
+
+	assert.Equal(t, http.StatusOK, certificateRequest(router, http.MethodPut, "/api/v1/services/uploader-1/heartbeat", "uploader", nil).Code)
+	assert.Equal(t, http.StatusOK, certificateRequest(router, http.MethodDelete, "/api/v1/services/uploader-1/unregister", "uploader", nil).Code)
+}
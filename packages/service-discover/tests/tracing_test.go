@@ -0,0 +1,74 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/carlosealves2/video-ia/service-discover/internal/bootstrap"
+	"github.com/carlosealves2/video-ia/service-discover/internal/config"
+)
+
+func setupTracingTestApp(exporter *tracetest.InMemoryExporter) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	cfg := &config.Config{
+		Port:     8080,
+		LogLevel: "error",
+		GinMode:  "test",
+	}
+
+	app := bootstrap.New(cfg).
+		InitLogger().
+		WithTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))).
+		InitRepository().
+		InitHandlers().
+		InitRouter()
+
+	return app.GetRouter()
+}
+
+func spanAttributes(span tracetest.SpanStub) map[attribute.Key]attribute.Value {
+	attrs := make(map[attribute.Key]attribute.Value, len(span.Attributes))
+	for _, kv := range span.Attributes {
+		attrs[kv.Key] = kv.Value
+	}
+	return attrs
+}
+
+func TestTracingContinuesCallerTrace(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	router := setupTracingTestApp(exporter)
+
+	require.Equal(t, http.StatusCreated, postRegister(router, `{"id": "uploader-0", "name": "uploader", "host": "10.0.0.1", "port": 3000}`).Code)
+	exporter.Reset()
+
+	const parent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPut, "/api/v1/services/uploader-0/heartbeat", nil)
+	req.Header.Set("traceparent", parent)
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1)
+	span := spans[0]
+
+	assert.Equal(t, "PUT /api/v1/services/:id/heartbeat", span.Name)
+	assert.Equal(t, trace.SpanKindServer, span.SpanKind)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", span.SpanContext.TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", span.Parent.SpanID().String())
+
+	attrs := spanAttributes(span)
+	assert.Equal(t, "uploader-0", attrs["service_id"].AsString())
+	assert.Equal(t, "uploader", attrs["service_name"].AsString())
+	assert.Equal(t, int64(http.StatusOK), attrs["http.response.status_code"].AsInt64())
+}